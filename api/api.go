@@ -0,0 +1,8 @@
+package api
+
+import _ "embed"
+
+// OpenAPI describes the HTTP API.
+//
+//go:embed openapi.json
+var OpenAPI []byte
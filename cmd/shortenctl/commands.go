@@ -0,0 +1,200 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/domurdoc/shortener/pkg/client"
+)
+
+// runLogin stores the given tokens, or checks the saved ones. A new user is
+// registered only when there is no config yet or -new is passed, so expired
+// credentials never silently turn into an empty account.
+func runLogin(ctx context.Context, e *env, args []string) error {
+	fs := flag.NewFlagSet("login", flag.ContinueOnError)
+	token := fs.String("token", "", "existing access token")
+	refreshToken := fs.String("refresh-token", "", "existing refresh token")
+	register := fs.Bool("new", false, "register a new user, replacing the saved one")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	switch {
+	case *register && (*token != "" || *refreshToken != ""):
+		return errors.New("-new cannot be combined with tokens")
+	case *register:
+		e.cfg.Token, e.cfg.RefreshToken = "", ""
+	case *token != "" || *refreshToken != "":
+		e.cfg.Token, e.cfg.RefreshToken = *token, *refreshToken
+	}
+	// The saved tokens are replaced only once the server accepted them.
+	cfg := *e.cfg
+	e.cfg = &config{Server: cfg.Server}
+	c, err := e.newClient(cfg.Token, cfg.RefreshToken)
+	if err != nil {
+		return err
+	}
+	if _, err := c.ListURLs(ctx, client.ListOptions{Limit: 1}); err != nil {
+		var apiErr *client.APIError
+		if errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusUnauthorized {
+			return errSessionExpired
+		}
+		return err
+	}
+	if c.Token() == "" {
+		return errors.New("server did not issue a token")
+	}
+	cfg.Token, cfg.RefreshToken = c.Token(), c.RefreshToken()
+	e.cfg = &cfg
+	e.client = c
+	if err := saveConfig(e.configPath, e.cfg); err != nil {
+		return err
+	}
+	fmt.Fprintf(e.out.w, "logged in to %s, config saved to %s\n", e.cfg.Server, e.configPath)
+	return nil
+}
+
+type shortenResult struct {
+	OriginalURL   string `json:"original_url"`
+	ShortURL      string `json:"short_url"`
+	AlreadyExists bool   `json:"already_exists"`
+}
+
+func runShorten(ctx context.Context, e *env, args []string) error {
+	fs := flag.NewFlagSet("shorten", flag.ContinueOnError)
+	teamID := fs.Int64("team", 0, "shorten on behalf of a team")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() == 0 {
+		return errors.New("at least one URL must be passed")
+	}
+	results := make([]shortenResult, 0, fs.NArg())
+	rows := make([][]string, 0, fs.NArg())
+	for _, originalURL := range fs.Args() {
+		shortURL, err := e.client.ShortenForTeam(ctx, originalURL, *teamID)
+		var conflictErr *client.ConflictError
+		if err != nil && !errors.As(err, &conflictErr) {
+			return fmt.Errorf("%s: %w", originalURL, err)
+		}
+		results = append(results, shortenResult{OriginalURL: originalURL, ShortURL: shortURL, AlreadyExists: err != nil})
+		rows = append(rows, []string{originalURL, shortURL, strconv.FormatBool(err != nil)})
+	}
+	return e.out.print(results, []string{"ORIGINAL URL", "SHORT URL", "EXISTED"}, rows)
+}
+
+// runBatch reads one URL per line, optionally preceded by a correlation ID
+// and whitespace. Line numbers are used as correlation IDs otherwise.
+func runBatch(ctx context.Context, e *env, args []string) error {
+	input := e.stdin
+	if len(args) > 0 && args[0] != "-" {
+		f, err := os.Open(args[0])
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		input = f
+	}
+	var items []client.BatchItem
+	scanner := bufio.NewScanner(input)
+	for line := 1; scanner.Scan(); line++ {
+		fields := strings.Fields(scanner.Text())
+		switch len(fields) {
+		case 0:
+			continue
+		case 1:
+			items = append(items, client.BatchItem{CorrelationID: strconv.Itoa(line), OriginalURL: fields[0]})
+		case 2:
+			items = append(items, client.BatchItem{CorrelationID: fields[0], OriginalURL: fields[1]})
+		default:
+			return fmt.Errorf("line %d: want [ID] URL", line)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+	if len(items) == 0 {
+		return errors.New("no URLs in input")
+	}
+	results, err := e.client.ShortenBatch(ctx, items)
+	var conflictErr *client.BatchConflictError
+	if err != nil && !errors.As(err, &conflictErr) {
+		return err
+	}
+	rows := make([][]string, len(results))
+	for i, res := range results {
+		rows[i] = []string{res.CorrelationID, items[i].OriginalURL, res.ShortURL}
+	}
+	return e.out.print(results, []string{"ID", "ORIGINAL URL", "SHORT URL"}, rows)
+}
+
+func runList(ctx context.Context, e *env, args []string) error {
+	var opts client.ListOptions
+	fs := flag.NewFlagSet("ls", flag.ContinueOnError)
+	fs.IntVar(&opts.Limit, "limit", 0, "page size, all links when 0")
+	fs.IntVar(&opts.Offset, "offset", 0, "number of links to skip")
+	fs.StringVar(&opts.Owner, "owner", "", "personal, team or all")
+	fs.Int64Var(&opts.TeamID, "team", 0, "team ID for -owner team")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	page, err := e.client.ListURLs(ctx, opts)
+	if err != nil {
+		return err
+	}
+	rows := make([][]string, len(page.URLs))
+	for i, u := range page.URLs {
+		team := ""
+		if u.TeamID != 0 {
+			team = strconv.FormatInt(u.TeamID, 10)
+		}
+		rows[i] = []string{u.ShortURL, u.OriginalURL, team}
+	}
+	urls := page.URLs
+	if urls == nil {
+		urls = []client.URLRecord{}
+	}
+	return e.out.print(urls, []string{"SHORT URL", "ORIGINAL URL", "TEAM"}, rows)
+}
+
+func runRemove(ctx context.Context, e *env, args []string) error {
+	if len(args) == 0 {
+		return errors.New("at least one short code must be passed")
+	}
+	if err := e.client.Delete(ctx, args...); err != nil {
+		return err
+	}
+	fmt.Fprintf(e.out.w, "deletion of %d links scheduled\n", len(args))
+	return nil
+}
+
+func runResolve(ctx context.Context, e *env, args []string) error {
+	if len(args) != 1 {
+		return errors.New("exactly one short code must be passed")
+	}
+	originalURL, err := e.client.Resolve(ctx, args[0])
+	if err != nil {
+		return err
+	}
+	return e.out.print(
+		map[string]string{"short_code": args[0], "original_url": originalURL},
+		[]string{"SHORT CODE", "ORIGINAL URL"},
+		[][]string{{args[0], originalURL}},
+	)
+}
+
+func runStats(ctx context.Context, e *env, args []string) error {
+	stats, err := e.client.Stats(ctx)
+	if err != nil {
+		return err
+	}
+	return e.out.print(stats, []string{"URLS", "ACTIVE URLS", "USERS"}, [][]string{{
+		strconv.Itoa(stats.URLs), strconv.Itoa(stats.ActiveURLs), strconv.Itoa(stats.Users),
+	}})
+}
@@ -0,0 +1,140 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sort"
+
+	"github.com/domurdoc/shortener/pkg/client"
+)
+
+const defaultServer = "http://localhost:8080"
+
+var errNotLoggedIn = errors.New("not logged in, run: shortenctl login")
+
+var errSessionExpired = errors.New("session expired, run: shortenctl login -token TOKEN, or shortenctl login -new for a new user")
+
+type env struct {
+	client     *client.Client
+	cfg        *config
+	configPath string
+	gzip       bool
+	out        *printer
+	stdin      io.Reader
+	// saveErr is the last failure to persist tokens rotated by the server.
+	saveErr error
+}
+
+type command struct {
+	usage     string
+	needLogin bool
+	run       func(ctx context.Context, e *env, args []string) error
+}
+
+var commands = map[string]command{
+	"login":   {"login [-token TOKEN [-refresh-token TOKEN]] [-new]", false, runLogin},
+	"shorten": {"shorten [-team ID] URL...", true, runShorten},
+	"batch":   {"batch [FILE]", true, runBatch},
+	"ls":      {"ls [-limit N] [-offset N] [-owner personal|team|all] [-team ID]", true, runList},
+	"rm":      {"rm SHORT_CODE...", true, runRemove},
+	"resolve": {"resolve SHORT_CODE", false, runResolve},
+	"stats":   {"stats", true, runStats},
+}
+
+func main() {
+	if err := run(context.Background(), os.Args[1:], os.Stdin, os.Stdout); err != nil {
+		fmt.Fprintln(os.Stderr, "shortenctl:", err)
+		os.Exit(1)
+	}
+}
+
+func run(ctx context.Context, args []string, stdin io.Reader, stdout io.Writer) error {
+	fs := flag.NewFlagSet("shortenctl", flag.ContinueOnError)
+	configPath := fs.String("config", defaultConfigPath(), "config file path")
+	server := fs.String("server", "", "server base URL, saved on login")
+	output := fs.String("o", outputTable, "output format: table or json")
+	gzip := fs.Bool("gzip", false, "compress request bodies")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: shortenctl [flags] COMMAND [args]\n\ncommands:")
+		names := make([]string, 0, len(commands))
+		for name := range commands {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			fmt.Fprintln(fs.Output(), "  "+commands[name].usage)
+		}
+		fmt.Fprintln(fs.Output(), "\nflags:")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return errors.New("no command")
+	}
+	cmd, ok := commands[fs.Arg(0)]
+	if !ok {
+		return fmt.Errorf("unknown command %q", fs.Arg(0))
+	}
+	if *output != outputTable && *output != outputJSON {
+		return fmt.Errorf("unknown output format %q", *output)
+	}
+
+	cfg, err := loadConfig(*configPath)
+	if err != nil {
+		return err
+	}
+	if *server != "" {
+		cfg.Server = *server
+	}
+	if cfg.Server == "" {
+		cfg.Server = defaultServer
+	}
+	if cmd.needLogin && !cfg.loggedIn() {
+		return errNotLoggedIn
+	}
+	e := &env{
+		cfg:        cfg,
+		configPath: *configPath,
+		gzip:       *gzip,
+		out:        &printer{w: stdout, format: *output},
+		stdin:      stdin,
+	}
+	if e.client, err = e.newClient(cfg.Token, cfg.RefreshToken); err != nil {
+		return err
+	}
+	err = cmd.run(ctx, e, fs.Args()[1:])
+	var apiErr *client.APIError
+	if errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusUnauthorized && cfg.loggedIn() {
+		err = errSessionExpired
+	}
+	return errors.Join(err, e.saveErr)
+}
+
+// newClient saves tokens the server rotates right away, since the refresh
+// token sent with the request is spent even if the command fails later on.
+// Tokens are only saved for a logged in config; login saves its own.
+func (e *env) newClient(token, refreshToken string) (*client.Client, error) {
+	opts := []client.Option{
+		client.WithBearer(token),
+		client.WithRefreshToken(refreshToken),
+		client.WithTokenHook(func(token, refreshToken string) {
+			if !e.cfg.loggedIn() {
+				return
+			}
+			e.cfg.Token, e.cfg.RefreshToken = token, refreshToken
+			e.saveErr = saveConfig(e.configPath, e.cfg)
+		}),
+	}
+	if e.gzip {
+		opts = append(opts, client.WithGzip())
+	}
+	return client.New(e.cfg.Server, opts...)
+}
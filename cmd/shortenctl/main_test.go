@@ -0,0 +1,126 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/domurdoc/shortener/internal/auth"
+	"github.com/domurdoc/shortener/internal/auth/strategy"
+	"github.com/domurdoc/shortener/internal/auth/transport"
+	"github.com/domurdoc/shortener/internal/handler"
+	"github.com/domurdoc/shortener/internal/repository/mem"
+	"github.com/domurdoc/shortener/internal/router"
+	"github.com/domurdoc/shortener/internal/service"
+	"github.com/domurdoc/shortener/pkg/client"
+)
+
+func newTestServer(t *testing.T) *httptest.Server {
+	server := httptest.NewUnstartedServer(nil)
+	svc := service.New("http://"+server.Listener.Addr().String(), 1, 1, time.Millisecond, mem.NewMemRecordRepo(), zap.NewNop().Sugar(), nil, service.Options{})
+	refresher := auth.NewRefresher(
+		transport.NewMulti(transport.NewBearer("X-Refresh-Token"), transport.NewCookie("refresh", 3600, false)),
+		mem.NewMemRefreshTokenRepo(),
+		time.Hour,
+		0,
+	)
+	a := auth.New(
+		strategy.NewJWT("secret", time.Hour),
+		transport.NewMulti(transport.NewBearer("Authorization"), transport.NewCookie("access", 3600, false)),
+		mem.NewMemUserRepo(),
+		refresher,
+	)
+	server.Config.Handler = router.New(handler.New(svc, nil, nil), handler.NewAdmin(nil), nil, auth.NewAuthMiddleware(a, true))
+	server.Start()
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestRun(t *testing.T) {
+	server := newTestServer(t)
+	ctx := context.Background()
+	configPath := filepath.Join(t.TempDir(), "config.json")
+
+	shortenctl := func(args ...string) (string, error) {
+		var out bytes.Buffer
+		args = append([]string{"-config", configPath, "-server", server.URL}, args...)
+		err := run(ctx, args, strings.NewReader(""), &out)
+		return out.String(), err
+	}
+	list := func() []client.URLRecord {
+		out, err := shortenctl("-o", "json", "ls")
+		require.NoError(t, err)
+		var urls []client.URLRecord
+		require.NoError(t, json.Unmarshal([]byte(out), &urls))
+		return urls
+	}
+	load := func() *config {
+		cfg, err := loadConfig(configPath)
+		require.NoError(t, err)
+		return cfg
+	}
+
+	_, err := shortenctl("ls")
+	require.ErrorIs(t, err, errNotLoggedIn)
+
+	_, err = shortenctl("login")
+	require.NoError(t, err)
+	cfg := load()
+	require.NotEmpty(t, cfg.Token)
+	require.NotEmpty(t, cfg.RefreshToken)
+
+	out, err := shortenctl("shorten", "http://example.com")
+	require.NoError(t, err)
+	assert.Contains(t, out, "http://example.com")
+	require.Len(t, list(), 1)
+
+	t.Run("Refreshes an expired access token", func(t *testing.T) {
+		cfg := load()
+		refreshToken := cfg.RefreshToken
+		cfg.Token = "expired"
+		require.NoError(t, saveConfig(configPath, cfg))
+
+		require.Len(t, list(), 1)
+		cfg = load()
+		assert.NotEqual(t, "expired", cfg.Token)
+		assert.NotEqual(t, refreshToken, cfg.RefreshToken)
+	})
+
+	t.Run("Login keeps the saved user", func(t *testing.T) {
+		cfg := load()
+		cfg.Token = ""
+		require.NoError(t, saveConfig(configPath, cfg))
+
+		_, err := shortenctl("login")
+		require.NoError(t, err)
+		assert.Len(t, list(), 1)
+	})
+
+	t.Run("Login does not register over a dead session", func(t *testing.T) {
+		saved := load()
+		require.NoError(t, saveConfig(configPath, &config{Server: saved.Server, Token: "expired", RefreshToken: "revoked"}))
+
+		_, err := shortenctl("login")
+		require.ErrorIs(t, err, errSessionExpired)
+		_, err = shortenctl("ls")
+		require.ErrorIs(t, err, errSessionExpired)
+		assert.Equal(t, "revoked", load().RefreshToken)
+
+		require.NoError(t, saveConfig(configPath, saved))
+	})
+
+	t.Run("Login -new registers a new user", func(t *testing.T) {
+		_, err := shortenctl("login", "-new")
+		require.NoError(t, err)
+		assert.Empty(t, list())
+	})
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"errors"
+	"os"
+	"strings"
+
+	"github.com/domurdoc/shortener/internal/backup"
+	"github.com/domurdoc/shortener/internal/config/db"
+	dbRepo "github.com/domurdoc/shortener/internal/repository/db"
+	fileRepo "github.com/domurdoc/shortener/internal/repository/file"
+	"github.com/domurdoc/shortener/internal/repository/file/serializer"
+	memRepo "github.com/domurdoc/shortener/internal/repository/mem"
+)
+
+func defaultBackend() string {
+	if dsn := os.Getenv("DATABASE_DSN"); dsn != "" {
+		return dsn
+	}
+	return os.Getenv("FILE_STORAGE_PATH")
+}
+
+func isPostgres(spec string) bool {
+	return strings.HasPrefix(spec, "postgres://") || strings.HasPrefix(spec, "postgresql://")
+}
+
+// openBackend migrates Postgres before use. The file backend keeps only
+// records, its users, teams, identities and refresh tokens live in memory for
+// the time of the command.
+func openBackend(spec string) (backup.Repos, func() error, error) {
+	if spec == "" {
+		return backup.Repos{}, nil, errors.New("no backend set")
+	}
+	if isPostgres(spec) {
+		pgDB, err := db.NewPG(spec)
+		if err != nil {
+			return backup.Repos{}, nil, err
+		}
+		if err := db.MigratePG(pgDB); err != nil {
+			return backup.Repos{}, nil, errors.Join(err, pgDB.Close())
+		}
+		return backup.Repos{
+			Records:    dbRepo.NewDBRecordRepo(pgDB, db.NewPGArger),
+			Users:      dbRepo.NewDBUserRepo(pgDB, db.NewPGArger),
+			Teams:      dbRepo.NewDBTeamRepo(pgDB, db.NewPGArger),
+			Identities: dbRepo.NewDBIdentityRepo(pgDB, db.NewPGArger),
+			Tokens:     dbRepo.NewDBRefreshTokenRepo(pgDB, db.NewPGArger),
+		}, pgDB.Close, nil
+	}
+	repo, err := fileRepo.New(strings.TrimPrefix(spec, "file:"), serializer.NewJSONSerializer())
+	if err != nil {
+		return backup.Repos{}, nil, err
+	}
+	return backup.Repos{
+		Records:    repo,
+		Users:      memRepo.NewMemUserRepo(),
+		Teams:      memRepo.NewMemTeamRepo(),
+		Identities: memRepo.NewMemIdentityRepo(),
+		Tokens:     memRepo.NewMemRefreshTokenRepo(),
+	}, func() error { return nil }, nil
+}
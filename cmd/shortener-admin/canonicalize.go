@@ -0,0 +1,43 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+
+	"github.com/domurdoc/shortener/internal/service"
+)
+
+// runCanonicalize backfills the canonical form with the options the server
+// runs with, records stored before canonicalization keep their URL as sent.
+func runCanonicalize(ctx context.Context, args []string, _ io.Reader, stdout io.Writer) (err error) {
+	fs := flag.NewFlagSet("canonicalize", flag.ContinueOnError)
+	on := fs.String("on", defaultBackend(), "backend")
+	sortQuery := fs.Bool("sort-query", envBool("CANONICAL_SORT_QUERY"), "sort query parameters")
+	stripTracking := fs.Bool("strip-tracking", envBool("CANONICAL_STRIP_TRACKING"), "remove tracking parameters")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	repos, closeRepos, err := openBackend(*on)
+	if err != nil {
+		return err
+	}
+	defer func() { err = errors.Join(err, closeRepos()) }()
+
+	canonicalizer := service.NewCanonicalizer(*sortQuery, *stripTracking, false)
+	updated, skipped, err := canonicalizer.Backfill(ctx, repos.Records)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(stdout, "updated %d records, skipped %d\n", updated, skipped)
+	return nil
+}
+
+func envBool(name string) bool {
+	value, _ := strconv.ParseBool(os.Getenv(name))
+	return value
+}
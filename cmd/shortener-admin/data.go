@@ -0,0 +1,103 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/domurdoc/shortener/internal/backup"
+)
+
+func runExport(ctx context.Context, args []string, _ io.Reader, stdout io.Writer) (err error) {
+	fs := flag.NewFlagSet("export", flag.ContinueOnError)
+	from := fs.String("from", defaultBackend(), "source backend")
+	output := fs.String("o", "-", "output file, - for stdout")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	src, closeSrc, err := openBackend(*from)
+	if err != nil {
+		return err
+	}
+	defer func() { err = errors.Join(err, closeSrc()) }()
+
+	w := stdout
+	if *output != "-" {
+		f, err := os.Create(*output)
+		if err != nil {
+			return err
+		}
+		defer func() { err = errors.Join(err, f.Close()) }()
+		w = f
+	}
+	summary, err := backup.Export(ctx, w, src)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(os.Stderr, "exported %s\n", summary)
+	return nil
+}
+
+func runImport(ctx context.Context, args []string, stdin io.Reader, stdout io.Writer) (err error) {
+	fs := flag.NewFlagSet("import", flag.ContinueOnError)
+	to := fs.String("to", defaultBackend(), "destination backend")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	dst, closeDst, err := openBackend(*to)
+	if err != nil {
+		return err
+	}
+	defer func() { err = errors.Join(err, closeDst()) }()
+
+	r := stdin
+	if fs.NArg() > 0 && fs.Arg(0) != "-" {
+		f, err := os.Open(fs.Arg(0))
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		r = f
+	}
+	summary, err := backup.Import(ctx, r, dst)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(stdout, "imported %s\n", summary)
+	return nil
+}
+
+func runCopy(ctx context.Context, args []string, _ io.Reader, stdout io.Writer) (err error) {
+	fs := flag.NewFlagSet("copy", flag.ContinueOnError)
+	from := fs.String("from", "", "source backend")
+	to := fs.String("to", "", "destination backend")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *from == "" || *to == "" {
+		return errors.New("both -from and -to must be set")
+	}
+	if *from == *to {
+		return errors.New("source and destination are the same")
+	}
+	src, closeSrc, err := openBackend(*from)
+	if err != nil {
+		return err
+	}
+	defer func() { err = errors.Join(err, closeSrc()) }()
+	dst, closeDst, err := openBackend(*to)
+	if err != nil {
+		return err
+	}
+	defer func() { err = errors.Join(err, closeDst()) }()
+
+	summary, err := backup.Copy(ctx, src, dst)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(stdout, "copied %s\n", summary)
+	return nil
+}
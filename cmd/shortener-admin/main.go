@@ -0,0 +1,64 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"sort"
+)
+
+type command struct {
+	usage string
+	run   func(ctx context.Context, args []string, stdin io.Reader, stdout io.Writer) error
+}
+
+var commands = map[string]command{
+	"migrate": {"migrate [-d DSN] up | down [N] | status | create NAME", runMigrate},
+	"export":  {"export [-from BACKEND] [-o FILE]", runExport},
+	"import":  {"import [-to BACKEND] [FILE]", runImport},
+	"copy":    {"copy -from BACKEND -to BACKEND", runCopy},
+	"canonicalize": {
+		"canonicalize [-on BACKEND] [-sort-query] [-strip-tracking]",
+		runCanonicalize,
+	},
+}
+
+func main() {
+	log.SetFlags(0)
+	log.SetPrefix("shortener-admin: ")
+	if err := run(context.Background(), os.Args[1:], os.Stdin, os.Stdout); err != nil {
+		log.Fatal(err)
+	}
+}
+
+func run(ctx context.Context, args []string, stdin io.Reader, stdout io.Writer) error {
+	if len(args) == 0 {
+		usage()
+		return errors.New("no command")
+	}
+	cmd, ok := commands[args[0]]
+	if !ok {
+		usage()
+		return fmt.Errorf("unknown command %q", args[0])
+	}
+	return cmd.run(ctx, args[1:], stdin, stdout)
+}
+
+func usage() {
+	fmt.Fprintln(os.Stderr, "usage: shortener-admin COMMAND [args]\n\ncommands:")
+	names := make([]string, 0, len(commands))
+	for name := range commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Fprintln(os.Stderr, "  "+commands[name].usage)
+	}
+	fmt.Fprintln(os.Stderr, `
+BACKEND is a postgres:// DSN or a path to a file storage, optionally prefixed
+with file:. It defaults to DATABASE_DSN, then FILE_STORAGE_PATH. import and
+copy need an empty destination.`)
+}
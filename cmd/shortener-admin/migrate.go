@@ -0,0 +1,127 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+
+	"github.com/golang-migrate/migrate/v4"
+
+	"github.com/domurdoc/shortener/internal/config/db"
+	"github.com/domurdoc/shortener/migrations"
+)
+
+var migrationName = regexp.MustCompile(`^(\d+)_\w+\.(up|down)\.sql$`)
+
+func runMigrate(ctx context.Context, args []string, _ io.Reader, stdout io.Writer) (err error) {
+	flags := flag.NewFlagSet("migrate", flag.ContinueOnError)
+	dsn := flags.String("d", os.Getenv("DATABASE_DSN"), "database DSN")
+	dir := flags.String("dir", "migrations", "directory for new migrations")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.Arg(0) == "create" {
+		if flags.NArg() != 2 {
+			return errors.New("usage: migrate create NAME")
+		}
+		return createMigration(*dir, flags.Arg(1), stdout)
+	}
+	if *dsn == "" {
+		return errors.New("database DSN must be set with -d or DATABASE_DSN")
+	}
+	pgDB, err := db.NewPG(*dsn)
+	if err != nil {
+		return err
+	}
+	defer func() { err = errors.Join(err, pgDB.Close()) }()
+	m, err := db.NewPGMigrator(pgDB)
+	if err != nil {
+		return err
+	}
+	defer func() { err = errors.Join(err, db.CloseMigrator(m)) }()
+
+	switch flags.Arg(0) {
+	case "up":
+		err = m.Up()
+	case "down":
+		steps := 1
+		if flags.NArg() > 1 {
+			if steps, err = strconv.Atoi(flags.Arg(1)); err != nil || steps <= 0 {
+				return errors.New("down takes a positive number of steps")
+			}
+		}
+		err = m.Steps(-steps)
+	case "status":
+		return printStatus(m, stdout)
+	default:
+		return errors.New("usage: migrate up | down [N] | status | create NAME")
+	}
+	if err != nil && !errors.Is(err, migrate.ErrNoChange) {
+		return err
+	}
+	return printStatus(m, stdout)
+}
+
+func printStatus(m *migrate.Migrate, stdout io.Writer) error {
+	latest, err := latestMigration(migrations.FS)
+	if err != nil {
+		return err
+	}
+	version, dirty, err := m.Version()
+	if errors.Is(err, migrate.ErrNilVersion) {
+		fmt.Fprintf(stdout, "version: none, latest: %d\n", latest)
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(stdout, "version: %d, latest: %d, dirty: %t\n", version, latest, dirty)
+	return nil
+}
+
+func latestMigration(fsys fs.FS) (uint64, error) {
+	entries, err := fs.ReadDir(fsys, ".")
+	if err != nil {
+		return 0, err
+	}
+	var latest uint64
+	for _, entry := range entries {
+		match := migrationName.FindStringSubmatch(entry.Name())
+		if match == nil {
+			continue
+		}
+		version, err := strconv.ParseUint(match[1], 10, 64)
+		if err != nil {
+			return 0, err
+		}
+		latest = max(latest, version)
+	}
+	return latest, nil
+}
+
+// createMigration follows the sequential naming of `migrate create -seq`.
+func createMigration(dir, name string, stdout io.Writer) error {
+	latest, err := latestMigration(os.DirFS(dir))
+	if err != nil {
+		return err
+	}
+	for _, direction := range []string{"up", "down"} {
+		path := filepath.Join(dir, fmt.Sprintf("%06d_%s.%s.sql", latest+1, name, direction))
+		f, err := os.OpenFile(path, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0o644)
+		if err != nil {
+			return err
+		}
+		if err := f.Close(); err != nil {
+			return err
+		}
+		fmt.Fprintln(stdout, path)
+	}
+	return nil
+}
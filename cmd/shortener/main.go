@@ -3,8 +3,11 @@ package main
 import (
 	"fmt"
 	"log"
+	"net"
 	"net/http"
 
+	"google.golang.org/grpc"
+
 	"github.com/domurdoc/shortener/internal/app"
 	"github.com/domurdoc/shortener/internal/auth"
 	"github.com/domurdoc/shortener/internal/compressor"
@@ -12,6 +15,8 @@ import (
 	"github.com/domurdoc/shortener/internal/httputil"
 	"github.com/domurdoc/shortener/internal/logger"
 	"github.com/domurdoc/shortener/internal/router"
+	"github.com/domurdoc/shortener/internal/rpc"
+	pb "github.com/domurdoc/shortener/pkg/api/shortener/v1"
 )
 
 func main() {
@@ -27,15 +32,44 @@ func main() {
 		"logLevel", a.Options.LogLevel,
 		"fileStoragePath", a.Options.FileStoragePath,
 		"databaseDSN", a.Options.DatabaseDSN,
+		"oidcIssuer", a.Options.OIDCIssuer,
+		"grpcAddr", a.Options.GRPCAddr,
 		"repo", fmt.Sprintf("%T", a.RecordRepo),
 	)
-	handler := handler.New(a.Service)
-	router := router.New(handler)
+	adminHandler := handler.NewAdmin(a.Admin)
+	var oidcHandler *handler.OIDCHandler
+	if a.OIDC != nil {
+		oidcHandler = handler.NewOIDC(a.OIDC, a.Auth, a.Identities, a.Options.JWTSecret.String(), a.Log)
+	}
+	handler := handler.New(a.Service, a.Teams, a.Idempotency)
+	router := router.New(
+		handler,
+		adminHandler,
+		oidcHandler,
+		auth.NewAuthMiddleware(a.Auth, bool(a.Options.AllowAnonymous)),
+		auth.NewCSRFMiddleware(a.Options.BaseURL.String()),
+	)
 	router = httputil.AddMiddlewares(
 		router,
 		logger.NewRequestLogger(a.Log),
-		auth.NewAuthMiddleware(a.Auth),
 		compressor.GZIPMiddleware,
 	)
+	if a.Options.GRPCAddr != "" {
+		go func() {
+			log.Fatal(serveGRPC(a))
+		}()
+	}
 	log.Fatal(http.ListenAndServe(a.Options.Addr.String(), router))
 }
+
+func serveGRPC(a *app.App) error {
+	listener, err := net.Listen("tcp", a.Options.GRPCAddr.String())
+	if err != nil {
+		return err
+	}
+	server := grpc.NewServer(grpc.UnaryInterceptor(
+		auth.NewUnaryInterceptor(a.Auth, bool(a.Options.AllowAnonymous), a.Log, rpc.PublicMethods...),
+	))
+	pb.RegisterShortenerServiceServer(server, rpc.New(a.Service, a.Teams, a.Log))
+	return server.Serve(listener)
+}
@@ -1,18 +1,26 @@
 package app
 
 import (
+	"context"
 	"database/sql"
 	"errors"
+	"fmt"
+	"net"
+	"net/url"
 	"time"
 
 	"go.uber.org/zap"
 
 	"github.com/domurdoc/shortener/internal/auth"
+	"github.com/domurdoc/shortener/internal/auth/oidc"
 	"github.com/domurdoc/shortener/internal/auth/strategy"
 	"github.com/domurdoc/shortener/internal/auth/transport"
 	"github.com/domurdoc/shortener/internal/config"
 	"github.com/domurdoc/shortener/internal/config/db"
+	"github.com/domurdoc/shortener/internal/denylist"
 	"github.com/domurdoc/shortener/internal/logger"
+	"github.com/domurdoc/shortener/internal/model"
+	"github.com/domurdoc/shortener/internal/policy"
 	"github.com/domurdoc/shortener/internal/repository"
 	dbRepo "github.com/domurdoc/shortener/internal/repository/db"
 	fileRepo "github.com/domurdoc/shortener/internal/repository/file"
@@ -22,13 +30,23 @@ import (
 )
 
 type App struct {
-	Options    *config.Options
-	RecordRepo repository.RecordRepo
-	UserRepo   repository.UserRepo
-	Log        *zap.SugaredLogger
-	Service    *service.Service
-	DB         *sql.DB
-	Auth       *auth.Auth
+	Options         *config.Options
+	RecordRepo      repository.RecordRepo
+	UserRepo        repository.UserRepo
+	TokenRepo       repository.RefreshTokenRepo
+	TeamRepo        repository.TeamRepo
+	IdentityRepo    repository.IdentityRepo
+	IdempotencyRepo repository.IdempotencyRepo
+	Log             *zap.SugaredLogger
+	Service         *service.Service
+	Admin           *service.AdminService
+	Teams           *service.TeamService
+	Identities      *service.IdentityService
+	Idempotency     *service.IdempotencyService
+	Policy          *policy.Policy
+	OIDC            *oidc.Provider
+	DB              *sql.DB
+	Auth            *auth.Auth
 }
 
 func New() (*App, error) {
@@ -45,6 +63,9 @@ func New() (*App, error) {
 	if err := a.initAuth(); err != nil {
 		return nil, errors.Join(err, a.Close())
 	}
+	if err := a.initAdmins(); err != nil {
+		return nil, errors.Join(err, a.Close())
+	}
 	return a, nil
 }
 
@@ -54,6 +75,9 @@ func (a *App) Close() error {
 	if a.Service != nil {
 		errs = append(errs, a.Service.Close())
 	}
+	if a.Policy != nil {
+		errs = append(errs, a.Policy.Close())
+	}
 	if a.Log != nil {
 		errs = append(errs, a.Log.Sync())
 	}
@@ -84,6 +108,10 @@ func (a *App) initRepo() error {
 		}
 		a.RecordRepo = dbRepo.NewDBRecordRepo(pgDB, db.NewPGArger)
 		a.UserRepo = dbRepo.NewDBUserRepo(pgDB, db.NewPGArger)
+		a.TokenRepo = dbRepo.NewDBRefreshTokenRepo(pgDB, db.NewPGArger)
+		a.TeamRepo = dbRepo.NewDBTeamRepo(pgDB, db.NewPGArger)
+		a.IdentityRepo = dbRepo.NewDBIdentityRepo(pgDB, db.NewPGArger)
+		a.IdempotencyRepo = dbRepo.NewDBIdempotencyRepo(pgDB, db.NewPGArger)
 	} else if a.Options.FileStoragePath.String() != "" {
 		jsonSerializer := serializer.NewJSONSerializer()
 		repo, err := fileRepo.New(
@@ -95,14 +123,47 @@ func (a *App) initRepo() error {
 		}
 		a.RecordRepo = repo
 		a.UserRepo = memRepo.NewMemUserRepo()
+		a.TokenRepo = memRepo.NewMemRefreshTokenRepo()
+		a.TeamRepo = memRepo.NewMemTeamRepo()
+		a.IdentityRepo = memRepo.NewMemIdentityRepo()
+		a.IdempotencyRepo = memRepo.NewMemIdempotencyRepo()
 	} else {
 		a.RecordRepo = memRepo.NewMemRecordRepo()
 		a.UserRepo = memRepo.NewMemUserRepo()
+		a.TokenRepo = memRepo.NewMemRefreshTokenRepo()
+		a.TeamRepo = memRepo.NewMemTeamRepo()
+		a.IdentityRepo = memRepo.NewMemIdentityRepo()
+		a.IdempotencyRepo = memRepo.NewMemIdempotencyRepo()
 	}
 	return nil
 }
 
 func (a *App) initService() error {
+	codes, err := a.newCodeGenerator()
+	if err != nil {
+		return err
+	}
+	codeDenylist, err := a.newDenylist()
+	if err != nil {
+		return err
+	}
+	destinations, err := a.newPolicy()
+	if err != nil {
+		return err
+	}
+	a.Policy = destinations
+	canonicalizer := service.NewCanonicalizer(
+		bool(a.Options.CanonicalSortQuery),
+		bool(a.Options.CanonicalStripTrack),
+		a.Options.RedirectURLForm.String() == config.URLFormCanonical,
+	)
+	// records stored before canonical URLs, or with other canonical options,
+	// would not dedup with new ones. Backfill pages through the records.
+	updated, skipped, err := canonicalizer.Backfill(context.Background(), a.RecordRepo)
+	if err != nil {
+		return err
+	}
+	a.Log.Infow("canonical URLs backfilled", "updated", updated, "skipped", skipped)
 	a.Service = service.New(
 		a.Options.BaseURL.String(),
 		int(a.Options.DeleterMaxWorkers),
@@ -111,20 +172,148 @@ func (a *App) initService() error {
 		a.RecordRepo,
 		a.Log,
 		a.DB,
+		service.Options{
+			Codes:         codes,
+			Denylist:      codeDenylist,
+			Canonicalizer: canonicalizer,
+			Destinations:  destinations,
+			PasswordAttempts: service.NewAttemptLimiter(
+				int(a.Options.PasswordMaxAttempts),
+				int(a.Options.PasswordLinkAttempts),
+				time.Duration(a.Options.PasswordWindow),
+			),
+			PendingFallbackURL: a.Options.PendingFallbackURL.String(),
+			RedirectStatus:     a.Options.RedirectStatus.Code(),
+			RedirectMaxAge:     time.Duration(a.Options.RedirectCacheMaxAge),
+		},
+	)
+	a.Teams = service.NewTeam(a.TeamRepo, a.Service)
+	a.Identities = service.NewIdentity(a.IdentityRepo, a.UserRepo)
+	a.Idempotency = service.NewIdempotency(a.IdempotencyRepo, time.Duration(a.Options.IdempotencyTTL))
+	a.Admin = service.NewAdmin(
+		a.Options.BaseURL.String(),
+		a.RecordRepo,
+		a.UserRepo,
 	)
 	return nil
 }
 
+func (a *App) newCodeGenerator() (service.CodeGenerator, error) {
+	length := int(a.Options.ShortCodeLength)
+	if length < 1 || length > service.MaxShortCodeLength {
+		return nil, fmt.Errorf("short code length must be between 1 and %d", service.MaxShortCodeLength)
+	}
+	alphabet := a.Options.ShortCodeAlphabet.Chars()
+	growth := float64(a.Options.ShortCodeGrowth)
+	switch a.Options.ShortCodeGenerator.String() {
+	case config.CodeGeneratorSequence:
+		return service.NewSequenceCodeGenerator(a.RecordRepo, alphabet, length), nil
+	case config.CodeGeneratorHashids:
+		return service.NewHashidsCodeGenerator(a.RecordRepo, alphabet, a.Options.ShortCodeSalt.String(), length), nil
+	case config.CodeGeneratorHash:
+		return service.NewHashCodeGenerator(alphabet, length, growth), nil
+	}
+	return service.NewRandomCodeGenerator(alphabet, length, growth), nil
+}
+
+func (a *App) newDenylist() (*denylist.Denylist, error) {
+	if path := a.Options.DenylistPath.String(); path != "" {
+		return denylist.Load(path, bool(a.Options.DenylistBuiltin))
+	}
+	return denylist.New(nil, bool(a.Options.DenylistBuiltin)), nil
+}
+
+func (a *App) newPolicy() (*policy.Policy, error) {
+	blocked, err := policy.ParsePrefixes(a.Options.BlockedRanges)
+	if err != nil {
+		return nil, err
+	}
+	var resolver policy.Resolver
+	if a.Options.ResolveDestinations {
+		resolver = net.DefaultResolver
+	}
+	var domains *policy.DomainList
+	if path := a.Options.DomainDenylistPath.String(); path != "" {
+		domains, err = policy.LoadDomainList(path, time.Duration(a.Options.DomainDenylistReload), a.Log)
+		if err != nil {
+			return nil, err
+		}
+	}
+	var threats *policy.Blocklist
+	if path := a.Options.BlocklistPath.String(); path != "" {
+		threats, err = policy.LoadBlocklist(path, time.Duration(a.Options.BlocklistReload), a.Log)
+		if err != nil {
+			return nil, errors.Join(err, domains.Close())
+		}
+	}
+	p, err := policy.New(a.Options.AllowedSchemes, blocked, resolver, a.Options.BaseURL.String(), domains, threats)
+	if err != nil {
+		return nil, errors.Join(err, domains.Close(), threats.Close())
+	}
+	return p, nil
+}
+
 func (a *App) initAuth() error {
 	strategy := strategy.NewJWT(
 		a.Options.JWTSecret.String(),
 		time.Duration(a.Options.JWTDuration),
 	)
-	transport := transport.NewCookie(
-		a.Options.CookieName.String(),
-		int(time.Duration(a.Options.CookieMaxAge).Seconds()),
-		false,
+	accessTransport := transport.NewMulti(
+		transport.NewBearer("Authorization"),
+		transport.NewCookie(
+			a.Options.CookieName.String(),
+			int(time.Duration(a.Options.CookieMaxAge).Seconds()),
+			false,
+		),
 	)
-	a.Auth = auth.New(strategy, transport, a.UserRepo)
+	refreshTransport := transport.NewMulti(
+		transport.NewBearer("X-Refresh-Token"),
+		transport.NewCookie(
+			a.Options.RefreshCookieName.String(),
+			int(time.Duration(a.Options.RefreshTokenDuration).Seconds()),
+			false,
+		),
+	)
+	refresher := auth.NewRefresher(
+		refreshTransport,
+		a.TokenRepo,
+		time.Duration(a.Options.RefreshTokenDuration),
+		time.Duration(a.Options.RefreshTokenLeeway),
+	)
+	a.Auth = auth.New(strategy, accessTransport, a.UserRepo, refresher)
+	if a.Options.OIDCIssuer != "" {
+		redirectURL := a.Options.OIDCRedirectURL.String()
+		if redirectURL == "" {
+			var err error
+			redirectURL, err = url.JoinPath(a.Options.BaseURL.String(), "/api/auth/oidc/callback")
+			if err != nil {
+				return err
+			}
+		}
+		a.OIDC = oidc.NewProvider(
+			a.Options.OIDCIssuer.String(),
+			a.Options.OIDCClientID.String(),
+			a.Options.OIDCClientSecret.String(),
+			redirectURL,
+			nil,
+		)
+	}
+	return nil
+}
+
+func (a *App) initAdmins() error {
+	for _, userID := range a.Options.AdminUserIDs {
+		err := a.Admin.SetUserRole(context.Background(), model.UserID(userID), model.RoleAdmin)
+		var notFoundErr *model.UserNotFoundError
+		if errors.As(err, &notFoundErr) {
+			// granting the role on creation would hand it to whoever signs
+			// up next with that ID
+			a.Log.Errorw("admin user not found, the role is granted to existing users only", "userID", userID)
+			continue
+		}
+		if err != nil {
+			return err
+		}
+	}
 	return nil
 }
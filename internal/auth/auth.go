@@ -4,6 +4,7 @@ import (
 	"context"
 	"errors"
 	"net/http"
+	"time"
 
 	"github.com/domurdoc/shortener/internal/auth/strategy"
 	"github.com/domurdoc/shortener/internal/auth/transport"
@@ -15,55 +16,179 @@ type Auth struct {
 	strategy  strategy.Strategy
 	transport transport.Transport
 	repo      repository.UserRepo
+	refresher *Refresher
 }
 
-func New(strategy strategy.Strategy, transport transport.Transport, repo repository.UserRepo) *Auth {
+// refresher may be nil, then access tokens are never reissued.
+func New(
+	strategy strategy.Strategy,
+	transport transport.Transport,
+	repo repository.UserRepo,
+	refresher *Refresher,
+) *Auth {
 	return &Auth{
 		strategy:  strategy,
 		transport: transport,
 		repo:      repo,
+		refresher: refresher,
 	}
 }
 
 func (a *Auth) Authenticate(ctx context.Context, r *http.Request) (*model.User, error) {
-	tokenString, err := a.transport.Read(r)
-	if err != nil {
-		return nil, &NoTokenError{err}
-	}
-	user, err := a.strategy.ReadToken(ctx, tokenString, a.repo)
-	if err != nil {
-		return nil, &InvalidTokenError{err}
-	}
-	return user, nil
+	user, _, _, err := a.authenticate(ctx, r)
+	return user, err
 }
 
+// Login starts a new session, the client has no transport of its own yet and
+// gets the tokens through all of them.
 func (a *Auth) Login(ctx context.Context, w http.ResponseWriter, user *model.User) error {
-	tokenString, err := a.strategy.WriteToken(ctx, user)
-	if err != nil {
+	if err := a.writeAccessToken(ctx, w, user, nil); err != nil {
 		return err
 	}
-	return a.transport.Write(w, tokenString)
+	if a.refresher != nil {
+		return a.refresher.Issue(ctx, w, user.ID, "")
+	}
+	return nil
 }
 
 func (a *Auth) Register(ctx context.Context) (*model.User, error) {
 	return a.repo.CreateUser(ctx)
 }
 
-func (a *Auth) AuthenticateOrRegisterAndLogin(ctx context.Context, w http.ResponseWriter, r *http.Request) (*model.User, error) {
-	user, err := a.Authenticate(ctx, r)
+func (a *Auth) Refresh(ctx context.Context, w http.ResponseWriter, r *http.Request) (*model.User, error) {
+	user, _, err := a.refresh(ctx, w, r)
+	return user, err
+}
+
+// refresh also returns the transport the refresh token came in.
+func (a *Auth) refresh(ctx context.Context, w http.ResponseWriter, r *http.Request) (*model.User, transport.Transport, error) {
+	if a.refresher == nil {
+		return nil, nil, &NoTokenError{errors.New("refresh tokens are disabled")}
+	}
+	userID, via, err := a.refresher.Rotate(ctx, w, r)
 	if err != nil {
+		return nil, nil, err
+	}
+	user, err := a.getEnabledUser(ctx, userID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := a.writeAccessToken(ctx, w, user, via); err != nil {
+		return nil, nil, err
+	}
+	return user, via, nil
+}
+
+// AuthenticateOrRefresh returns NoTokenError when the request carries neither
+// an access nor a refresh token. An expiring access token without a refresh
+// token is not renewed, it would outlive the refresh token and its family.
+func (a *Auth) AuthenticateOrRefresh(ctx context.Context, w http.ResponseWriter, r *http.Request) (*model.User, error) {
+	user, _, err := a.authenticateOrRefresh(ctx, w, r)
+	return user, err
+}
+
+// authenticateOrRefresh also returns the transport that authenticated the
+// request.
+func (a *Auth) authenticateOrRefresh(ctx context.Context, w http.ResponseWriter, r *http.Request) (*model.User, transport.Transport, error) {
+	user, expiresAt, via, err := a.authenticate(ctx, r)
+	if err == nil && (a.refresher == nil || !a.refresher.expiresSoon(expiresAt)) {
+		return user, via, nil
+	}
+	if a.refresher != nil {
+		refreshedUser, refreshVia, refreshErr := a.refresh(ctx, w, r)
+		if refreshErr == nil {
+			return refreshedUser, refreshVia, nil
+		}
 		var noTokenErr *NoTokenError
-		if errors.As(err, &noTokenErr) {
-			user, err = a.Register(ctx)
-			if err != nil {
-				return nil, err
-			}
-			if err = a.Login(ctx, w, user); err != nil {
-				return nil, err
-			}
-			return user, nil
+		if !errors.As(refreshErr, &noTokenErr) {
+			a.clearRejected(w, err, refreshErr)
+			return nil, nil, refreshErr
 		}
+	}
+	if err == nil {
+		return user, via, nil
+	}
+	a.clearRejected(w, err, nil)
+	return nil, nil, err
+}
+
+// clearRejected drops invalid tokens from the client. Otherwise a browser
+// keeps sending a revoked cookie and gets 401 on every request instead of
+// starting a new session.
+func (a *Auth) clearRejected(w http.ResponseWriter, accessErr, refreshErr error) {
+	var invalidTokenErr *InvalidTokenError
+	if errors.As(accessErr, &invalidTokenErr) {
+		a.transport.Clear(w)
+	}
+	if errors.As(refreshErr, &invalidTokenErr) {
+		a.refresher.transport.Clear(w)
+	}
+}
+
+func (a *Auth) AuthenticateOrRegisterAndLogin(ctx context.Context, w http.ResponseWriter, r *http.Request) (*model.User, error) {
+	user, _, err := a.authenticateOrRegisterAndLogin(ctx, w, r)
+	return user, err
+}
+
+// authenticateOrRegisterAndLogin returns no transport for new users, they
+// did not authenticate with any.
+func (a *Auth) authenticateOrRegisterAndLogin(ctx context.Context, w http.ResponseWriter, r *http.Request) (*model.User, transport.Transport, error) {
+	user, via, err := a.authenticateOrRefresh(ctx, w, r)
+	var noTokenErr *NoTokenError
+	if errors.As(err, &noTokenErr) {
+		user, err = a.Register(ctx)
+		if err != nil {
+			return nil, nil, err
+		}
+		if err = a.Login(ctx, w, user); err != nil {
+			return nil, nil, err
+		}
+		return user, nil, nil
+	}
+	return user, via, err
+}
+
+// authenticate also returns the transport the access token came in.
+func (a *Auth) authenticate(ctx context.Context, r *http.Request) (*model.User, time.Time, transport.Transport, error) {
+	tokenString, via, err := transport.ReadFrom(a.transport, r)
+	if err != nil {
+		return nil, time.Time{}, nil, &NoTokenError{err}
+	}
+	user, expiresAt, err := a.verifyToken(ctx, tokenString)
+	return user, expiresAt, via, err
+}
+
+func (a *Auth) IssueToken(ctx context.Context, user *model.User) (string, error) {
+	return a.strategy.WriteToken(ctx, user)
+}
+
+func (a *Auth) verifyToken(ctx context.Context, tokenString string) (*model.User, time.Time, error) {
+	user, expiresAt, err := a.strategy.ReadToken(ctx, tokenString, a.repo)
+	if err != nil {
+		return nil, time.Time{}, &InvalidTokenError{err}
+	}
+	if user.Disabled {
+		return nil, time.Time{}, &UserDisabledError{UserID: user.ID}
+	}
+	return user, expiresAt, nil
+}
+
+func (a *Auth) getEnabledUser(ctx context.Context, userID model.UserID) (*model.User, error) {
+	user, err := a.repo.GetUser(ctx, userID)
+	if err != nil {
 		return nil, err
 	}
+	if user.Disabled {
+		return nil, &UserDisabledError{UserID: user.ID}
+	}
 	return user, nil
 }
+
+// writeAccessToken writes through transports like via, all of them for nil.
+func (a *Auth) writeAccessToken(ctx context.Context, w http.ResponseWriter, user *model.User, via transport.Transport) error {
+	tokenString, err := a.IssueToken(ctx, user)
+	if err != nil {
+		return err
+	}
+	return transport.WriteLike(a.transport, w, via, tokenString)
+}
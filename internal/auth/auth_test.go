@@ -0,0 +1,225 @@
+package auth
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/domurdoc/shortener/internal/auth/strategy"
+	"github.com/domurdoc/shortener/internal/auth/transport"
+	"github.com/domurdoc/shortener/internal/repository/mem"
+)
+
+func newTestAuth(accessExp time.Duration) *Auth {
+	refresher := NewRefresher(
+		transport.NewCookie("refresh", 3600, false),
+		mem.NewMemRefreshTokenRepo(),
+		time.Hour,
+		time.Minute,
+	)
+	return New(
+		strategy.NewJWT("secret", accessExp),
+		transport.NewCookie("access", 3600, false),
+		mem.NewMemUserRepo(),
+		refresher,
+	)
+}
+
+func cookiesFrom(w *httptest.ResponseRecorder) map[string]*http.Cookie {
+	cookies := make(map[string]*http.Cookie)
+	for _, c := range w.Result().Cookies() {
+		cookies[c.Name] = c
+	}
+	return cookies
+}
+
+func TestAuth_Refresh(t *testing.T) {
+	a := newTestAuth(30 * time.Second)
+
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	user, err := a.AuthenticateOrRegisterAndLogin(context.TODO(), w, r)
+	require.NoError(t, err)
+	issued := cookiesFrom(w)
+	require.Contains(t, issued, "access")
+	require.Contains(t, issued, "refresh")
+
+	t.Run("expiring access token is reissued", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		r.AddCookie(issued["access"])
+		r.AddCookie(issued["refresh"])
+		refreshedUser, err := a.AuthenticateOrRegisterAndLogin(context.TODO(), w, r)
+		require.NoError(t, err)
+		assert.Equal(t, user.ID, refreshedUser.ID)
+		rotated := cookiesFrom(w)
+		assert.Contains(t, rotated, "access")
+		assert.NotEqual(t, issued["refresh"].Value, rotated["refresh"].Value)
+
+		t.Run("parallel request within grace rotates too", func(t *testing.T) {
+			w := httptest.NewRecorder()
+			r := httptest.NewRequest(http.MethodGet, "/", nil)
+			r.AddCookie(issued["refresh"])
+			refreshedUser, err := a.AuthenticateOrRegisterAndLogin(context.TODO(), w, r)
+			require.NoError(t, err)
+			assert.Equal(t, user.ID, refreshedUser.ID)
+			assert.NotEqual(t, rotated["refresh"].Value, cookiesFrom(w)["refresh"].Value)
+		})
+
+		t.Run("reused refresh token revokes family", func(t *testing.T) {
+			a.refresher.grace = 0
+			w := httptest.NewRecorder()
+			r := httptest.NewRequest(http.MethodGet, "/", nil)
+			r.AddCookie(issued["refresh"])
+			_, err := a.AuthenticateOrRegisterAndLogin(context.TODO(), w, r)
+			var invalidTokenErr *InvalidTokenError
+			assert.ErrorAs(t, err, &invalidTokenErr)
+
+			w = httptest.NewRecorder()
+			r = httptest.NewRequest(http.MethodGet, "/", nil)
+			r.AddCookie(rotated["refresh"])
+			_, err = a.AuthenticateOrRegisterAndLogin(context.TODO(), w, r)
+			assert.ErrorAs(t, err, &invalidTokenErr)
+			cleared := cookiesFrom(w)
+			require.Contains(t, cleared, "refresh")
+			assert.Negative(t, cleared["refresh"].MaxAge)
+
+			// The next request without the cleared cookie starts over.
+			w = httptest.NewRecorder()
+			_, err = a.AuthenticateOrRegisterAndLogin(context.TODO(), w, httptest.NewRequest(http.MethodGet, "/", nil))
+			assert.NoError(t, err)
+		})
+	})
+
+	t.Run("expiring access token alone is not renewed", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		r.AddCookie(issued["access"])
+		authenticatedUser, err := a.AuthenticateOrRegisterAndLogin(context.TODO(), w, r)
+		require.NoError(t, err)
+		assert.Equal(t, user.ID, authenticatedUser.ID)
+		assert.Empty(t, w.Result().Cookies())
+	})
+
+	t.Run("fresh access token is kept", func(t *testing.T) {
+		a := newTestAuth(time.Hour)
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		_, err := a.AuthenticateOrRegisterAndLogin(context.TODO(), w, r)
+		require.NoError(t, err)
+
+		r = httptest.NewRequest(http.MethodGet, "/", nil)
+		r.AddCookie(cookiesFrom(w)["access"])
+		w = httptest.NewRecorder()
+		_, err = a.AuthenticateOrRegisterAndLogin(context.TODO(), w, r)
+		require.NoError(t, err)
+		assert.Empty(t, w.Result().Cookies())
+	})
+}
+
+func TestAuth_RefreshWritesLikeRequest(t *testing.T) {
+	newAuth := func() *Auth {
+		refresher := NewRefresher(
+			transport.NewMulti(transport.NewBearer("X-Refresh-Token"), transport.NewCookie("refresh", 3600, false)),
+			mem.NewMemRefreshTokenRepo(),
+			time.Hour,
+			time.Minute,
+		)
+		return New(
+			strategy.NewJWT("secret", 30*time.Second),
+			transport.NewMulti(transport.NewBearer("Authorization"), transport.NewCookie("access", 3600, false)),
+			mem.NewMemUserRepo(),
+			refresher,
+		)
+	}
+
+	t.Run("cookies", func(t *testing.T) {
+		a := newAuth()
+		w := httptest.NewRecorder()
+		_, err := a.AuthenticateOrRegisterAndLogin(context.TODO(), w, httptest.NewRequest(http.MethodGet, "/", nil))
+		require.NoError(t, err)
+
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		r.AddCookie(cookiesFrom(w)["refresh"])
+		w = httptest.NewRecorder()
+		_, err = a.AuthenticateOrRegisterAndLogin(context.TODO(), w, r)
+		require.NoError(t, err)
+		assert.Contains(t, cookiesFrom(w), "access")
+		assert.Contains(t, cookiesFrom(w), "refresh")
+		assert.Empty(t, w.Header().Get("Authorization"))
+		assert.Empty(t, w.Header().Get("X-Refresh-Token"))
+	})
+
+	t.Run("bearer", func(t *testing.T) {
+		a := newAuth()
+		w := httptest.NewRecorder()
+		_, err := a.AuthenticateOrRegisterAndLogin(context.TODO(), w, httptest.NewRequest(http.MethodGet, "/", nil))
+		require.NoError(t, err)
+
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		r.Header.Set("X-Refresh-Token", w.Header().Get("X-Refresh-Token"))
+		w = httptest.NewRecorder()
+		_, err = a.AuthenticateOrRegisterAndLogin(context.TODO(), w, r)
+		require.NoError(t, err)
+		assert.NotEmpty(t, w.Header().Get("Authorization"))
+		assert.NotEmpty(t, w.Header().Get("X-Refresh-Token"))
+		assert.Empty(t, w.Result().Cookies())
+	})
+}
+
+func TestCSRFMiddleware(t *testing.T) {
+	a := New(
+		strategy.NewJWT("secret", time.Hour),
+		transport.NewMulti(transport.NewBearer("Authorization"), transport.NewCookie("access", 3600, false)),
+		mem.NewMemUserRepo(),
+		nil,
+	)
+	w := httptest.NewRecorder()
+	_, err := a.AuthenticateOrRegisterAndLogin(context.TODO(), w, httptest.NewRequest(http.MethodGet, "/", nil))
+	require.NoError(t, err)
+	access := cookiesFrom(w)["access"]
+	bearer := w.Header().Get("Authorization")
+	require.NotEmpty(t, bearer)
+
+	handler := NewAuthMiddleware(a, true)(NewCSRFMiddleware("http://short.example")(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})))
+	tests := []struct {
+		name    string
+		method  string
+		cookie  bool
+		headers map[string]string
+		want    int
+	}{
+		{"no origin", http.MethodPost, true, nil, http.StatusForbidden},
+		{"old browser referer", http.MethodPost, true, map[string]string{"Referer": "http://short.example/page"}, http.StatusOK},
+		{"foreign referer", http.MethodPost, true, map[string]string{"Referer": "http://evil.example/page"}, http.StatusForbidden},
+		{"same-origin fetch", http.MethodPost, true, map[string]string{"Sec-Fetch-Site": "same-origin"}, http.StatusOK},
+		{"cross-site fetch", http.MethodPost, true, map[string]string{"Sec-Fetch-Site": "cross-site"}, http.StatusForbidden},
+		{"sibling subdomain", http.MethodDelete, true, map[string]string{"Sec-Fetch-Site": "same-site"}, http.StatusForbidden},
+		{"trusted origin", http.MethodPost, true, map[string]string{"Origin": "http://short.example"}, http.StatusOK},
+		{"foreign origin", http.MethodPost, true, map[string]string{"Origin": "http://evil.example"}, http.StatusForbidden},
+		{"safe method", http.MethodGet, true, map[string]string{"Sec-Fetch-Site": "cross-site"}, http.StatusOK},
+		{"no cookie credentials", http.MethodPost, false, map[string]string{"Sec-Fetch-Site": "cross-site"}, http.StatusOK},
+		{"bearer with cookie", http.MethodPost, true, map[string]string{"Authorization": bearer}, http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(tt.method, "/", nil)
+			if tt.cookie {
+				r.AddCookie(access)
+			}
+			for k, v := range tt.headers {
+				r.Header.Set(k, v)
+			}
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, r)
+			assert.Equal(t, tt.want, w.Code)
+		})
+	}
+}
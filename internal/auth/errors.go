@@ -1,6 +1,10 @@
 package auth
 
-import "fmt"
+import (
+	"fmt"
+
+	"github.com/domurdoc/shortener/internal/model"
+)
 
 type NoTokenError struct {
 	Err error
@@ -27,3 +31,11 @@ func (e *InvalidTokenError) Error() string {
 func (e *InvalidTokenError) Unwrap() error {
 	return e.Err
 }
+
+type UserDisabledError struct {
+	UserID model.UserID
+}
+
+func (e *UserDisabledError) Error() string {
+	return fmt.Sprintf("user %d is disabled", e.UserID)
+}
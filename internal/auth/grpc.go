@@ -0,0 +1,156 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	"github.com/domurdoc/shortener/internal/model"
+)
+
+const (
+	MetadataKey = "authorization"
+	// RefreshMetadataKey carries the refresh token both ways, like the
+	// X-Refresh-Token header over HTTP.
+	RefreshMetadataKey = "x-refresh-token"
+)
+
+// NewUnaryInterceptor authenticates gRPC calls by a bearer token in metadata.
+// A refresh token sent with an expired or expiring access token is rotated.
+// New tokens are returned in the response header under the same keys.
+// Unexpected errors are logged to log, clients get a generic status.
+func NewUnaryInterceptor(
+	auth *Auth,
+	allowAnonymous bool,
+	log *zap.SugaredLogger,
+	publicMethods ...string,
+) grpc.UnaryServerInterceptor {
+	public := make(map[string]bool, len(publicMethods))
+	for _, m := range publicMethods {
+		public[m] = true
+	}
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		if public[info.FullMethod] {
+			return handler(ctx, req)
+		}
+		user, err := auth.authenticateOrRefreshMetadata(ctx)
+		var noTokenErr *NoTokenError
+		if errors.As(err, &noTokenErr) && allowAnonymous {
+			user, err = auth.registerMetadata(ctx)
+		}
+		if err != nil {
+			return nil, toStatus(err, log)
+		}
+		return handler(context.WithValue(ctx, userKey, user), req)
+	}
+}
+
+func UserFromContext(ctx context.Context) *model.User {
+	return ctx.Value(userKey).(*model.User)
+}
+
+// authenticateOrRefreshMetadata mirrors AuthenticateOrRefresh, an expiring
+// access token without a refresh token is not renewed.
+func (a *Auth) authenticateOrRefreshMetadata(ctx context.Context) (*model.User, error) {
+	user, expiresAt, err := a.authenticateMetadata(ctx)
+	if err == nil && (a.refresher == nil || !a.refresher.expiresSoon(expiresAt)) {
+		return user, nil
+	}
+	if a.refresher != nil {
+		if refreshToken, refreshErr := metadataToken(ctx, RefreshMetadataKey); refreshErr == nil {
+			return a.refreshMetadata(ctx, refreshToken)
+		}
+	}
+	return user, err
+}
+
+func (a *Auth) authenticateMetadata(ctx context.Context) (*model.User, time.Time, error) {
+	tokenString, err := metadataToken(ctx, MetadataKey)
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+	return a.verifyToken(ctx, tokenString)
+}
+
+func (a *Auth) refreshMetadata(ctx context.Context, refreshToken string) (*model.User, error) {
+	userID, rotated, err := a.refresher.rotate(ctx, refreshToken)
+	if err != nil {
+		return nil, err
+	}
+	user, err := a.getEnabledUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	tokenString, err := a.IssueToken(ctx, user)
+	if err != nil {
+		return nil, err
+	}
+	md := metadata.Pairs(MetadataKey, "Bearer "+tokenString, RefreshMetadataKey, "Bearer "+rotated)
+	if err := grpc.SetHeader(ctx, md); err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+func (a *Auth) registerMetadata(ctx context.Context) (*model.User, error) {
+	user, err := a.Register(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if err := a.loginMetadata(ctx, user); err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+// loginMetadata is Login for gRPC, it starts a new refresh token family.
+func (a *Auth) loginMetadata(ctx context.Context, user *model.User) error {
+	tokenString, err := a.IssueToken(ctx, user)
+	if err != nil {
+		return err
+	}
+	md := metadata.Pairs(MetadataKey, "Bearer "+tokenString)
+	if a.refresher != nil {
+		refreshToken, err := a.refresher.issue(ctx, user.ID, "")
+		if err != nil {
+			return err
+		}
+		md.Append(RefreshMetadataKey, "Bearer "+refreshToken)
+	}
+	return grpc.SetHeader(ctx, md)
+}
+
+func metadataToken(ctx context.Context, key string) (string, error) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	values := md.Get(key)
+	if len(values) == 0 {
+		return "", &NoTokenError{fmt.Errorf("no %s metadata", key)}
+	}
+	tokenString, ok := strings.CutPrefix(values[0], "Bearer ")
+	if !ok {
+		return "", &InvalidTokenError{errors.New("wrong token type")}
+	}
+	return tokenString, nil
+}
+
+func toStatus(err error, log *zap.SugaredLogger) error {
+	var noTokenErr *NoTokenError
+	var invalidTokenErr *InvalidTokenError
+	if errors.As(err, &noTokenErr) || errors.As(err, &invalidTokenErr) {
+		return status.Error(codes.Unauthenticated, err.Error())
+	}
+	var userDisabledErr *UserDisabledError
+	if errors.As(err, &userDisabledErr) {
+		return status.Error(codes.PermissionDenied, err.Error())
+	}
+	log.Errorw("grpc authentication failed", "err", err)
+	return status.Error(codes.Internal, "internal error")
+}
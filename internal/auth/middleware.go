@@ -5,35 +5,75 @@ import (
 	"errors"
 	"net/http"
 
+	"github.com/domurdoc/shortener/internal/auth/transport"
 	"github.com/domurdoc/shortener/internal/httputil"
 	"github.com/domurdoc/shortener/internal/model"
 )
 
 type ctxKey string
 
-const userKey = ctxKey("user")
+const (
+	userKey       = ctxKey("user")
+	cookieAuthKey = ctxKey("cookieAuth")
+)
 
-func NewAuthMiddleware(auth *Auth) httputil.Middleware {
+// NewAuthMiddleware registers unknown clients as new users only when allowAnonymous is set.
+// It records whether a cookie authenticated the request for NewCSRFMiddleware.
+func NewAuthMiddleware(auth *Auth, allowAnonymous bool) httputil.Middleware {
+	authenticate := auth.authenticateOrRefresh
+	if allowAnonymous {
+		authenticate = auth.authenticateOrRegisterAndLogin
+	}
 	return func(h http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			ctx := r.Context()
-			user, err := auth.AuthenticateOrRegisterAndLogin(ctx, w, r)
+			user, via, err := authenticate(ctx, w, r)
 			if err != nil {
-				var invalidTokenErr *InvalidTokenError
-				if errors.As(err, &invalidTokenErr) {
-					http.Error(w, err.Error(), http.StatusUnauthorized)
-					return
-				}
-				http.Error(w, err.Error(), http.StatusInternalServerError)
+				WriteError(w, err)
 				return
 			}
+			_, byCookie := via.(*transport.CookieTransport)
+			r = r.WithContext(context.WithValue(ctx, cookieAuthKey, byCookie))
 			h.ServeHTTP(w, AttachUser(r, user))
 		})
 	}
 }
 
+func AdminMiddleware(h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if GetUser(r).Role != model.RoleAdmin {
+			httputil.WriteProblem(w, httputil.NewProblem(
+				http.StatusForbidden,
+				"admin-required",
+				"Admin role required",
+				"admin role required",
+			))
+			return
+		}
+		h.ServeHTTP(w, r)
+	})
+}
+
+// WriteError answers with the problem for an authentication error.
+func WriteError(w http.ResponseWriter, err error) {
+	var noTokenErr *NoTokenError
+	var invalidTokenErr *InvalidTokenError
+	var userDisabledErr *UserDisabledError
+	switch {
+	case errors.As(err, &noTokenErr):
+		httputil.WriteProblem(w, httputil.NewProblem(http.StatusUnauthorized, "no-token", "Authentication required", err.Error()))
+	case errors.As(err, &invalidTokenErr):
+		httputil.WriteProblem(w, httputil.NewProblem(http.StatusUnauthorized, "invalid-token", "Invalid token", err.Error()))
+	case errors.As(err, &userDisabledErr):
+		httputil.WriteProblem(w, httputil.NewProblem(http.StatusForbidden, "user-disabled", "User disabled", err.Error()).
+			With("user_id", userDisabledErr.UserID))
+	default:
+		httputil.InternalError(w)
+	}
+}
+
 func GetUser(r *http.Request) *model.User {
-	return r.Context().Value(userKey).(*model.User)
+	return UserFromContext(r.Context())
 }
 
 func AttachUser(r *http.Request, user *model.User) *http.Request {
@@ -0,0 +1,57 @@
+package oidc
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"strings"
+
+	"github.com/domurdoc/shortener/internal/utils"
+)
+
+const sessionValueLength = 43
+
+// Session keeps the login attempt state between the redirect to the IdP and the callback.
+type Session struct {
+	State    string
+	Nonce    string
+	Verifier string
+}
+
+func NewSession() *Session {
+	return &Session{
+		State:    utils.GenerateRandomString(utils.ALPHA, sessionValueLength),
+		Nonce:    utils.GenerateRandomString(utils.ALPHA, sessionValueLength),
+		Verifier: utils.GenerateRandomString(utils.ALPHA, sessionValueLength),
+	}
+}
+
+func (s *Session) CodeChallenge() string {
+	sum := sha256.Sum256([]byte(s.Verifier))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+// Encode signs the session so it can be stored in a client cookie.
+func (s *Session) Encode(secret string) string {
+	payload := strings.Join([]string{s.State, s.Nonce, s.Verifier}, ".")
+	return payload + "." + sign(payload, secret)
+}
+
+func DecodeSession(value, secret string) (*Session, error) {
+	parts := strings.Split(value, ".")
+	if len(parts) != 4 {
+		return nil, errors.New("malformed session")
+	}
+	payload := strings.Join(parts[:3], ".")
+	if !hmac.Equal([]byte(parts[3]), []byte(sign(payload, secret))) {
+		return nil, errors.New("session signature mismatch")
+	}
+	return &Session{State: parts[0], Nonce: parts[1], Verifier: parts[2]}, nil
+}
+
+func sign(payload, secret string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(payload))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
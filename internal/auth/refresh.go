@@ -0,0 +1,126 @@
+package auth
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/domurdoc/shortener/internal/auth/transport"
+	"github.com/domurdoc/shortener/internal/model"
+	"github.com/domurdoc/shortener/internal/repository"
+	"github.com/domurdoc/shortener/internal/utils"
+)
+
+const (
+	refreshTokenLength = 48
+	familyLength       = 32
+	// reuseGrace lets requests sent in parallel with the same refresh token
+	// all rotate it instead of tripping reuse detection.
+	reuseGrace = 30 * time.Second
+)
+
+type Refresher struct {
+	transport transport.Transport
+	repo      repository.RefreshTokenRepo
+	tokenExp  time.Duration
+	leeway    time.Duration
+	grace     time.Duration
+}
+
+// NewRefresher creates long-lived rotating refresh tokens. Access tokens
+// expiring within leeway are reissued together with a new refresh token.
+func NewRefresher(
+	transport transport.Transport,
+	repo repository.RefreshTokenRepo,
+	tokenExp time.Duration,
+	leeway time.Duration,
+) *Refresher {
+	return &Refresher{
+		transport: transport,
+		repo:      repo,
+		tokenExp:  tokenExp,
+		leeway:    leeway,
+		grace:     reuseGrace,
+	}
+}
+
+// Issue starts a new token family when family is empty.
+func (r *Refresher) Issue(ctx context.Context, w http.ResponseWriter, userID model.UserID, family string) error {
+	tokenString, err := r.issue(ctx, userID, family)
+	if err != nil {
+		return err
+	}
+	return r.transport.Write(w, tokenString)
+}
+
+func (r *Refresher) issue(ctx context.Context, userID model.UserID, family string) (string, error) {
+	if family == "" {
+		family = utils.GenerateRandomString(utils.ALPHA, familyLength)
+	}
+	tokenString := utils.GenerateRandomString(utils.ALPHA, refreshTokenLength)
+	token := &model.RefreshToken{
+		Hash:      hashRefreshToken(tokenString),
+		UserID:    userID,
+		Family:    family,
+		ExpiresAt: time.Now().Add(r.tokenExp),
+	}
+	if err := r.repo.StoreRefreshToken(ctx, token); err != nil {
+		return "", err
+	}
+	return tokenString, nil
+}
+
+// Rotate exchanges the refresh token for a new one of the same family, sent
+// back the way the old one came. It returns that transport too. Presenting a
+// token rotated more than the grace window ago revokes the whole family.
+func (r *Refresher) Rotate(ctx context.Context, w http.ResponseWriter, req *http.Request) (model.UserID, transport.Transport, error) {
+	tokenString, via, err := transport.ReadFrom(r.transport, req)
+	if err != nil {
+		return 0, nil, &NoTokenError{err}
+	}
+	userID, rotated, err := r.rotate(ctx, tokenString)
+	if err != nil {
+		return 0, nil, err
+	}
+	return userID, via, transport.WriteLike(r.transport, w, via, rotated)
+}
+
+// rotate returns the user and the token replacing tokenString.
+func (r *Refresher) rotate(ctx context.Context, tokenString string) (model.UserID, string, error) {
+	token, err := r.repo.UseRefreshToken(ctx, hashRefreshToken(tokenString), r.grace)
+	var reusedErr *model.RefreshTokenReusedError
+	if errors.As(err, &reusedErr) {
+		if err := r.repo.RevokeRefreshTokenFamily(ctx, reusedErr.Family); err != nil {
+			return 0, "", err
+		}
+		return 0, "", &InvalidTokenError{reusedErr}
+	}
+	var notFoundErr *model.RefreshTokenNotFoundError
+	if errors.As(err, &notFoundErr) {
+		return 0, "", &InvalidTokenError{err}
+	}
+	if err != nil {
+		return 0, "", err
+	}
+	if time.Now().After(token.ExpiresAt) {
+		return 0, "", &InvalidTokenError{fmt.Errorf("refresh token expired")}
+	}
+	rotated, err := r.issue(ctx, token.UserID, token.Family)
+	if err != nil {
+		return 0, "", err
+	}
+	return token.UserID, rotated, nil
+}
+
+func (r *Refresher) expiresSoon(expiresAt time.Time) bool {
+	return !expiresAt.IsZero() && time.Until(expiresAt) < r.leeway
+}
+
+func hashRefreshToken(tokenString string) string {
+	sum := sha256.Sum256([]byte(tokenString))
+	return hex.EncodeToString(sum[:])
+}
@@ -2,12 +2,14 @@ package strategy
 
 import (
 	"context"
+	"time"
 
 	"github.com/domurdoc/shortener/internal/model"
 	"github.com/domurdoc/shortener/internal/repository"
 )
 
+// ReadToken returns zero expiration time for tokens that never expire.
 type Strategy interface {
 	WriteToken(context.Context, *model.User) (string, error)
-	ReadToken(context.Context, string, repository.UserRepo) (*model.User, error)
+	ReadToken(context.Context, string, repository.UserRepo) (*model.User, time.Time, error)
 }
@@ -3,6 +3,7 @@ package strategy
 import (
 	"context"
 	"strconv"
+	"time"
 
 	"github.com/domurdoc/shortener/internal/model"
 	"github.com/domurdoc/shortener/internal/repository"
@@ -18,10 +19,11 @@ func (s *DebugStrategy) WriteToken(ctx context.Context, user *model.User) (strin
 	return strconv.Itoa(int(user.ID)), nil
 }
 
-func (s *DebugStrategy) ReadToken(ctx context.Context, tokenString string, repo repository.UserRepo) (*model.User, error) {
+func (s *DebugStrategy) ReadToken(ctx context.Context, tokenString string, repo repository.UserRepo) (*model.User, time.Time, error) {
 	userID, err := strconv.Atoi(tokenString)
 	if err != nil {
-		return nil, err
+		return nil, time.Time{}, err
 	}
-	return repo.GetUser(ctx, model.UserID(userID))
+	user, err := repo.GetUser(ctx, model.UserID(userID))
+	return user, time.Time{}, err
 }
@@ -2,6 +2,7 @@ package strategy
 
 import (
 	"context"
+	"fmt"
 	"time"
 
 	"github.com/golang-jwt/jwt/v4"
@@ -38,17 +39,25 @@ func (s *JWTStrategy) WriteToken(ctx context.Context, user *model.User) (string,
 	return tokenString, nil
 }
 
-func (s *JWTStrategy) ReadToken(ctx context.Context, tokenString string, repo repository.UserRepo) (*model.User, error) {
+func (s *JWTStrategy) ReadToken(ctx context.Context, tokenString string, repo repository.UserRepo) (*model.User, time.Time, error) {
 	claims := &claims{}
 	token, err := jwt.ParseWithClaims(tokenString, claims,
 		func(t *jwt.Token) (any, error) {
 			return []byte(s.secretKey), nil
 		})
 	if err != nil {
-		return nil, err
+		return nil, time.Time{}, err
 	}
 	if !token.Valid {
-		return nil, err
+		return nil, time.Time{}, fmt.Errorf("token is not valid")
 	}
-	return repo.GetUser(ctx, claims.UserID)
+	user, err := repo.GetUser(ctx, claims.UserID)
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+	var expiresAt time.Time
+	if claims.ExpiresAt != nil {
+		expiresAt = claims.ExpiresAt.Time
+	}
+	return user, expiresAt, nil
 }
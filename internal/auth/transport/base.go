@@ -5,4 +5,6 @@ import "net/http"
 type Transport interface {
 	Read(*http.Request) (string, error)
 	Write(http.ResponseWriter, string) error
+	// Clear tells the client to drop the token.
+	Clear(http.ResponseWriter)
 }
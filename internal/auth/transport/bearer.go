@@ -33,3 +33,6 @@ func (b *BearerTransport) Write(w http.ResponseWriter, tokenString string) error
 	w.Header().Set(b.header, fmt.Sprintf("Bearer %s", tokenString))
 	return nil
 }
+
+// Clear does nothing, bearer clients keep the token until it is rejected.
+func (b *BearerTransport) Clear(w http.ResponseWriter) {}
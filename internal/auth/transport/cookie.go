@@ -49,3 +49,14 @@ func (c *CookieTransport) Write(w http.ResponseWriter, tokenString string) error
 	http.SetCookie(w, &cookie)
 	return nil
 }
+
+func (c *CookieTransport) Clear(w http.ResponseWriter) {
+	http.SetCookie(w, &http.Cookie{
+		Name:     c.name,
+		SameSite: c.sameSite,
+		MaxAge:   -1,
+		Secure:   c.secure,
+		HttpOnly: c.httpOnly,
+		Path:     c.path,
+	})
+}
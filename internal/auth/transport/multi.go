@@ -0,0 +1,100 @@
+package transport
+
+import (
+	"errors"
+	"net/http"
+)
+
+type MultiTransport struct {
+	transports []Transport
+}
+
+// NewMulti reads the token from the first transport that has one, so clients
+// may use whichever they support. Write sends it to all of them, WriteLike
+// only to the kind the client already uses.
+func NewMulti(transports ...Transport) *MultiTransport {
+	return &MultiTransport{transports: transports}
+}
+
+func (m *MultiTransport) Transports() []Transport {
+	return m.transports
+}
+
+func (m *MultiTransport) Read(r *http.Request) (string, error) {
+	tokenString, _, err := m.ReadFrom(r)
+	return tokenString, err
+}
+
+// ReadFrom is Read that also returns the transport holding the token.
+func (m *MultiTransport) ReadFrom(r *http.Request) (string, Transport, error) {
+	var errs []error
+	for _, t := range m.transports {
+		tokenString, err := t.Read(r)
+		if err == nil {
+			return tokenString, t, nil
+		}
+		errs = append(errs, err)
+	}
+	return "", nil, errors.Join(errs...)
+}
+
+func (m *MultiTransport) Write(w http.ResponseWriter, tokenString string) error {
+	return m.WriteLike(w, nil, tokenString)
+}
+
+// WriteLike writes the token to the transports of the same kind as like, so
+// a cookie client gets no token headers and a bearer client no cookies. A nil
+// like writes to all of them.
+func (m *MultiTransport) WriteLike(w http.ResponseWriter, like Transport, tokenString string) error {
+	for _, t := range m.transports {
+		if like != nil && !SameKind(t, like) {
+			continue
+		}
+		if err := t.Write(w, tokenString); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (m *MultiTransport) Clear(w http.ResponseWriter) {
+	for _, t := range m.transports {
+		t.Clear(w)
+	}
+}
+
+// ReadFrom reads the token from t and returns the transport that held it,
+// one of the transports of a MultiTransport.
+func ReadFrom(t Transport, r *http.Request) (string, Transport, error) {
+	if m, ok := t.(*MultiTransport); ok {
+		return m.ReadFrom(r)
+	}
+	tokenString, err := t.Read(r)
+	if err != nil {
+		return "", nil, err
+	}
+	return tokenString, t, nil
+}
+
+// WriteLike is MultiTransport.WriteLike for any transport, a single one is
+// always written.
+func WriteLike(t Transport, w http.ResponseWriter, like Transport, tokenString string) error {
+	if m, ok := t.(*MultiTransport); ok {
+		return m.WriteLike(w, like, tokenString)
+	}
+	return t.Write(w, tokenString)
+}
+
+// SameKind tells whether a and b carry tokens the same way, such as the
+// access and the refresh cookie.
+func SameKind(a, b Transport) bool {
+	switch a.(type) {
+	case *CookieTransport:
+		_, ok := b.(*CookieTransport)
+		return ok
+	case *BearerTransport:
+		_, ok := b.(*BearerTransport)
+		return ok
+	}
+	return false
+}
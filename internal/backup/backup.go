@@ -0,0 +1,433 @@
+// Package backup moves users, teams, identities, refresh tokens and records
+// between repositories, directly or through a newline-delimited JSON dump.
+package backup
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/domurdoc/shortener/internal/model"
+	"github.com/domurdoc/shortener/internal/repository"
+)
+
+const pageSize = 500
+
+// ErrNotEmpty is returned by Import and Copy for a destination that already
+// holds users, teams or records. Numeric user and team IDs of the source
+// would merge different people and their links into the existing ones.
+var ErrNotEmpty = errors.New("destination is not empty")
+
+// Repos with a nil Teams, Identities or Tokens repo export none of them and
+// fail to import them.
+type Repos struct {
+	Records    repository.RecordRepo
+	Users      repository.UserRepo
+	Teams      repository.TeamRepo
+	Identities repository.IdentityRepo
+	Tokens     repository.RefreshTokenRepo
+}
+
+type Summary struct {
+	Users         int
+	Teams         int
+	Identities    int
+	RefreshTokens int
+	Records       int
+}
+
+func (s *Summary) String() string {
+	return fmt.Sprintf(
+		"%d users, %d teams, %d identities, %d refresh tokens, %d records",
+		s.Users, s.Teams, s.Identities, s.RefreshTokens, s.Records,
+	)
+}
+
+// entry is a dump line holding one of its fields.
+type entry struct {
+	User         *jsonUser         `json:"user,omitempty"`
+	Team         *jsonTeam         `json:"team,omitempty"`
+	Identity     *jsonIdentity     `json:"identity,omitempty"`
+	RefreshToken *jsonRefreshToken `json:"refresh_token,omitempty"`
+	Record       *jsonRecord       `json:"record,omitempty"`
+}
+
+type jsonUser struct {
+	ID       model.UserID `json:"id"`
+	Role     model.Role   `json:"role"`
+	Disabled bool         `json:"disabled,omitempty"`
+}
+
+type jsonTeam struct {
+	ID      model.TeamID     `json:"id"`
+	Name    string           `json:"name"`
+	Members []jsonTeamMember `json:"members,omitempty"`
+}
+
+type jsonTeamMember struct {
+	UserID model.UserID   `json:"user_id"`
+	Role   model.TeamRole `json:"role"`
+}
+
+type jsonIdentity struct {
+	Issuer  string       `json:"issuer"`
+	Subject string       `json:"subject"`
+	UserID  model.UserID `json:"user_id"`
+}
+
+type jsonRefreshToken struct {
+	Hash      string       `json:"hash"`
+	UserID    model.UserID `json:"user_id"`
+	Family    string       `json:"family"`
+	ExpiresAt time.Time    `json:"expires_at"`
+	Used      bool         `json:"used,omitempty"`
+}
+
+type jsonRecord struct {
+	ShortCode      model.ShortCode   `json:"short_code"`
+	OriginalURL    model.OriginalURL `json:"original_url"`
+	CanonicalURL   model.OriginalURL `json:"canonical_url,omitempty"`
+	PasswordHash   string            `json:"password_hash,omitempty"`
+	MaxClicks      int64             `json:"max_clicks,omitempty"`
+	ActiveFrom     time.Time         `json:"active_from,omitzero"`
+	ActiveUntil    time.Time         `json:"active_until,omitzero"`
+	RedirectStatus int               `json:"redirect_status,omitempty"`
+	Owners         []model.UserID    `json:"owners,omitempty"`
+	Creator        model.UserID      `json:"creator,omitempty"`
+	Teams          []model.TeamID    `json:"teams,omitempty"`
+	ForceDeleted   bool              `json:"force_deleted,omitempty"`
+	CreatedAt      time.Time         `json:"created_at"`
+	Clicks         int64             `json:"clicks,omitempty"`
+}
+
+func Export(ctx context.Context, w io.Writer, src Repos) (*Summary, error) {
+	enc := json.NewEncoder(w)
+	return walk(ctx, src, func(e entry) error {
+		return enc.Encode(e)
+	})
+}
+
+func Import(ctx context.Context, r io.Reader, dst Repos) (*Summary, error) {
+	summary := &Summary{}
+	if err := checkEmpty(ctx, dst); err != nil {
+		return summary, err
+	}
+	importer := newImporter(dst, summary)
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		var e entry
+		if err := json.Unmarshal(scanner.Bytes(), &e); err != nil {
+			return summary, fmt.Errorf("line %d: %w", line, err)
+		}
+		if err := importer.add(ctx, e); err != nil {
+			return summary, fmt.Errorf("line %d: %w", line, err)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return summary, err
+	}
+	return summary, importer.flush(ctx)
+}
+
+func Copy(ctx context.Context, src, dst Repos) (*Summary, error) {
+	summary := &Summary{}
+	if err := checkEmpty(ctx, dst); err != nil {
+		return summary, err
+	}
+	importer := newImporter(dst, summary)
+	if _, err := walk(ctx, src, func(e entry) error {
+		return importer.add(ctx, e)
+	}); err != nil {
+		return summary, err
+	}
+	return summary, importer.flush(ctx)
+}
+
+// checkEmpty looks at records too, the file backend keeps owners of records
+// without users.
+func checkEmpty(ctx context.Context, dst Repos) error {
+	users, err := dst.Users.CountUsers(ctx)
+	if err != nil {
+		return err
+	}
+	if users > 0 {
+		return fmt.Errorf("%w: %d users", ErrNotEmpty, users)
+	}
+	records, err := dst.Records.List(ctx, 1, 0)
+	if err != nil {
+		return err
+	}
+	if len(records) > 0 {
+		return fmt.Errorf("%w: has records", ErrNotEmpty)
+	}
+	if dst.Teams == nil {
+		return nil
+	}
+	teams, err := dst.Teams.ListAllTeams(ctx, 1, 0)
+	if err != nil {
+		return err
+	}
+	if len(teams) > 0 {
+		return fmt.Errorf("%w: has teams", ErrNotEmpty)
+	}
+	return nil
+}
+
+// walk emits users before the teams, identities, refresh tokens and records
+// that reference them. Users unknown to the user repo, e.g. owners with the
+// file backend that keeps only records, are emitted as regular users so
+// ownership survives the move. Expired refresh tokens are left out.
+func walk(ctx context.Context, src Repos, emit func(entry) error) (*Summary, error) {
+	summary := &Summary{}
+	seen := make(map[model.UserID]bool)
+	emitUser := func(user model.User) error {
+		seen[user.ID] = true
+		summary.Users++
+		return emit(entry{User: &jsonUser{ID: user.ID, Role: user.Role, Disabled: user.Disabled}})
+	}
+	ensureUser := func(userID model.UserID) error {
+		if seen[userID] {
+			return nil
+		}
+		return emitUser(model.User{ID: userID, Role: model.RoleUser})
+	}
+
+	if err := forEachPage(ctx, src.Users.ListUsers, emitUser); err != nil {
+		return summary, err
+	}
+	if src.Teams != nil {
+		if err := forEachPage(ctx, src.Teams.ListAllTeams, func(team model.Team) error {
+			members, err := src.Teams.ListMembers(ctx, team.ID)
+			if err != nil {
+				return err
+			}
+			t := &jsonTeam{ID: team.ID, Name: team.Name}
+			for _, member := range members {
+				if err := ensureUser(member.UserID); err != nil {
+					return err
+				}
+				t.Members = append(t.Members, jsonTeamMember{UserID: member.UserID, Role: member.Role})
+			}
+			summary.Teams++
+			return emit(entry{Team: t})
+		}); err != nil {
+			return summary, err
+		}
+	}
+	if src.Identities != nil {
+		if err := forEachPage(ctx, src.Identities.ListIdentities, func(identity model.Identity) error {
+			if err := ensureUser(identity.UserID); err != nil {
+				return err
+			}
+			summary.Identities++
+			return emit(entry{Identity: &jsonIdentity{
+				Issuer:  identity.Issuer,
+				Subject: identity.Subject,
+				UserID:  identity.UserID,
+			}})
+		}); err != nil {
+			return summary, err
+		}
+	}
+	if src.Tokens != nil {
+		now := time.Now()
+		if err := forEachPage(ctx, src.Tokens.ListRefreshTokens, func(token model.RefreshToken) error {
+			if !token.ExpiresAt.After(now) {
+				return nil
+			}
+			if err := ensureUser(token.UserID); err != nil {
+				return err
+			}
+			summary.RefreshTokens++
+			return emit(entry{RefreshToken: &jsonRefreshToken{
+				Hash:      token.Hash,
+				UserID:    token.UserID,
+				Family:    token.Family,
+				ExpiresAt: token.ExpiresAt,
+				Used:      token.Used,
+			}})
+		}); err != nil {
+			return summary, err
+		}
+	}
+	err := forEachPage(ctx, src.Records.List, func(record model.RecordDetails) error {
+		for _, userID := range record.Owners {
+			if err := ensureUser(userID); err != nil {
+				return err
+			}
+		}
+		// the creator may have deleted the link since
+		if record.Creator != 0 {
+			if err := ensureUser(record.Creator); err != nil {
+				return err
+			}
+		}
+		summary.Records++
+		return emit(entry{Record: &jsonRecord{
+			ShortCode:      record.ShortCode,
+			OriginalURL:    record.OriginalURL,
+			CanonicalURL:   record.CanonicalURL,
+			PasswordHash:   record.PasswordHash,
+			MaxClicks:      record.MaxClicks,
+			ActiveFrom:     record.ActiveFrom,
+			ActiveUntil:    record.ActiveUntil,
+			RedirectStatus: record.RedirectStatus,
+			Owners:         record.Owners,
+			Creator:        record.Creator,
+			Teams:          record.Teams,
+			ForceDeleted:   record.ForceDeleted,
+			CreatedAt:      record.CreatedAt,
+			Clicks:         record.Clicks,
+		}})
+	})
+	return summary, err
+}
+
+func forEachPage[T any](
+	ctx context.Context,
+	list func(ctx context.Context, limit, offset int) ([]T, error),
+	fn func(T) error,
+) error {
+	for offset := 0; ; offset += pageSize {
+		items, err := list(ctx, pageSize, offset)
+		if err != nil {
+			return err
+		}
+		for _, item := range items {
+			if err := fn(item); err != nil {
+				return err
+			}
+		}
+		if len(items) < pageSize {
+			return nil
+		}
+	}
+}
+
+// importer batches records, everything else is stored right away since
+// records reference it.
+type importer struct {
+	dst     Repos
+	summary *Summary
+	records []model.RecordDetails
+}
+
+func newImporter(dst Repos, summary *Summary) *importer {
+	return &importer{dst: dst, summary: summary}
+}
+
+func (i *importer) add(ctx context.Context, e entry) error {
+	switch {
+	case e.User != nil:
+		user := &model.User{ID: e.User.ID, Role: e.User.Role, Disabled: e.User.Disabled}
+		if user.Role == "" {
+			user.Role = model.RoleUser
+		}
+		if err := i.dst.Users.StoreUser(ctx, user); err != nil {
+			return err
+		}
+		i.summary.Users++
+	case e.Team != nil:
+		if i.dst.Teams == nil {
+			return errors.New("destination keeps no teams")
+		}
+		if err := i.dst.Teams.StoreTeam(ctx, &model.Team{ID: e.Team.ID, Name: e.Team.Name}); err != nil {
+			return err
+		}
+		for _, member := range e.Team.Members {
+			if err := i.dst.Teams.SetMember(ctx, &model.TeamMember{
+				TeamID: e.Team.ID,
+				UserID: member.UserID,
+				Role:   member.Role,
+			}); err != nil {
+				return err
+			}
+		}
+		i.summary.Teams++
+	case e.Identity != nil:
+		if err := i.addIdentity(ctx, e.Identity); err != nil {
+			return err
+		}
+		i.summary.Identities++
+	case e.RefreshToken != nil:
+		if i.dst.Tokens == nil {
+			return errors.New("destination keeps no refresh tokens")
+		}
+		if err := i.dst.Tokens.StoreRefreshToken(ctx, &model.RefreshToken{
+			Hash:      e.RefreshToken.Hash,
+			UserID:    e.RefreshToken.UserID,
+			Family:    e.RefreshToken.Family,
+			ExpiresAt: e.RefreshToken.ExpiresAt,
+			Used:      e.RefreshToken.Used,
+		}); err != nil {
+			return err
+		}
+		i.summary.RefreshTokens++
+	case e.Record != nil:
+		i.records = append(i.records, model.RecordDetails{
+			BaseRecord: model.BaseRecord{
+				ShortCode:    e.Record.ShortCode,
+				OriginalURL:  e.Record.OriginalURL,
+				CanonicalURL: e.Record.CanonicalURL,
+				PasswordHash: e.Record.PasswordHash,
+				LinkOptions: model.LinkOptions{
+					MaxClicks:      e.Record.MaxClicks,
+					ActiveFrom:     e.Record.ActiveFrom,
+					ActiveUntil:    e.Record.ActiveUntil,
+					RedirectStatus: e.Record.RedirectStatus,
+				},
+			},
+			Owners:       e.Record.Owners,
+			Creator:      e.Record.Creator,
+			Teams:        e.Record.Teams,
+			ForceDeleted: e.Record.ForceDeleted,
+			CreatedAt:    e.Record.CreatedAt,
+			Clicks:       e.Record.Clicks,
+		})
+		if len(i.records) >= pageSize {
+			return i.flush(ctx)
+		}
+	default:
+		return errors.New("empty entry")
+	}
+	return nil
+}
+
+func (i *importer) flush(ctx context.Context) error {
+	if len(i.records) == 0 {
+		return nil
+	}
+	if err := i.dst.Records.Import(ctx, i.records); err != nil {
+		return err
+	}
+	i.summary.Records += len(i.records)
+	i.records = i.records[:0]
+	return nil
+}
+
+// addIdentity leaves an identity already linked to the same user alone, one
+// linked to another user is a conflict.
+func (i *importer) addIdentity(ctx context.Context, e *jsonIdentity) error {
+	if i.dst.Identities == nil {
+		return errors.New("destination keeps no identities")
+	}
+	existing, err := i.dst.Identities.FetchIdentity(ctx, e.Issuer, e.Subject)
+	var notFoundErr *model.IdentityNotFoundError
+	switch {
+	case errors.As(err, &notFoundErr):
+		return i.dst.Identities.StoreIdentity(ctx, &model.Identity{Issuer: e.Issuer, Subject: e.Subject, UserID: e.UserID})
+	case err != nil:
+		return err
+	case existing.UserID != e.UserID:
+		return fmt.Errorf("identity %q of %q belongs to user %d", e.Subject, e.Issuer, existing.UserID)
+	}
+	return nil
+}
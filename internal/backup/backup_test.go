@@ -0,0 +1,165 @@
+package backup
+
+import (
+	"bytes"
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/domurdoc/shortener/internal/model"
+	"github.com/domurdoc/shortener/internal/repository/file"
+	"github.com/domurdoc/shortener/internal/repository/file/serializer"
+	"github.com/domurdoc/shortener/internal/repository/mem"
+)
+
+func TestCopyAndDump(t *testing.T) {
+	ctx := context.Background()
+	fileRepo, err := file.New(filepath.Join(t.TempDir(), "db.json"), serializer.NewJSONSerializer())
+	require.NoError(t, err)
+	src := Repos{Records: fileRepo, Users: mem.NewMemUserRepo()}
+
+	require.NoError(t, src.Records.Store(ctx, &model.BaseRecord{ShortCode: "a", OriginalURL: "http://a.ru"}, 3))
+	var urlExistsErr *model.OriginalURLExistsError
+	require.ErrorAs(t, src.Records.Store(ctx, &model.BaseRecord{ShortCode: "x", OriginalURL: "http://a.ru"}, 7), &urlExistsErr)
+	require.NoError(t, src.Records.Store(ctx, &model.BaseRecord{ShortCode: "b", OriginalURL: "http://b.ru"}, 7))
+	require.NoError(t, src.Records.ForceDelete(ctx, "b"))
+	require.NoError(t, src.Records.Store(ctx, &model.BaseRecord{ShortCode: "c", OriginalURL: "http://c.ru"}, 7))
+	_, err = src.Records.Delete(ctx, []model.UserRecord{{ShortCode: "c", UserID: 7}})
+	require.NoError(t, err)
+
+	check := func(t *testing.T, dst Repos) {
+		a, err := dst.Records.FetchDetails(ctx, "a")
+		require.NoError(t, err)
+		assert.Equal(t, []model.UserID{3, 7}, a.Owners)
+		b, err := dst.Records.FetchDetails(ctx, "b")
+		require.NoError(t, err)
+		assert.True(t, b.ForceDeleted)
+		c, err := dst.Records.FetchDetails(ctx, "c")
+		require.NoError(t, err)
+		assert.True(t, c.IsDeleted)
+		assert.False(t, c.ForceDeleted)
+		user, err := dst.Users.GetUser(ctx, 7)
+		require.NoError(t, err)
+		assert.Equal(t, model.RoleUser, user.Role)
+	}
+
+	t.Run("copy", func(t *testing.T) {
+		dst := Repos{Records: mem.NewMemRecordRepo(), Users: mem.NewMemUserRepo()}
+		summary, err := Copy(ctx, src, dst)
+		require.NoError(t, err)
+		assert.Equal(t, &Summary{Users: 2, Records: 3}, summary)
+		check(t, dst)
+	})
+
+	t.Run("export and import", func(t *testing.T) {
+		var buf bytes.Buffer
+		_, err := Export(ctx, &buf, src)
+		require.NoError(t, err)
+		dst := Repos{Records: mem.NewMemRecordRepo(), Users: mem.NewMemUserRepo()}
+		summary, err := Import(ctx, &buf, dst)
+		require.NoError(t, err)
+		assert.Equal(t, &Summary{Users: 2, Records: 3}, summary)
+		check(t, dst)
+
+		_, err = Import(ctx, bytes.NewBufferString(`{"record":{"short_code":"z","original_url":"http://a.ru"}}`), dst)
+		assert.ErrorIs(t, err, ErrNotEmpty)
+	})
+}
+
+func TestCopyTeams(t *testing.T) {
+	ctx := context.Background()
+	src := Repos{
+		Records:    mem.NewMemRecordRepo(),
+		Users:      mem.NewMemUserRepo(),
+		Teams:      mem.NewMemTeamRepo(),
+		Identities: mem.NewMemIdentityRepo(),
+		Tokens:     mem.NewMemRefreshTokenRepo(),
+	}
+	owner, err := src.Users.CreateUser(ctx)
+	require.NoError(t, err)
+	team, err := src.Teams.CreateTeam(ctx, "core", owner.ID)
+	require.NoError(t, err)
+	require.NoError(t, src.Teams.SetMember(ctx, &model.TeamMember{TeamID: team.ID, UserID: 5, Role: model.TeamRoleViewer}))
+	require.NoError(t, src.Records.StoreForTeam(ctx, &model.BaseRecord{ShortCode: "t", OriginalURL: "http://t.ru"}, team.ID))
+	require.NoError(t, src.Identities.StoreIdentity(ctx, &model.Identity{Issuer: "https://idp", Subject: "s", UserID: owner.ID}))
+	live := &model.RefreshToken{Hash: "live", UserID: owner.ID, Family: "f", ExpiresAt: time.Now().Add(time.Hour), Used: true}
+	require.NoError(t, src.Tokens.StoreRefreshToken(ctx, live))
+	require.NoError(t, src.Tokens.StoreRefreshToken(ctx, &model.RefreshToken{Hash: "old", UserID: owner.ID, Family: "f"}))
+
+	dst := Repos{
+		Records:    mem.NewMemRecordRepo(),
+		Users:      mem.NewMemUserRepo(),
+		Teams:      mem.NewMemTeamRepo(),
+		Identities: mem.NewMemIdentityRepo(),
+		Tokens:     mem.NewMemRefreshTokenRepo(),
+	}
+	summary, err := Copy(ctx, src, dst)
+	require.NoError(t, err)
+	assert.Equal(t, &Summary{Users: 2, Teams: 1, Identities: 1, RefreshTokens: 1, Records: 1}, summary)
+
+	members, err := dst.Teams.ListMembers(ctx, team.ID)
+	require.NoError(t, err)
+	assert.Equal(t, []model.TeamMember{
+		{TeamID: team.ID, UserID: owner.ID, Role: model.TeamRoleOwner},
+		{TeamID: team.ID, UserID: 5, Role: model.TeamRoleViewer},
+	}, members)
+	records, err := dst.Records.FetchForTeam(ctx, team.ID)
+	require.NoError(t, err)
+	assert.Len(t, records, 1)
+	identity, err := dst.Identities.FetchIdentity(ctx, "https://idp", "s")
+	require.NoError(t, err)
+	assert.Equal(t, owner.ID, identity.UserID)
+	var reusedErr *model.RefreshTokenReusedError
+	_, err = dst.Tokens.UseRefreshToken(ctx, "live", 0)
+	assert.ErrorAs(t, err, &reusedErr, "used tokens stay used")
+	var notFoundErr *model.RefreshTokenNotFoundError
+	_, err = dst.Tokens.UseRefreshToken(ctx, "old", 0)
+	assert.ErrorAs(t, err, &notFoundErr, "expired tokens are left out")
+
+	_, err = Copy(ctx, src, Repos{Records: mem.NewMemRecordRepo(), Users: mem.NewMemUserRepo()})
+	assert.Error(t, err)
+}
+
+func TestCopyIntoNonEmpty(t *testing.T) {
+	ctx := context.Background()
+	src := Repos{Records: mem.NewMemRecordRepo(), Users: mem.NewMemUserRepo()}
+	user, err := src.Users.CreateUser(ctx)
+	require.NoError(t, err)
+	require.NoError(t, src.Records.Store(ctx, &model.BaseRecord{ShortCode: "a", OriginalURL: "http://a.ru"}, user.ID))
+
+	t.Run("Users", func(t *testing.T) {
+		dst := Repos{Records: mem.NewMemRecordRepo(), Users: mem.NewMemUserRepo()}
+		other, err := dst.Users.CreateUser(ctx)
+		require.NoError(t, err)
+		require.NoError(t, dst.Records.Store(ctx, &model.BaseRecord{ShortCode: "b", OriginalURL: "http://b.ru"}, other.ID))
+
+		_, err = Copy(ctx, src, dst)
+		require.ErrorIs(t, err, ErrNotEmpty)
+		records, err := dst.Records.FetchForUser(ctx, other.ID)
+		require.NoError(t, err)
+		assert.Len(t, records, 1, "the existing user gets no links of the source")
+	})
+
+	t.Run("Records without users", func(t *testing.T) {
+		dst := Repos{Records: mem.NewMemRecordRepo(), Users: mem.NewMemUserRepo()}
+		require.NoError(t, dst.Records.Store(ctx, &model.BaseRecord{ShortCode: "b", OriginalURL: "http://b.ru"}, user.ID))
+
+		var buf bytes.Buffer
+		_, err := Export(ctx, &buf, src)
+		require.NoError(t, err)
+		_, err = Import(ctx, &buf, dst)
+		require.ErrorIs(t, err, ErrNotEmpty)
+	})
+
+	t.Run("Teams", func(t *testing.T) {
+		dst := Repos{Records: mem.NewMemRecordRepo(), Users: mem.NewMemUserRepo(), Teams: mem.NewMemTeamRepo()}
+		require.NoError(t, dst.Teams.StoreTeam(ctx, &model.Team{ID: 1, Name: "core"}))
+
+		_, err := Copy(ctx, src, dst)
+		require.ErrorIs(t, err, ErrNotEmpty)
+	})
+}
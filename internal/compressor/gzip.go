@@ -29,6 +29,7 @@ func (c *compressWriter) WriteHeader(statusCode int) {
 	if !httputil.HasHeader(c.w.Header(), httputil.HeaderContentType) || httputil.HasContentType(
 		c.w.Header(),
 		httputil.ContentTypeJSON,
+		httputil.ContentTypeProblemJSON,
 		httputil.ContentTypeTextPlain,
 	) {
 		c.ok = true
@@ -41,7 +42,23 @@ func (c *compressWriter) Header() http.Header {
 	return c.w.Header()
 }
 
+// Flush sends the data compressed so far.
+func (c *compressWriter) Flush() {
+	if c.ok {
+		c.zw.Flush()
+	}
+	http.NewResponseController(c.w).Flush()
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer.
+func (c *compressWriter) Unwrap() http.ResponseWriter {
+	return c.w
+}
+
 func (c *compressWriter) Close() error {
+	if !c.ok {
+		return nil
+	}
 	return c.zw.Close()
 }
 
@@ -1,6 +1,7 @@
 package db
 
 import (
+	"context"
 	"database/sql"
 	"errors"
 	"fmt"
@@ -21,19 +22,41 @@ func NewPG(dsn string) (*sql.DB, error) {
 	return db, nil
 }
 
-func MigratePG(pgDB *sql.DB) error {
+// NewPGMigrator runs the embedded migrations against pgDB on a connection of
+// its own. Closing the migrator releases the connection and leaves pgDB open.
+func NewPGMigrator(pgDB *sql.DB) (*migrate.Migrate, error) {
 	d1, err := iofs.New(migrations.FS, ".")
 	if err != nil {
-		return err
+		return nil, err
 	}
-	d2, err := postgres.WithInstance(pgDB, &postgres.Config{})
+	ctx := context.Background()
+	conn, err := pgDB.Conn(ctx)
 	if err != nil {
-		return err
+		return nil, err
+	}
+	d2, err := postgres.WithConnection(ctx, conn, &postgres.Config{})
+	if err != nil {
+		return nil, errors.Join(err, conn.Close())
 	}
 	m, err := migrate.NewWithInstance("iofs", d1, "postgres", d2)
+	if err != nil {
+		return nil, errors.Join(err, d2.Close())
+	}
+	return m, nil
+}
+
+// CloseMigrator joins the errors of closing the source and the connection.
+func CloseMigrator(m *migrate.Migrate) error {
+	sourceErr, dbErr := m.Close()
+	return errors.Join(sourceErr, dbErr)
+}
+
+func MigratePG(pgDB *sql.DB) (err error) {
+	m, err := NewPGMigrator(pgDB)
 	if err != nil {
 		return err
 	}
+	defer func() { err = errors.Join(err, CloseMigrator(m)) }()
 	err = m.Up()
 	if err != nil && !errors.Is(err, migrate.ErrNoChange) {
 		return err
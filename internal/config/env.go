@@ -10,9 +10,42 @@ func parseEnv(options *Options) {
 	setOptionFromEnv(&options.JWTSecret, "JWT_SECRET")
 	setOptionFromEnv(&options.JWTDuration, "JWT_DURATION")
 	setOptionFromEnv(&options.CookieMaxAge, "COOKIE_MAX_AGE")
+	setOptionFromEnv(&options.RefreshTokenDuration, "REFRESH_TOKEN_DURATION")
+	setOptionFromEnv(&options.RefreshTokenLeeway, "REFRESH_TOKEN_LEEWAY")
 	setOptionFromEnv(&options.DeleterMaxWorkers, "DELETER_MAX_WORKERS")
 	setOptionFromEnv(&options.DeleterMaxBatchSize, "DELETER_MAX_BATCH_SIZE")
 	setOptionFromEnv(&options.DeleterCheckInterval, "DELETER_CHECK_INTERVAL")
+	setOptionFromEnv(&options.AdminUserIDs, "ADMIN_USER_IDS")
+	setOptionFromEnv(&options.AllowAnonymous, "ALLOW_ANONYMOUS")
+	setOptionFromEnv(&options.OIDCIssuer, "OIDC_ISSUER")
+	setOptionFromEnv(&options.OIDCClientID, "OIDC_CLIENT_ID")
+	setOptionFromEnv(&options.OIDCClientSecret, "OIDC_CLIENT_SECRET")
+	setOptionFromEnv(&options.OIDCRedirectURL, "OIDC_REDIRECT_URL")
+	setOptionFromEnv(&options.GRPCAddr, "GRPC_ADDRESS")
+	setOptionFromEnv(&options.IdempotencyTTL, "IDEMPOTENCY_TTL")
+	setOptionFromEnv(&options.ShortCodeGenerator, "SHORT_CODE_GENERATOR")
+	setOptionFromEnv(&options.ShortCodeSalt, "SHORT_CODE_SALT")
+	setOptionFromEnv(&options.ShortCodeLength, "SHORT_CODE_LENGTH")
+	setOptionFromEnv(&options.ShortCodeAlphabet, "SHORT_CODE_ALPHABET")
+	setOptionFromEnv(&options.ShortCodeGrowth, "SHORT_CODE_GROWTH_THRESHOLD")
+	setOptionFromEnv(&options.DenylistPath, "DENYLIST_PATH")
+	setOptionFromEnv(&options.DenylistBuiltin, "DENYLIST_BUILTIN")
+	setOptionFromEnv(&options.CanonicalSortQuery, "CANONICAL_SORT_QUERY")
+	setOptionFromEnv(&options.CanonicalStripTrack, "CANONICAL_STRIP_TRACKING")
+	setOptionFromEnv(&options.RedirectURLForm, "REDIRECT_URL_FORM")
+	setOptionFromEnv(&options.AllowedSchemes, "ALLOWED_SCHEMES")
+	setOptionFromEnv(&options.BlockedRanges, "BLOCKED_RANGES")
+	setOptionFromEnv(&options.ResolveDestinations, "RESOLVE_DESTINATIONS")
+	setOptionFromEnv(&options.DomainDenylistPath, "DOMAIN_DENYLIST_PATH")
+	setOptionFromEnv(&options.DomainDenylistReload, "DOMAIN_DENYLIST_RELOAD")
+	setOptionFromEnv(&options.BlocklistPath, "MALWARE_BLOCKLIST_PATH")
+	setOptionFromEnv(&options.BlocklistReload, "MALWARE_BLOCKLIST_RELOAD")
+	setOptionFromEnv(&options.PasswordMaxAttempts, "LINK_PASSWORD_MAX_ATTEMPTS")
+	setOptionFromEnv(&options.PasswordLinkAttempts, "LINK_PASSWORD_MAX_LINK_ATTEMPTS")
+	setOptionFromEnv(&options.PasswordWindow, "LINK_PASSWORD_WINDOW")
+	setOptionFromEnv(&options.PendingFallbackURL, "PENDING_LINK_FALLBACK_URL")
+	setOptionFromEnv(&options.RedirectStatus, "REDIRECT_STATUS")
+	setOptionFromEnv(&options.RedirectCacheMaxAge, "REDIRECT_CACHE_MAX_AGE")
 }
 
 func setOptionFromEnv(s option, envName string) {
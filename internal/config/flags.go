@@ -11,5 +11,6 @@ func parseArgs(options *Options) {
 	flag.Var(&options.DeleterMaxWorkers, "w", "deleter max workers")
 	flag.Var(&options.DeleterMaxBatchSize, "s", "deleter max batch size")
 	flag.Var(&options.DeleterCheckInterval, "c", "deleter check interval")
+	flag.Var(&options.GRPCAddr, "g", "gRPC bind address, disabled when empty")
 	flag.Parse()
 }
@@ -10,9 +10,43 @@ type Options struct {
 	JWTDuration          Duration
 	CookieName           String
 	CookieMaxAge         Duration
+	RefreshCookieName    String
+	RefreshTokenDuration Duration
+	RefreshTokenLeeway   Duration
 	DeleterMaxWorkers    Integer
 	DeleterMaxBatchSize  Integer
 	DeleterCheckInterval Duration
+	AdminUserIDs         IntegerList
+	AllowAnonymous       Bool
+	OIDCIssuer           String
+	OIDCClientID         String
+	OIDCClientSecret     String
+	OIDCRedirectURL      String
+	GRPCAddr             String
+	IdempotencyTTL       Duration
+	ShortCodeGenerator   ShortCodeGenerator
+	ShortCodeSalt        String
+	ShortCodeLength      Integer
+	ShortCodeAlphabet    ShortCodeAlphabet
+	ShortCodeGrowth      Float
+	DenylistPath         String
+	DenylistBuiltin      Bool
+	CanonicalSortQuery   Bool
+	CanonicalStripTrack  Bool
+	RedirectURLForm      RedirectURLForm
+	AllowedSchemes       StringList
+	BlockedRanges        StringList
+	ResolveDestinations  Bool
+	DomainDenylistPath   String
+	DomainDenylistReload Duration
+	BlocklistPath        String
+	BlocklistReload      Duration
+	PasswordMaxAttempts  Integer
+	PasswordLinkAttempts Integer
+	PasswordWindow       Duration
+	PendingFallbackURL   String
+	RedirectStatus       RedirectStatus
+	RedirectCacheMaxAge  Duration
 }
 
 func New(
@@ -25,9 +59,43 @@ func New(
 	jwtDuration,
 	cookieName,
 	cookieMaxAge,
+	refreshCookieName,
+	refreshTokenDuration,
+	refreshTokenLeeway,
 	deleterMaxWorkers,
 	deleterMaxBatchSize,
-	deleterCheckInterval string,
+	deleterCheckInterval,
+	adminUserIDs,
+	allowAnonymous,
+	oidcIssuer,
+	oidcClientID,
+	oidcClientSecret,
+	oidcRedirectURL,
+	grpcAddr,
+	idempotencyTTL,
+	shortCodeGenerator,
+	shortCodeSalt,
+	shortCodeLength,
+	shortCodeAlphabet,
+	shortCodeGrowth,
+	denylistPath,
+	denylistBuiltin,
+	canonicalSortQuery,
+	canonicalStripTrack,
+	redirectURLForm,
+	allowedSchemes,
+	blockedRanges,
+	resolveDestinations,
+	domainDenylistPath,
+	domainDenylistReload,
+	blocklistPath,
+	blocklistReload,
+	passwordMaxAttempts,
+	passwordLinkAttempts,
+	passwordWindow,
+	pendingFallbackURL,
+	redirectStatus,
+	redirectCacheMaxAge string,
 ) *Options {
 	options := Options{}
 	setOptionFromString(&options.BaseURL, baseURL)
@@ -39,9 +107,43 @@ func New(
 	setOptionFromString(&options.JWTDuration, jwtDuration)
 	setOptionFromString(&options.CookieMaxAge, cookieMaxAge)
 	setOptionFromString(&options.CookieName, cookieName)
+	setOptionFromString(&options.RefreshCookieName, refreshCookieName)
+	setOptionFromString(&options.RefreshTokenDuration, refreshTokenDuration)
+	setOptionFromString(&options.RefreshTokenLeeway, refreshTokenLeeway)
 	setOptionFromString(&options.DeleterMaxWorkers, deleterMaxWorkers)
 	setOptionFromString(&options.DeleterMaxBatchSize, deleterMaxBatchSize)
 	setOptionFromString(&options.DeleterCheckInterval, deleterCheckInterval)
+	setOptionFromString(&options.AdminUserIDs, adminUserIDs)
+	setOptionFromString(&options.AllowAnonymous, allowAnonymous)
+	setOptionFromString(&options.OIDCIssuer, oidcIssuer)
+	setOptionFromString(&options.OIDCClientID, oidcClientID)
+	setOptionFromString(&options.OIDCClientSecret, oidcClientSecret)
+	setOptionFromString(&options.OIDCRedirectURL, oidcRedirectURL)
+	setOptionFromString(&options.GRPCAddr, grpcAddr)
+	setOptionFromString(&options.IdempotencyTTL, idempotencyTTL)
+	setOptionFromString(&options.ShortCodeGenerator, shortCodeGenerator)
+	setOptionFromString(&options.ShortCodeSalt, shortCodeSalt)
+	setOptionFromString(&options.ShortCodeLength, shortCodeLength)
+	setOptionFromString(&options.ShortCodeAlphabet, shortCodeAlphabet)
+	setOptionFromString(&options.ShortCodeGrowth, shortCodeGrowth)
+	setOptionFromString(&options.DenylistPath, denylistPath)
+	setOptionFromString(&options.DenylistBuiltin, denylistBuiltin)
+	setOptionFromString(&options.CanonicalSortQuery, canonicalSortQuery)
+	setOptionFromString(&options.CanonicalStripTrack, canonicalStripTrack)
+	setOptionFromString(&options.RedirectURLForm, redirectURLForm)
+	setOptionFromString(&options.AllowedSchemes, allowedSchemes)
+	setOptionFromString(&options.BlockedRanges, blockedRanges)
+	setOptionFromString(&options.ResolveDestinations, resolveDestinations)
+	setOptionFromString(&options.DomainDenylistPath, domainDenylistPath)
+	setOptionFromString(&options.DomainDenylistReload, domainDenylistReload)
+	setOptionFromString(&options.BlocklistPath, blocklistPath)
+	setOptionFromString(&options.BlocklistReload, blocklistReload)
+	setOptionFromString(&options.PasswordMaxAttempts, passwordMaxAttempts)
+	setOptionFromString(&options.PasswordLinkAttempts, passwordLinkAttempts)
+	setOptionFromString(&options.PasswordWindow, passwordWindow)
+	setOptionFromString(&options.PendingFallbackURL, pendingFallbackURL)
+	setOptionFromString(&options.RedirectStatus, redirectStatus)
+	setOptionFromString(&options.RedirectCacheMaxAge, redirectCacheMaxAge)
 	return &options
 }
 
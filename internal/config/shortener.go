@@ -1,6 +1,11 @@
 package config
 
-import "github.com/domurdoc/shortener/internal/utils"
+import (
+	"strings"
+
+	"github.com/domurdoc/shortener/internal/policy"
+	"github.com/domurdoc/shortener/internal/utils"
+)
 
 func LoadOptions() *Options {
 	options := New(
@@ -13,9 +18,43 @@ func LoadOptions() *Options {
 		"600s",
 		"ilovesber",
 		"600s",
+		"ilovesber_refresh",
+		"720h",
+		"60s",
 		"10",
 		"10",
 		"5s",
+		"",
+		"true",
+		"",
+		"",
+		"",
+		"",
+		"",
+		"24h",
+		"random",
+		"",
+		"6",
+		"letters",
+		"0.01",
+		"",
+		"true",
+		"false",
+		"false",
+		"original",
+		"http,https",
+		strings.Join(policy.DefaultBlockedRanges, ","),
+		"false",
+		"",
+		"30s",
+		"",
+		"5m",
+		"5",
+		"100",
+		"15m",
+		"",
+		"307",
+		"24h",
 	)
 	parseArgs(options)
 	parseEnv(options)
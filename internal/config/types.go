@@ -2,11 +2,15 @@ package config
 
 import (
 	"fmt"
+	"maps"
 	"net/url"
 	"slices"
 	"strconv"
 	"strings"
 	"time"
+
+	"github.com/domurdoc/shortener/internal/model"
+	"github.com/domurdoc/shortener/internal/utils"
 )
 
 type LogLevel struct {
@@ -27,6 +31,124 @@ func (l *LogLevel) Set(value string) error {
 	return fmt.Errorf("must be one of (case-insensitive): %v", levels)
 }
 
+// Code generators accepted by ShortCodeGenerator.
+const (
+	CodeGeneratorRandom   = "random"
+	CodeGeneratorSequence = "sequence"
+	CodeGeneratorHashids  = "hashids"
+	CodeGeneratorHash     = "hash"
+)
+
+type ShortCodeGenerator struct {
+	text string
+}
+
+func (g ShortCodeGenerator) String() string {
+	return g.text
+}
+
+func (g *ShortCodeGenerator) Set(value string) error {
+	generators := []string{CodeGeneratorRandom, CodeGeneratorSequence, CodeGeneratorHashids, CodeGeneratorHash}
+	value = strings.ToLower(value)
+	if slices.Contains(generators, value) {
+		g.text = value
+		return nil
+	}
+	return fmt.Errorf("must be one of (case-insensitive): %v", generators)
+}
+
+// URL forms accepted by RedirectURLForm.
+const (
+	URLFormOriginal  = "original"
+	URLFormCanonical = "canonical"
+)
+
+type RedirectURLForm struct {
+	text string
+}
+
+func (f RedirectURLForm) String() string {
+	return f.text
+}
+
+func (f *RedirectURLForm) Set(value string) error {
+	forms := []string{URLFormOriginal, URLFormCanonical}
+	value = strings.ToLower(value)
+	if slices.Contains(forms, value) {
+		f.text = value
+		return nil
+	}
+	return fmt.Errorf("must be one of (case-insensitive): %v", forms)
+}
+
+// RedirectStatus is one of model.RedirectStatuses.
+type RedirectStatus struct {
+	code int
+}
+
+func (s RedirectStatus) String() string {
+	return strconv.Itoa(s.code)
+}
+
+func (s RedirectStatus) Code() int {
+	return s.code
+}
+
+func (s *RedirectStatus) Set(value string) error {
+	code, err := strconv.Atoi(value)
+	if err != nil || !model.IsRedirectStatus(code) {
+		return fmt.Errorf("must be one of %v", model.RedirectStatuses)
+	}
+	s.code = code
+	return nil
+}
+
+// shortCodeAlphabets are the named alphabets of ShortCodeAlphabet.
+var shortCodeAlphabets = map[string]string{
+	"letters":  utils.ALPHA,
+	"base62":   utils.BASE62,
+	"readable": utils.READABLE,
+}
+
+// ShortCodeAlphabet is a named alphabet or the characters themselves, which
+// must be unique and URL path safe.
+type ShortCodeAlphabet struct {
+	text  string
+	chars string
+}
+
+func (a ShortCodeAlphabet) String() string {
+	return a.text
+}
+
+func (a ShortCodeAlphabet) Chars() string {
+	return a.chars
+}
+
+func (a *ShortCodeAlphabet) Set(value string) error {
+	if chars, ok := shortCodeAlphabets[strings.ToLower(value)]; ok {
+		a.text, a.chars = strings.ToLower(value), chars
+		return nil
+	}
+	if len(value) < 10 {
+		return fmt.Errorf("must be one of %v or at least 10 characters", slices.Sorted(maps.Keys(shortCodeAlphabets)))
+	}
+	for i, c := range value {
+		if !isShortCodeChar(c) {
+			return fmt.Errorf("character %q is not one of a-z, A-Z, 0-9, - and _", c)
+		}
+		if strings.ContainsRune(value[:i], c) {
+			return fmt.Errorf("character %q is repeated", c)
+		}
+	}
+	a.text, a.chars = value, value
+	return nil
+}
+
+func isShortCodeChar(c rune) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '-' || c == '_'
+}
+
 type NetAddress struct {
 	Host string
 	Port int
@@ -114,3 +236,77 @@ func (i *Integer) Set(value string) error {
 func (i Integer) String() string {
 	return strconv.Itoa(int(i))
 }
+
+type Float float64
+
+func (f *Float) Set(value string) error {
+	n, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return err
+	}
+	*f = Float(n)
+	return nil
+}
+
+func (f Float) String() string {
+	return strconv.FormatFloat(float64(f), 'g', -1, 64)
+}
+
+type StringList []string
+
+func (l *StringList) Set(value string) error {
+	var list StringList
+	for _, part := range strings.Split(value, ",") {
+		if part = strings.TrimSpace(part); part != "" {
+			list = append(list, part)
+		}
+	}
+	*l = list
+	return nil
+}
+
+func (l StringList) String() string {
+	return strings.Join(l, ",")
+}
+
+type IntegerList []int
+
+func (l *IntegerList) Set(value string) error {
+	var list IntegerList
+	for _, part := range strings.Split(value, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		n, err := strconv.Atoi(part)
+		if err != nil {
+			return err
+		}
+		list = append(list, n)
+	}
+	*l = list
+	return nil
+}
+
+func (l IntegerList) String() string {
+	parts := make([]string, len(l))
+	for i, n := range l {
+		parts[i] = strconv.Itoa(n)
+	}
+	return strings.Join(parts, ",")
+}
+
+type Bool bool
+
+func (b *Bool) Set(value string) error {
+	v, err := strconv.ParseBool(value)
+	if err != nil {
+		return err
+	}
+	*b = Bool(v)
+	return nil
+}
+
+func (b Bool) String() string {
+	return strconv.FormatBool(bool(b))
+}
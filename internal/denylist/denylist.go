@@ -0,0 +1,107 @@
+// Package denylist rejects short codes that spell offensive or otherwise
+// unwanted words, also when written in leetspeak.
+package denylist
+
+import (
+	"bufio"
+	_ "embed"
+	"io"
+	"os"
+	"strings"
+)
+
+//go:embed words.txt
+var builtin string
+
+// leet folds lookalike characters into one letter. Both codes and words are
+// folded, so "hell" also matches "he11" and "heII".
+var leet = map[rune]rune{
+	'0': 'o',
+	'1': 'i',
+	'l': 'i',
+	'3': 'e',
+	'4': 'a',
+	'5': 's',
+	'7': 't',
+	'8': 'b',
+	'9': 'g',
+}
+
+type Denylist struct {
+	words    []string
+	patterns []string
+}
+
+// New builds a denylist of words, adding the built-in ones when withBuiltin
+// is set.
+func New(words []string, withBuiltin bool) *Denylist {
+	d := &Denylist{}
+	if withBuiltin {
+		words, _ := Parse(strings.NewReader(builtin))
+		d.Add(words...)
+	}
+	d.Add(words...)
+	return d
+}
+
+// Load reads extra words from a file in the words.txt format.
+func Load(path string, withBuiltin bool) (*Denylist, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	words, err := Parse(file)
+	if err != nil {
+		return nil, err
+	}
+	return New(words, withBuiltin), nil
+}
+
+// Parse reads one word per line, skipping blank lines and # comments.
+func Parse(r io.Reader) ([]string, error) {
+	var words []string
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		word := strings.TrimSpace(scanner.Text())
+		if word == "" || strings.HasPrefix(word, "#") {
+			continue
+		}
+		words = append(words, word)
+	}
+	return words, scanner.Err()
+}
+
+func (d *Denylist) Add(words ...string) {
+	for _, word := range words {
+		if pattern := normalize(word); pattern != "" {
+			d.words = append(d.words, word)
+			d.patterns = append(d.patterns, pattern)
+		}
+	}
+}
+
+// Match returns the first denied word found in code.
+func (d *Denylist) Match(code string) (string, bool) {
+	code = normalize(code)
+	for i, pattern := range d.patterns {
+		if strings.Contains(code, pattern) {
+			return d.words[i], true
+		}
+	}
+	return "", false
+}
+
+// normalize lowercases s, folds leetspeak and drops separators such as - and _.
+func normalize(s string) string {
+	var b strings.Builder
+	for _, c := range strings.ToLower(s) {
+		if folded, ok := leet[c]; ok {
+			c = folded
+		}
+		if c >= 'a' && c <= 'z' {
+			b.WriteRune(c)
+		}
+	}
+	return b.String()
+}
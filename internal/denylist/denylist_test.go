@@ -0,0 +1,46 @@
+package denylist
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDenylist_Match(t *testing.T) {
+	d := New([]string{"acme"}, true)
+	tests := []struct {
+		code string
+		want bool
+	}{
+		{code: "xxSHITxx", want: true},
+		{code: "a5shole", want: true},
+		{code: "s1ut00", want: true},
+		{code: "sIut00", want: true},
+		{code: "AcMe42", want: true},
+		{code: "4cm3zz", want: true},
+		{code: "a-c_m-e", want: true},
+		{code: "qwErty", want: false},
+		{code: "QWrtpz", want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.code, func(t *testing.T) {
+			_, denied := d.Match(tt.code)
+			assert.Equal(t, tt.want, denied)
+		})
+	}
+}
+
+func TestDenylist_WithoutBuiltin(t *testing.T) {
+	words, err := Parse(strings.NewReader("# brands\n\nacme\n  globex  \n"))
+	require.NoError(t, err)
+	assert.Equal(t, []string{"acme", "globex"}, words)
+
+	d := New(words, false)
+	_, denied := d.Match("shit42")
+	assert.False(t, denied)
+	word, denied := d.Match("GL0BEX")
+	assert.True(t, denied)
+	assert.Equal(t, "globex", word)
+}
@@ -0,0 +1,153 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/domurdoc/shortener/internal/httputil"
+	"github.com/domurdoc/shortener/internal/model"
+	"github.com/domurdoc/shortener/internal/service"
+)
+
+const (
+	defaultUsersLimit = 100
+	maxUsersLimit     = 1000
+)
+
+type AdminHandler struct {
+	service *service.AdminService
+}
+
+func NewAdmin(service *service.AdminService) *AdminHandler {
+	return &AdminHandler{service: service}
+}
+
+type jsonRecordDetails struct {
+	ShortCode   model.ShortCode   `json:"short_code"`
+	ShortURL    model.ShortURL    `json:"short_url"`
+	OriginalURL model.OriginalURL `json:"original_url"`
+	IsDeleted   bool              `json:"is_deleted"`
+	Owners      []model.UserID    `json:"owners"`
+	Teams       []model.TeamID    `json:"teams,omitempty"`
+}
+
+type jsonUser struct {
+	ID       model.UserID `json:"id"`
+	Role     model.Role   `json:"role"`
+	Disabled bool         `json:"disabled"`
+}
+
+type jsonStats struct {
+	URLs       int `json:"urls"`
+	ActiveURLs int `json:"active_urls"`
+	Users      int `json:"users"`
+}
+
+func (h *AdminHandler) LookupShortCode(w http.ResponseWriter, r *http.Request) {
+	details, err := h.service.LookupShortCode(r.Context(), r.PathValue("shortCode"))
+	if h.writeShortCodeError(w, err) {
+		return
+	}
+	owners := details.Owners
+	if owners == nil {
+		owners = []model.UserID{}
+	}
+	writeJSONResponse(w, jsonRecordDetails{
+		ShortCode:   details.ShortCode,
+		ShortURL:    details.ShortURL,
+		OriginalURL: details.OriginalURL,
+		IsDeleted:   details.IsDeleted,
+		Owners:      owners,
+		Teams:       details.Teams,
+	}, http.StatusOK)
+}
+
+func (h *AdminHandler) ForceDelete(w http.ResponseWriter, r *http.Request) {
+	err := h.service.ForceDelete(r.Context(), r.PathValue("shortCode"))
+	if h.writeShortCodeError(w, err) {
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *AdminHandler) Restore(w http.ResponseWriter, r *http.Request) {
+	err := h.service.Restore(r.Context(), r.PathValue("shortCode"))
+	if h.writeShortCodeError(w, err) {
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *AdminHandler) ListUsers(w http.ResponseWriter, r *http.Request) {
+	limit, err := queryInt(r, "limit", defaultUsersLimit)
+	if err != nil || limit <= 0 || limit > maxUsersLimit {
+		httputil.Error(w, "limit must be between 1 and 1000", http.StatusBadRequest)
+		return
+	}
+	offset, err := queryInt(r, "offset", 0)
+	if err != nil || offset < 0 {
+		httputil.Error(w, "offset must be non-negative", http.StatusBadRequest)
+		return
+	}
+	users, err := h.service.ListUsers(r.Context(), limit, offset)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	jsonUsers := make([]jsonUser, 0, len(users))
+	for _, user := range users {
+		jsonUsers = append(jsonUsers, jsonUser(user))
+	}
+	writeJSONResponse(w, jsonUsers, http.StatusOK)
+}
+
+func (h *AdminHandler) DisableUser(w http.ResponseWriter, r *http.Request) {
+	h.setUserDisabled(w, r, true)
+}
+
+func (h *AdminHandler) EnableUser(w http.ResponseWriter, r *http.Request) {
+	h.setUserDisabled(w, r, false)
+}
+
+func (h *AdminHandler) Stats(w http.ResponseWriter, r *http.Request) {
+	stats, err := h.service.Stats(r.Context())
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSONResponse(w, jsonStats{
+		URLs:       stats.Records,
+		ActiveURLs: stats.ActiveRecords,
+		Users:      stats.Users,
+	}, http.StatusOK)
+}
+
+func (h *AdminHandler) setUserDisabled(w http.ResponseWriter, r *http.Request, disabled bool) {
+	userID, err := strconv.Atoi(r.PathValue("userID"))
+	if err != nil {
+		httputil.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	err = h.service.SetUserDisabled(r.Context(), model.UserID(userID), disabled)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *AdminHandler) writeShortCodeError(w http.ResponseWriter, err error) bool {
+	if err == nil {
+		return false
+	}
+	writeError(w, err)
+	return true
+}
+
+func queryInt(r *http.Request, key string, defaultValue int) (int, error) {
+	value := r.URL.Query().Get(key)
+	if value == "" {
+		return defaultValue, nil
+	}
+	return strconv.Atoi(value)
+}
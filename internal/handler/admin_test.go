@@ -0,0 +1,49 @@
+package handler
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/domurdoc/shortener/internal/model"
+	"github.com/domurdoc/shortener/internal/repository/mem"
+	"github.com/domurdoc/shortener/internal/service"
+)
+
+func TestAdmin_ForceDeleteRestore(t *testing.T) {
+	recordRepo := mem.NewMemRecordRepo()
+	userRepo := mem.NewMemUserRepo()
+	handler := New(newTestService("", recordRepo, service.Options{}), nil, nil)
+	adminHandler := NewAdmin(service.NewAdmin("", recordRepo, userRepo))
+
+	user, err := userRepo.CreateUser(context.TODO())
+	require.NoError(t, err)
+	record := &model.BaseRecord{ShortCode: "x", OriginalURL: "http://yandex.com"}
+	require.NoError(t, recordRepo.Store(context.TODO(), record, user.ID))
+
+	retrieve := func() int {
+		r := httptest.NewRequest(http.MethodGet, "/{shortCode}", nil)
+		r.SetPathValue("shortCode", "x")
+		w := httptest.NewRecorder()
+		handler.Retrieve(w, r)
+		return w.Code
+	}
+	admin := func(h http.HandlerFunc, shortCode string) int {
+		r := httptest.NewRequest(http.MethodPost, "/api/admin/urls/{shortCode}", nil)
+		r.SetPathValue("shortCode", shortCode)
+		w := httptest.NewRecorder()
+		h(w, r)
+		return w.Code
+	}
+
+	assert.Equal(t, http.StatusNoContent, admin(adminHandler.ForceDelete, "x"))
+	assert.Equal(t, http.StatusGone, retrieve())
+	assert.Equal(t, http.StatusOK, admin(adminHandler.LookupShortCode, "x"))
+	assert.Equal(t, http.StatusNoContent, admin(adminHandler.Restore, "x"))
+	assert.Equal(t, http.StatusTemporaryRedirect, retrieve())
+	assert.Equal(t, http.StatusNotFound, admin(adminHandler.ForceDelete, "y"))
+}
@@ -9,19 +9,22 @@ import (
 )
 
 type Handler struct {
-	service *service.Service
+	service     *service.Service
+	teams       *service.TeamService
+	idempotency *service.IdempotencyService
 }
 
-func New(service *service.Service) *Handler {
-	return &Handler{service: service}
+// idempotency may be nil, Idempotency-Key headers are ignored then.
+func New(service *service.Service, teams *service.TeamService, idempotency *service.IdempotencyService) *Handler {
+	return &Handler{service: service, teams: teams, idempotency: idempotency}
 }
 
-func (h *Handler) writeJSONResponse(w http.ResponseWriter, response any, status int) {
+func writeJSONResponse(w http.ResponseWriter, response any, status int) {
 	httputil.SetContentType(w.Header(), httputil.ContentTypeJSON)
 	w.WriteHeader(status)
 	enc := json.NewEncoder(w)
 	if err := enc.Encode(response); err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		writeError(w, err)
 		return
 	}
 }
@@ -0,0 +1,14 @@
+package handler
+
+import (
+	"time"
+
+	"github.com/domurdoc/shortener/internal/repository"
+	"github.com/domurdoc/shortener/internal/service"
+)
+
+// newTestService builds a service for handler tests, opts hold only what a
+// test cares about.
+func newTestService(baseURL string, repo repository.RecordRepo, opts service.Options) *service.Service {
+	return service.New(baseURL, 1, 1, time.Second, repo, nil, nil, opts)
+}
@@ -15,12 +15,12 @@ func (h *Handler) DeleteShortCodes(w http.ResponseWriter, r *http.Request) {
 	user := auth.GetUser(r)
 
 	if !httputil.HasContentType(r.Header, httputil.ContentTypeJSON) {
-		http.Error(w, fmt.Sprintf("wanted Content-Type: %s", httputil.ContentTypeJSON), http.StatusBadRequest)
+		httputil.Error(w, fmt.Sprintf("wanted Content-Type: %s", httputil.ContentTypeJSON), http.StatusBadRequest)
 		return
 	}
 	dec := json.NewDecoder(r.Body)
 	if err := dec.Decode(&shortCodes); err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		httputil.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
 	go h.service.DeleteShortCodes(r.Context(), user, shortCodes)
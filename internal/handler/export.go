@@ -0,0 +1,210 @@
+package handler
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"html"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/domurdoc/shortener/internal/auth"
+	"github.com/domurdoc/shortener/internal/httputil"
+	"github.com/domurdoc/shortener/internal/model"
+)
+
+const exportPageSize = 500
+
+const (
+	exportFormatCSV  = "csv"
+	exportFormatJSON = "json"
+	exportFormatHTML = "html"
+)
+
+var exportCSVHeader = []string{"short_url", "original_url", "created_at", "clicks"}
+
+type jsonExportRecord struct {
+	ShortURL    model.ShortURL    `json:"short_url"`
+	OriginalURL model.OriginalURL `json:"original_url"`
+	CreatedAt   time.Time         `json:"created_at"`
+	Clicks      int64             `json:"clicks"`
+}
+
+type exportWriter interface {
+	Begin() error
+	Write(model.RecordDetails) error
+	End() error
+}
+
+// ExportForUser streams the user's personal links followed by links of the
+// user's teams page by page, so exports of any size keep memory flat.
+func (h *Handler) ExportForUser(w http.ResponseWriter, r *http.Request) {
+	user := auth.GetUser(r)
+
+	format := r.URL.Query().Get("format")
+	if format == "" {
+		format = exportFormatJSON
+	}
+	bw := bufio.NewWriter(w)
+	var exporter exportWriter
+	var contentType string
+	switch format {
+	case exportFormatCSV:
+		exporter, contentType = &csvExportWriter{w: csv.NewWriter(bw)}, httputil.ContentTypeCSV
+	case exportFormatJSON:
+		exporter, contentType = &jsonExportWriter{w: bw}, httputil.ContentTypeJSON
+	case exportFormatHTML:
+		exporter, contentType = &bookmarksExportWriter{w: bw}, httputil.ContentTypeHTML
+	default:
+		httputil.Error(
+			w,
+			fmt.Sprintf("format must be one of: %s, %s, %s", exportFormatCSV, exportFormatJSON, exportFormatHTML),
+			http.StatusBadRequest,
+		)
+		return
+	}
+
+	records, err := h.teams.ListAllURLs(r.Context(), user, exportPageSize, 0)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	httputil.SetContentType(w.Header(), contentType)
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="urls.%s"`, format))
+	w.WriteHeader(http.StatusOK)
+
+	if err := exporter.Begin(); err != nil {
+		return
+	}
+	for offset := 0; len(records) > 0; {
+		for _, record := range records {
+			if err := exporter.Write(record); err != nil {
+				return
+			}
+		}
+		if err := bw.Flush(); err != nil {
+			return
+		}
+		http.NewResponseController(w).Flush()
+		if len(records) < exportPageSize {
+			break
+		}
+		offset += len(records)
+		// The status is already sent, a failure here can only cut the
+		// export short.
+		records, err = h.teams.ListAllURLs(r.Context(), user, exportPageSize, offset)
+		if err != nil {
+			return
+		}
+	}
+	if err := exporter.End(); err != nil {
+		return
+	}
+	bw.Flush()
+}
+
+type csvExportWriter struct {
+	w *csv.Writer
+}
+
+func (c *csvExportWriter) Begin() error {
+	return c.w.Write(exportCSVHeader)
+}
+
+func (c *csvExportWriter) Write(record model.RecordDetails) error {
+	// Flush into the page buffer right away, the handler flushes pages.
+	if err := c.w.Write([]string{
+		string(record.ShortURL),
+		string(record.OriginalURL),
+		formatCreatedAt(record.CreatedAt),
+		strconv.FormatInt(record.Clicks, 10),
+	}); err != nil {
+		return err
+	}
+	c.w.Flush()
+	return c.w.Error()
+}
+
+func (c *csvExportWriter) End() error {
+	c.w.Flush()
+	return c.w.Error()
+}
+
+type jsonExportWriter struct {
+	w     io.Writer
+	count int
+}
+
+func (j *jsonExportWriter) Begin() error {
+	_, err := io.WriteString(j.w, "[")
+	return err
+}
+
+func (j *jsonExportWriter) Write(record model.RecordDetails) error {
+	data, err := json.Marshal(jsonExportRecord{
+		ShortURL:    record.ShortURL,
+		OriginalURL: record.OriginalURL,
+		CreatedAt:   record.CreatedAt,
+		Clicks:      record.Clicks,
+	})
+	if err != nil {
+		return err
+	}
+	if j.count > 0 {
+		if _, err := io.WriteString(j.w, ","); err != nil {
+			return err
+		}
+	}
+	j.count++
+	_, err = j.w.Write(data)
+	return err
+}
+
+func (j *jsonExportWriter) End() error {
+	_, err := io.WriteString(j.w, "]\n")
+	return err
+}
+
+// bookmarksExportWriter writes the Netscape bookmark file format browsers and
+// read-later tools import.
+type bookmarksExportWriter struct {
+	w io.Writer
+}
+
+func (b *bookmarksExportWriter) Begin() error {
+	_, err := io.WriteString(b.w, `<!DOCTYPE NETSCAPE-Bookmark-file-1>
+<META HTTP-EQUIV="Content-Type" CONTENT="text/html; charset=UTF-8">
+<TITLE>Bookmarks</TITLE>
+<H1>Bookmarks</H1>
+<DL><p>
+`)
+	return err
+}
+
+func (b *bookmarksExportWriter) Write(record model.RecordDetails) error {
+	_, err := fmt.Fprintf(
+		b.w,
+		"    <DT><A HREF=\"%s\" ADD_DATE=\"%d\">%s</A>\n    <DD>%s, %d clicks\n",
+		html.EscapeString(string(record.OriginalURL)),
+		max(record.CreatedAt.Unix(), 0),
+		html.EscapeString(string(record.OriginalURL)),
+		html.EscapeString(string(record.ShortURL)),
+		record.Clicks,
+	)
+	return err
+}
+
+func (b *bookmarksExportWriter) End() error {
+	_, err := io.WriteString(b.w, "</DL><p>\n")
+	return err
+}
+
+func formatCreatedAt(createdAt time.Time) string {
+	if createdAt.IsZero() {
+		return ""
+	}
+	return createdAt.UTC().Format(time.RFC3339)
+}
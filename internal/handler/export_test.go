@@ -0,0 +1,106 @@
+package handler
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"path"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/domurdoc/shortener/internal/auth"
+	"github.com/domurdoc/shortener/internal/httputil"
+	"github.com/domurdoc/shortener/internal/model"
+	"github.com/domurdoc/shortener/internal/repository/mem"
+	"github.com/domurdoc/shortener/internal/service"
+)
+
+func TestShortener_ExportForUser(t *testing.T) {
+	userRepo := mem.NewMemUserRepo()
+	user, err := userRepo.CreateUser(context.TODO())
+	require.NoError(t, err)
+	repo := mem.NewMemRecordRepo()
+	svc := newTestService("http://localhost:8081", repo, service.Options{})
+	teams := service.NewTeam(mem.NewMemTeamRepo(), svc)
+	handler := New(svc, teams, nil)
+
+	shortURL, err := svc.Shorten(context.TODO(), user, "http://a.com/?q=<b>", model.LinkOptions{})
+	require.NoError(t, err)
+	_, err = svc.Shorten(context.TODO(), user, "http://b.com", model.LinkOptions{})
+	require.NoError(t, err)
+	team, err := teams.CreateTeam(context.TODO(), user, "ops")
+	require.NoError(t, err)
+	teamURL, err := teams.Shorten(context.TODO(), user, team.ID, "http://c.com", model.LinkOptions{})
+	require.NoError(t, err)
+	parsed, err := url.Parse(shortURL)
+	require.NoError(t, err)
+	for range 2 {
+		_, err = svc.GetByShortCode(context.TODO(), path.Base(parsed.Path), "", "")
+		require.NoError(t, err)
+	}
+
+	export := func(format string) (*http.Response, string) {
+		r := httptest.NewRequest(http.MethodGet, "/api/user/urls/export?format="+format, nil)
+		w := httptest.NewRecorder()
+		handler.ExportForUser(w, auth.AttachUser(r, user))
+		resp := w.Result()
+		body, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+		require.NoError(t, resp.Body.Close())
+		return resp, string(body)
+	}
+
+	t.Run("Invalid format", func(t *testing.T) {
+		resp, _ := export("xml")
+		assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+	})
+
+	t.Run("JSON", func(t *testing.T) {
+		resp, body := export("json")
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Equal(t, httputil.ContentTypeJSON, resp.Header.Get(httputil.HeaderContentType))
+		var records []jsonExportRecord
+		require.NoError(t, json.Unmarshal([]byte(body), &records))
+		require.Len(t, records, 3)
+		assert.Equal(t, shortURL, string(records[0].ShortURL))
+		assert.Equal(t, int64(2), records[0].Clicks)
+		assert.False(t, records[0].CreatedAt.IsZero())
+		assert.Equal(t, int64(0), records[1].Clicks)
+		assert.Equal(t, teamURL, string(records[2].ShortURL))
+	})
+
+	t.Run("CSV", func(t *testing.T) {
+		resp, body := export("csv")
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Equal(t, `attachment; filename="urls.csv"`, resp.Header.Get("Content-Disposition"))
+		rows, err := csv.NewReader(strings.NewReader(body)).ReadAll()
+		require.NoError(t, err)
+		require.Len(t, rows, 4)
+		assert.Equal(t, exportCSVHeader, rows[0])
+		assert.Equal(t, []string{shortURL, "http://a.com/?q=<b>"}, rows[1][:2])
+		assert.Equal(t, "2", rows[1][3])
+	})
+
+	t.Run("HTML", func(t *testing.T) {
+		resp, body := export("html")
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.True(t, strings.HasPrefix(body, "<!DOCTYPE NETSCAPE-Bookmark-file-1>"))
+		assert.Contains(t, body, `HREF="http://a.com/?q=&lt;b&gt;"`)
+		assert.Contains(t, body, shortURL)
+		assert.NotContains(t, body, "<b>")
+	})
+
+	t.Run("Clicks are saved on close", func(t *testing.T) {
+		require.NoError(t, svc.Close())
+		details, err := repo.FetchDetails(context.TODO(), model.ShortCode(path.Base(parsed.Path)))
+		require.NoError(t, err)
+		assert.Equal(t, int64(2), details.Clicks)
+	})
+}
@@ -0,0 +1,115 @@
+package handler
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"net/http"
+
+	"github.com/domurdoc/shortener/internal/auth"
+	"github.com/domurdoc/shortener/internal/httputil"
+	"github.com/domurdoc/shortener/internal/model"
+)
+
+const (
+	HeaderIdempotencyKey      = "Idempotency-Key"
+	HeaderIdempotencyReplayed = "Idempotency-Replayed"
+
+	idempotencyKeyMaxLength = 255
+)
+
+type recordingResponseWriter struct {
+	http.ResponseWriter
+	statusCode int
+	body       bytes.Buffer
+}
+
+func (w *recordingResponseWriter) WriteHeader(statusCode int) {
+	if w.statusCode == 0 {
+		w.statusCode = statusCode
+	}
+	w.ResponseWriter.WriteHeader(statusCode)
+}
+
+func (w *recordingResponseWriter) Write(b []byte) (int, error) {
+	if w.statusCode == 0 {
+		w.statusCode = http.StatusOK
+	}
+	w.body.Write(b)
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *recordingResponseWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// Idempotent replays the stored response when a request is retried with the
+// same Idempotency-Key. The key is reserved before the request runs, so a
+// concurrent retry gets 409 with Retry-After instead of running it again.
+// Server errors are not stored, so they can be retried.
+func (h *Handler) Idempotent(next http.HandlerFunc) http.HandlerFunc {
+	if h.idempotency == nil {
+		return next
+	}
+	return func(w http.ResponseWriter, r *http.Request) {
+		key := r.Header.Get(HeaderIdempotencyKey)
+		if key == "" {
+			next(w, r)
+			return
+		}
+		if len(key) > idempotencyKeyMaxLength {
+			httputil.Error(w, "Idempotency-Key is too long", http.StatusBadRequest)
+			return
+		}
+		user := auth.GetUser(r)
+
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			httputil.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		r.Body = io.NopCloser(bytes.NewReader(body))
+		hash := sha256.New()
+		io.WriteString(hash, r.Method+" "+r.URL.RequestURI()+"\n")
+		hash.Write(body)
+		requestHash := hex.EncodeToString(hash.Sum(nil))
+
+		stored, err := h.idempotency.Begin(r.Context(), user, key, requestHash)
+		var pendingErr *model.IdempotencyKeyPendingError
+		if errors.As(err, &pendingErr) {
+			w.Header().Set("Retry-After", "1")
+		}
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+		if stored != nil {
+			if stored.ContentType != "" {
+				httputil.SetContentType(w.Header(), stored.ContentType)
+			}
+			w.Header().Set(HeaderIdempotencyReplayed, "true")
+			w.WriteHeader(stored.StatusCode)
+			w.Write(stored.Body)
+			return
+		}
+
+		recorder := &recordingResponseWriter{ResponseWriter: w}
+		next(recorder, r)
+		if recorder.statusCode == 0 || recorder.statusCode >= http.StatusInternalServerError {
+			h.idempotency.Release(r.Context(), user, key)
+			return
+		}
+		// The response is already sent, a failure to keep it only means a
+		// retry runs the request again.
+		h.idempotency.Save(r.Context(), &model.IdempotencyKey{
+			UserID:      user.ID,
+			Key:         key,
+			RequestHash: requestHash,
+			StatusCode:  recorder.statusCode,
+			ContentType: w.Header().Get(httputil.HeaderContentType),
+			Body:        recorder.body.Bytes(),
+		})
+	}
+}
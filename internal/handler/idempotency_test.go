@@ -0,0 +1,107 @@
+package handler
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/domurdoc/shortener/internal/auth"
+	"github.com/domurdoc/shortener/internal/httputil"
+	"github.com/domurdoc/shortener/internal/model"
+	"github.com/domurdoc/shortener/internal/repository/mem"
+	"github.com/domurdoc/shortener/internal/service"
+)
+
+func TestShortener_Idempotent(t *testing.T) {
+	userRepo := mem.NewMemUserRepo()
+	user, err := userRepo.CreateUser(context.TODO())
+	require.NoError(t, err)
+	shortener := newTestService("http://localhost:8081", mem.NewMemRecordRepo(), service.Options{})
+	idempotency := service.NewIdempotency(mem.NewMemIdempotencyRepo(), time.Hour)
+	handler := New(shortener, nil, idempotency)
+	shorten := handler.Idempotent(handler.ShortenJSON)
+
+	do := func(key, body string) (*http.Response, string) {
+		r := httptest.NewRequest(http.MethodPost, "/api/shorten", strings.NewReader(body))
+		r.Header.Set(httputil.HeaderContentType, httputil.ContentTypeJSON)
+		if key != "" {
+			r.Header.Set(HeaderIdempotencyKey, key)
+		}
+		w := httptest.NewRecorder()
+		shorten(w, auth.AttachUser(r, user))
+		resp := w.Result()
+		respBody, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+		require.NoError(t, resp.Body.Close())
+		return resp, string(respBody)
+	}
+
+	resp, body := do("k1", `{"url": "http://a.com"}`)
+	require.Equal(t, http.StatusCreated, resp.StatusCode)
+	assert.Empty(t, resp.Header.Get(HeaderIdempotencyReplayed))
+
+	resp, replayedBody := do("k1", `{"url": "http://a.com"}`)
+	assert.Equal(t, http.StatusCreated, resp.StatusCode)
+	assert.Equal(t, "true", resp.Header.Get(HeaderIdempotencyReplayed))
+	assert.Equal(t, httputil.ContentTypeJSON, resp.Header.Get(httputil.HeaderContentType))
+	assert.Equal(t, body, replayedBody)
+
+	resp, _ = do("k1", `{"url": "http://b.com"}`)
+	assert.Equal(t, http.StatusUnprocessableEntity, resp.StatusCode)
+
+	resp, _ = do("", `{"url": "http://a.com"}`)
+	assert.Equal(t, http.StatusConflict, resp.StatusCode)
+
+	resp, _ = do(strings.Repeat("k", idempotencyKeyMaxLength+1), `{"url": "http://c.com"}`)
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+}
+
+func TestShortener_IdempotentPending(t *testing.T) {
+	user := &model.User{ID: 1}
+	idempotency := service.NewIdempotency(mem.NewMemIdempotencyRepo(), time.Hour)
+	handler := New(newTestService("http://localhost:8081", mem.NewMemRecordRepo(), service.Options{}), nil, idempotency)
+	shorten := handler.Idempotent(handler.ShortenJSON)
+	body := `{"url": "http://a.com"}`
+	do := func() *httptest.ResponseRecorder {
+		r := httptest.NewRequest(http.MethodPost, "/api/shorten", strings.NewReader(body))
+		r.Header.Set(httputil.HeaderContentType, httputil.ContentTypeJSON)
+		r.Header.Set(HeaderIdempotencyKey, "k1")
+		w := httptest.NewRecorder()
+		shorten(w, auth.AttachUser(r, user))
+		return w
+	}
+
+	// The first request with the key still runs.
+	hash := sha256.Sum256([]byte("POST /api/shorten\n" + body))
+	requestHash := hex.EncodeToString(hash[:])
+	stored, err := idempotency.Begin(context.TODO(), user, "k1", requestHash)
+	require.NoError(t, err)
+	require.Nil(t, stored)
+
+	w := do()
+	assert.Equal(t, http.StatusConflict, w.Code)
+	assert.Equal(t, "1", w.Header().Get("Retry-After"))
+	assert.Contains(t, w.Body.String(), httputil.ProblemTypePrefix+"idempotency-key-pending")
+
+	require.NoError(t, idempotency.Save(context.TODO(), &model.IdempotencyKey{
+		UserID:      user.ID,
+		Key:         "k1",
+		RequestHash: requestHash,
+		StatusCode:  http.StatusCreated,
+		ContentType: httputil.ContentTypeJSON,
+		Body:        []byte(`{"result":"http://localhost:8081/first"}`),
+	}))
+	w = do()
+	assert.Equal(t, http.StatusCreated, w.Code)
+	assert.Equal(t, "true", w.Header().Get(HeaderIdempotencyReplayed))
+	assert.JSONEq(t, `{"result":"http://localhost:8081/first"}`, w.Body.String())
+}
@@ -0,0 +1,115 @@
+package handler
+
+import (
+	"net/http"
+
+	"go.uber.org/zap"
+
+	"github.com/domurdoc/shortener/internal/auth"
+	"github.com/domurdoc/shortener/internal/auth/oidc"
+	"github.com/domurdoc/shortener/internal/httputil"
+	"github.com/domurdoc/shortener/internal/model"
+	"github.com/domurdoc/shortener/internal/service"
+)
+
+const (
+	oidcSessionCookie     = "oidc_session"
+	oidcSessionCookiePath = "/api/auth/oidc"
+	oidcSessionMaxAge     = 600
+)
+
+type OIDCHandler struct {
+	provider   *oidc.Provider
+	auth       *auth.Auth
+	identities *service.IdentityService
+	secret     string
+	log        *zap.SugaredLogger
+}
+
+// NewOIDC logs failures of the identity provider to log, browsers only get a
+// generic error.
+func NewOIDC(
+	provider *oidc.Provider,
+	auth *auth.Auth,
+	identities *service.IdentityService,
+	secret string,
+	log *zap.SugaredLogger,
+) *OIDCHandler {
+	return &OIDCHandler{
+		provider:   provider,
+		auth:       auth,
+		identities: identities,
+		secret:     secret,
+		log:        log,
+	}
+}
+
+type jsonLoginResponse struct {
+	UserID model.UserID `json:"user_id"`
+}
+
+func (h *OIDCHandler) Login(w http.ResponseWriter, r *http.Request) {
+	session := oidc.NewSession()
+	authURL, err := h.provider.AuthCodeURL(r.Context(), session)
+	if err != nil {
+		h.log.Errorw("oidc discovery failed", "err", err)
+		httputil.Error(w, "identity provider unavailable", http.StatusBadGateway)
+		return
+	}
+	h.setSessionCookie(w, session.Encode(h.secret), oidcSessionMaxAge)
+	http.Redirect(w, r, authURL, http.StatusFound)
+}
+
+func (h *OIDCHandler) Callback(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	if errMsg := query.Get("error"); errMsg != "" {
+		h.log.Infow("oidc login rejected", "error", errMsg, "description", query.Get("error_description"))
+		httputil.Error(w, "login failed", http.StatusUnauthorized)
+		return
+	}
+	cookie, err := r.Cookie(oidcSessionCookie)
+	if err != nil {
+		httputil.Error(w, "no login in progress", http.StatusBadRequest)
+		return
+	}
+	session, err := oidc.DecodeSession(cookie.Value, h.secret)
+	if err != nil || session.State != query.Get("state") {
+		httputil.Error(w, "state mismatch", http.StatusBadRequest)
+		return
+	}
+	h.setSessionCookie(w, "", -1)
+
+	claims, err := h.provider.Exchange(r.Context(), query.Get("code"), session)
+	if err != nil {
+		h.log.Warnw("oidc code exchange failed", "err", err)
+		httputil.Error(w, "login failed", http.StatusUnauthorized)
+		return
+	}
+	current, _ := h.auth.Authenticate(r.Context(), r)
+	user, err := h.identities.ResolveUser(r.Context(), current, h.provider.Issuer(), claims.Subject)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	if user.Disabled {
+		auth.WriteError(w, &auth.UserDisabledError{UserID: user.ID})
+		return
+	}
+	if err := h.auth.Login(r.Context(), w, user); err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSONResponse(w, jsonLoginResponse{UserID: user.ID}, http.StatusOK)
+}
+
+// The session cookie must survive the cross-site redirect back from the IdP, hence Lax.
+func (h *OIDCHandler) setSessionCookie(w http.ResponseWriter, value string, maxAge int) {
+	http.SetCookie(w, &http.Cookie{
+		Name:     oidcSessionCookie,
+		Value:    value,
+		Path:     oidcSessionCookiePath,
+		MaxAge:   maxAge,
+		HttpOnly: true,
+		SameSite: http.SameSiteLaxMode,
+	})
+}
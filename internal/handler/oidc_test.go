@@ -0,0 +1,190 @@
+package handler
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/domurdoc/shortener/internal/auth"
+	"github.com/domurdoc/shortener/internal/auth/oidc"
+	"github.com/domurdoc/shortener/internal/auth/strategy"
+	"github.com/domurdoc/shortener/internal/auth/transport"
+	"github.com/domurdoc/shortener/internal/repository/mem"
+	"github.com/domurdoc/shortener/internal/service"
+)
+
+type fakeIdP struct {
+	*httptest.Server
+	key       *rsa.PrivateKey
+	subject   string
+	challenge string
+	nonce     string
+}
+
+func newFakeIdP(t *testing.T) *fakeIdP {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	idp := &fakeIdP{key: key, subject: "alice"}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		writeJSONResponse(w, map[string]string{
+			"issuer":                 idp.URL,
+			"authorization_endpoint": idp.URL + "/authorize",
+			"token_endpoint":         idp.URL + "/token",
+			"jwks_uri":               idp.URL + "/jwks",
+		}, http.StatusOK)
+	})
+	mux.HandleFunc("/jwks", func(w http.ResponseWriter, r *http.Request) {
+		writeJSONResponse(w, map[string]any{"keys": []map[string]string{{
+			"kid": "k1",
+			"kty": "RSA",
+			"n":   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+			"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+		}}}, http.StatusOK)
+	})
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		clientID, clientSecret, _ := r.BasicAuth()
+		sum := sha256.Sum256([]byte(r.PostFormValue("code_verifier")))
+		if clientID != "client" || clientSecret != "secret" ||
+			r.PostFormValue("code") != "code" ||
+			base64.RawURLEncoding.EncodeToString(sum[:]) != idp.challenge {
+			writeJSONResponse(w, map[string]string{"error": "invalid_grant"}, http.StatusBadRequest)
+			return
+		}
+		token := jwt.NewWithClaims(jwt.SigningMethodRS256, oidc.Claims{
+			RegisteredClaims: jwt.RegisteredClaims{
+				Issuer:    idp.URL,
+				Subject:   idp.subject,
+				Audience:  jwt.ClaimStrings{"client"},
+				ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Minute)),
+			},
+			Nonce: idp.nonce,
+		})
+		token.Header["kid"] = "k1"
+		idToken, err := token.SignedString(key)
+		require.NoError(t, err)
+		writeJSONResponse(w, map[string]string{"id_token": idToken}, http.StatusOK)
+	})
+	idp.Server = httptest.NewServer(mux)
+	t.Cleanup(idp.Close)
+	return idp
+}
+
+func TestOIDC_LoginCallback(t *testing.T) {
+	idp := newFakeIdP(t)
+	userRepo := mem.NewMemUserRepo()
+	a := auth.New(strategy.NewJWT("jwt", time.Hour), transport.NewCookie("access", 3600, false), userRepo, nil)
+	h := NewOIDC(
+		oidc.NewProvider(idp.URL, "client", "secret", "http://localhost/api/auth/oidc/callback", idp.Client()),
+		a,
+		service.NewIdentity(mem.NewMemIdentityRepo(), userRepo),
+		"cookie-secret",
+		zap.NewNop().Sugar(),
+	)
+
+	login := func() (*http.Cookie, url.Values) {
+		w := httptest.NewRecorder()
+		h.Login(w, httptest.NewRequest(http.MethodGet, "/api/auth/oidc/login", nil))
+		require.Equal(t, http.StatusFound, w.Code)
+		location, err := url.Parse(w.Header().Get("Location"))
+		require.NoError(t, err)
+		query := location.Query()
+		assert.Equal(t, "S256", query.Get("code_challenge_method"))
+		idp.challenge = query.Get("code_challenge")
+		idp.nonce = query.Get("nonce")
+		return cookiesByName(w)[oidcSessionCookie], query
+	}
+	callback := func(session *http.Cookie, state string, extra ...*http.Cookie) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(http.MethodGet, "/api/auth/oidc/callback?code=code&state="+url.QueryEscape(state), nil)
+		r.AddCookie(session)
+		for _, c := range extra {
+			r.AddCookie(c)
+		}
+		w := httptest.NewRecorder()
+		h.Callback(w, r)
+		return w
+	}
+	userID := func(w *httptest.ResponseRecorder) int {
+		var resp jsonLoginResponse
+		require.NoError(t, json.NewDecoder(w.Body).Decode(&resp))
+		return int(resp.UserID)
+	}
+
+	session, query := login()
+	w := callback(session, query.Get("state"))
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, cookiesByName(w), "access")
+	first := userID(w)
+
+	t.Run("same subject maps to same user", func(t *testing.T) {
+		session, query := login()
+		w := callback(session, query.Get("state"))
+		require.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, first, userID(w))
+	})
+
+	t.Run("anonymous user is linked on first login", func(t *testing.T) {
+		anon := httptest.NewRecorder()
+		_, err := a.AuthenticateOrRegisterAndLogin(t.Context(), anon, httptest.NewRequest(http.MethodGet, "/", nil))
+		require.NoError(t, err)
+		anonCookie := cookiesByName(anon)["access"]
+		anonUser, err := a.Authenticate(t.Context(), withCookie(anonCookie))
+		require.NoError(t, err)
+
+		idp.subject = "bob"
+		session, query := login()
+		w := callback(session, query.Get("state"), anonCookie)
+		require.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, int(anonUser.ID), userID(w))
+	})
+
+	t.Run("state mismatch is rejected", func(t *testing.T) {
+		session, _ := login()
+		w := callback(session, "forged")
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+
+	t.Run("provider errors are not passed on", func(t *testing.T) {
+		session, _ := login()
+		r := httptest.NewRequest(http.MethodGet, "/api/auth/oidc/callback?error=access_denied&error_description=internal+realm+corp", nil)
+		r.AddCookie(session)
+		w := httptest.NewRecorder()
+		h.Callback(w, r)
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+		assert.NotContains(t, w.Body.String(), "access_denied")
+
+		session, query := login()
+		idp.nonce = "other"
+		w = callback(session, query.Get("state"))
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+		assert.NotContains(t, w.Body.String(), "nonce")
+	})
+}
+
+func cookiesByName(w *httptest.ResponseRecorder) map[string]*http.Cookie {
+	cookies := make(map[string]*http.Cookie)
+	for _, c := range w.Result().Cookies() {
+		cookies[c.Name] = c
+	}
+	return cookies
+}
+
+func withCookie(c *http.Cookie) *http.Request {
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.AddCookie(c)
+	return r
+}
@@ -0,0 +1,13 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/domurdoc/shortener/api"
+	"github.com/domurdoc/shortener/internal/httputil"
+)
+
+func (h *Handler) OpenAPI(w http.ResponseWriter, r *http.Request) {
+	httputil.SetContentType(w.Header(), httputil.ContentTypeJSON)
+	w.Write(api.OpenAPI)
+}
@@ -0,0 +1,152 @@
+package handler_test
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/getkin/kin-openapi/openapi3filter"
+	"github.com/getkin/kin-openapi/routers/legacy"
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/domurdoc/shortener/api"
+	"github.com/domurdoc/shortener/internal/auth"
+	"github.com/domurdoc/shortener/internal/auth/strategy"
+	"github.com/domurdoc/shortener/internal/auth/transport"
+	"github.com/domurdoc/shortener/internal/handler"
+	"github.com/domurdoc/shortener/internal/model"
+	"github.com/domurdoc/shortener/internal/repository/mem"
+	"github.com/domurdoc/shortener/internal/router"
+	"github.com/domurdoc/shortener/internal/service"
+)
+
+func TestOpenAPI_ResponsesMatchSchema(t *testing.T) {
+	doc, err := openapi3.NewLoader().LoadFromData(api.OpenAPI)
+	require.NoError(t, err)
+	require.NoError(t, doc.Validate(context.Background()))
+	specRouter, err := legacy.NewRouter(doc)
+	require.NoError(t, err)
+
+	recordRepo := mem.NewMemRecordRepo()
+	svc := service.New("http://localhost:8080", 1, 1, time.Second, recordRepo, nil, nil, service.Options{})
+	teams := service.NewTeam(mem.NewMemTeamRepo(), svc)
+	userRepo := mem.NewMemUserRepo()
+	a := auth.New(strategy.NewJWT("secret", time.Hour), transport.NewCookie("ilovesber", 3600, false), userRepo, nil)
+	admin := handler.NewAdmin(service.NewAdmin("http://localhost:8080", recordRepo, userRepo))
+	h := router.New(handler.New(svc, teams, nil), admin, nil, auth.NewAuthMiddleware(a, true))
+
+	var cookies []*http.Cookie
+	do := func(method, path, contentType, body string) *http.Response {
+		t.Helper()
+		r := httptest.NewRequest(method, "http://localhost:8080"+path, strings.NewReader(body))
+		if contentType != "" {
+			r.Header.Set("Content-Type", contentType)
+		}
+		for _, c := range cookies {
+			r.AddCookie(c)
+		}
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		resp := w.Result()
+		if c := resp.Cookies(); len(c) > 0 {
+			cookies = c
+		}
+
+		route, pathParams, err := specRouter.FindRoute(r)
+		require.NoError(t, err, "%s %s is not documented", method, path)
+		r.Body = io.NopCloser(strings.NewReader(body))
+		reqInput := &openapi3filter.RequestValidationInput{
+			Request:    r,
+			PathParams: pathParams,
+			Route:      route,
+			Options:    &openapi3filter.Options{AuthenticationFunc: openapi3filter.NoopAuthenticationFunc},
+		}
+		respBody, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+		err = openapi3filter.ValidateResponse(context.Background(), &openapi3filter.ResponseValidationInput{
+			RequestValidationInput: reqInput,
+			Status:                 resp.StatusCode,
+			Header:                 resp.Header,
+			Body:                   io.NopCloser(bytes.NewReader(respBody)),
+		})
+		assert.NoError(t, err, "%s %s -> %d %s", method, path, resp.StatusCode, respBody)
+		return resp
+	}
+
+	assert.Equal(t, http.StatusCreated, do(http.MethodPost, "/", "text/plain", "http://yandex.com").StatusCode)
+	assert.Equal(t, http.StatusConflict, do(http.MethodPost, "/", "text/plain", "http://yandex.com").StatusCode)
+	assert.Equal(t, http.StatusBadRequest, do(http.MethodPost, "/", "text/plain", "yandex").StatusCode)
+	assert.Equal(t, http.StatusCreated, do(http.MethodPost, "/api/shorten", "application/json", `{"url":"http://ya.ru"}`).StatusCode)
+	assert.Equal(t, http.StatusConflict, do(http.MethodPost, "/api/shorten", "application/json", `{"url":"http://ya.ru"}`).StatusCode)
+	assert.Equal(t, http.StatusCreated, do(http.MethodPost, "/api/shorten/batch", "application/json",
+		`[{"correlation_id":"1","original_url":"http://a.ru"},{"correlation_id":"2","original_url":"http://b.ru"}]`).StatusCode)
+	assert.Equal(t, http.StatusConflict, do(http.MethodPost, "/api/shorten/batch", "application/json",
+		`[{"correlation_id":"1","original_url":"http://a.ru"}]`).StatusCode)
+	assert.Equal(t, http.StatusOK, do(http.MethodGet, "/api/user/urls", "", "").StatusCode)
+	assert.Equal(t, http.StatusAccepted, do(http.MethodDelete, "/api/user/urls", "application/json", `["nope"]`).StatusCode)
+
+	records, err := recordRepo.FetchForUser(context.Background(), 1)
+	require.NoError(t, err)
+	require.NotEmpty(t, records)
+	shortCode := string(records[0].ShortCode)
+	assert.Equal(t, http.StatusTemporaryRedirect, do(http.MethodGet, "/"+shortCode, "", "").StatusCode)
+	assert.Equal(t, http.StatusNoContent, do(http.MethodPut, "/api/user/urls/"+shortCode+"/password", "application/json", `{"password":"pw"}`).StatusCode)
+	assert.Equal(t, http.StatusUnauthorized, do(http.MethodGet, "/"+shortCode, "", "").StatusCode)
+	assert.Equal(t, http.StatusUnauthorized, do(http.MethodPost, "/"+shortCode, "application/x-www-form-urlencoded", "password=nope").StatusCode)
+	assert.Equal(t, http.StatusSeeOther, do(http.MethodPost, "/"+shortCode, "application/x-www-form-urlencoded", "password=pw").StatusCode)
+	assert.Equal(t, http.StatusNoContent, do(http.MethodDelete, "/api/user/urls/"+shortCode+"/password", "", "").StatusCode)
+	assert.Equal(t, http.StatusNotFound, do(http.MethodDelete, "/api/user/urls/missing/password", "", "").StatusCode)
+	assert.Equal(t, http.StatusNotFound, do(http.MethodGet, "/missing", "", "").StatusCode)
+	require.NoError(t, recordRepo.ForceDelete(context.Background(), records[0].ShortCode))
+	assert.Equal(t, http.StatusGone, do(http.MethodGet, "/"+shortCode, "", "").StatusCode)
+
+	assert.Equal(t, http.StatusCreated, do(http.MethodPost, "/api/teams", "application/json", `{"name":"ops"}`).StatusCode)
+	assert.Equal(t, http.StatusOK, do(http.MethodGet, "/api/teams", "", "").StatusCode)
+	assert.Equal(t, http.StatusOK, do(http.MethodGet, "/api/teams/1/members", "", "").StatusCode)
+	assert.Equal(t, http.StatusNoContent, do(http.MethodPut, "/api/teams/1/members/2", "application/json", `{"role":"viewer"}`).StatusCode)
+	assert.Equal(t, http.StatusConflict, do(http.MethodDelete, "/api/teams/1/members/1", "", "").StatusCode)
+	assert.Equal(t, http.StatusNoContent, do(http.MethodDelete, "/api/teams/1/members/2", "", "").StatusCode)
+	assert.Equal(t, http.StatusNotFound, do(http.MethodGet, "/api/teams/9/members", "", "").StatusCode)
+	assert.Equal(t, http.StatusNoContent, do(http.MethodDelete, "/api/teams/1/urls", "application/json", `[]`).StatusCode)
+	assert.Equal(t, http.StatusOK, do(http.MethodGet, "/api/openapi.json", "", "").StatusCode)
+
+	assert.Equal(t, http.StatusForbidden, do(http.MethodGet, "/api/admin/stats", "", "").StatusCode)
+	require.NoError(t, userRepo.SetUserRole(context.Background(), 1, model.RoleAdmin))
+	assert.Equal(t, http.StatusOK, do(http.MethodGet, "/api/admin/stats", "", "").StatusCode)
+	assert.Equal(t, http.StatusOK, do(http.MethodGet, "/api/admin/users", "", "").StatusCode)
+	assert.Equal(t, http.StatusOK, do(http.MethodGet, "/api/admin/urls/"+shortCode, "", "").StatusCode)
+	assert.Equal(t, http.StatusNotFound, do(http.MethodGet, "/api/admin/urls/missing", "", "").StatusCode)
+	assert.Equal(t, http.StatusNoContent, do(http.MethodPost, "/api/admin/urls/"+shortCode+"/restore", "", "").StatusCode)
+	assert.Equal(t, http.StatusNoContent, do(http.MethodDelete, "/api/admin/urls/"+shortCode, "", "").StatusCode)
+	other, err := userRepo.CreateUser(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusNoContent, do(http.MethodPost, fmt.Sprintf("/api/admin/users/%d/disable", other.ID), "", "").StatusCode)
+	assert.Equal(t, http.StatusNoContent, do(http.MethodPost, fmt.Sprintf("/api/admin/users/%d/enable", other.ID), "", "").StatusCode)
+}
+
+// TestOpenAPI_DocumentsEveryRoute fails for routes added without a spec entry.
+func TestOpenAPI_DocumentsEveryRoute(t *testing.T) {
+	doc, err := openapi3.NewLoader().LoadFromData(api.OpenAPI)
+	require.NoError(t, err)
+
+	oidcHandler := handler.NewOIDC(nil, nil, nil, "", nil)
+	routes, ok := router.New(handler.New(nil, nil, nil), handler.NewAdmin(nil), oidcHandler).(chi.Routes)
+	require.True(t, ok)
+	err = chi.Walk(routes, func(method, route string, _ http.Handler, _ ...func(http.Handler) http.Handler) error {
+		pathItem := doc.Paths.Find(route)
+		if assert.NotNil(t, pathItem, "%s is not documented", route) {
+			assert.NotNil(t, pathItem.GetOperation(method), "%s %s is not documented", method, route)
+		}
+		return nil
+	})
+	require.NoError(t, err)
+}
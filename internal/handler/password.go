@@ -0,0 +1,147 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html/template"
+	"math"
+	"net/http"
+	"time"
+
+	"github.com/domurdoc/shortener/internal/auth"
+	"github.com/domurdoc/shortener/internal/httputil"
+	"github.com/domurdoc/shortener/internal/model"
+	"github.com/domurdoc/shortener/internal/service"
+)
+
+type jsonPasswordRequest struct {
+	Password string `json:"password"`
+}
+
+// passwordPage asks for the password of a protected link and posts it back
+// to the link itself.
+var passwordPage = template.Must(template.New("password").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<meta name="robots" content="noindex">
+<title>Protected link</title>
+</head>
+<body>
+<h1>This link is protected</h1>
+{{if .}}<p role="alert">{{.}}</p>
+{{end}}<form method="post">
+<label>Password <input type="password" name="password" autocomplete="off" autofocus required></label>
+<button type="submit">Continue</button>
+</form>
+</body>
+</html>
+`))
+
+// writePasswordPage answers a password error with the form and a short note
+// on what went wrong.
+func writePasswordPage(w http.ResponseWriter, err error) {
+	var message string
+	status := http.StatusUnauthorized
+	var passwordErr *model.LinkPasswordError
+	var attemptsErr *model.TooManyAttemptsError
+	switch {
+	case errors.As(err, &attemptsErr):
+		status = http.StatusTooManyRequests
+		message = fmt.Sprintf("Too many wrong passwords, try again in %s.", attemptsErr.RetryAfter.Round(time.Second))
+	case errors.As(err, &passwordErr) && passwordErr.Given:
+		message = "Wrong password."
+	}
+	httputil.SetContentType(w.Header(), httputil.ContentTypeHTML)
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(status)
+	passwordPage.Execute(w, message)
+}
+
+func retryAfterSeconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
+
+// SetLinkPassword protects a link of the user with a password.
+func (h *Handler) SetLinkPassword(w http.ResponseWriter, r *http.Request) {
+	user := auth.GetUser(r)
+
+	password, ok := readPassword(w, r)
+	if !ok {
+		return
+	}
+	if err := h.service.SetPassword(r.Context(), user, r.PathValue("shortCode"), password); err != nil {
+		writeError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// RemoveLinkPassword makes a protected link of the user open again.
+func (h *Handler) RemoveLinkPassword(w http.ResponseWriter, r *http.Request) {
+	user := auth.GetUser(r)
+
+	if err := h.service.SetPassword(r.Context(), user, r.PathValue("shortCode"), ""); err != nil {
+		writeError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// SetTeamLinkPassword protects a link created for the team with a password,
+// members need the edit permission.
+func (h *Handler) SetTeamLinkPassword(w http.ResponseWriter, r *http.Request) {
+	user := auth.GetUser(r)
+
+	teamID, err := pathTeamID(r)
+	if err != nil {
+		httputil.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	password, ok := readPassword(w, r)
+	if !ok {
+		return
+	}
+	if err := h.teams.SetPassword(r.Context(), user, teamID, r.PathValue("shortCode"), password); err != nil {
+		writeError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// RemoveTeamLinkPassword makes a protected team link open again.
+func (h *Handler) RemoveTeamLinkPassword(w http.ResponseWriter, r *http.Request) {
+	user := auth.GetUser(r)
+
+	teamID, err := pathTeamID(r)
+	if err != nil {
+		httputil.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err := h.teams.SetPassword(r.Context(), user, teamID, r.PathValue("shortCode"), ""); err != nil {
+		writeError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// readPassword answers bad requests itself and reports whether to go on.
+func readPassword(w http.ResponseWriter, r *http.Request) (string, bool) {
+	var req jsonPasswordRequest
+
+	if !httputil.HasContentType(r.Header, httputil.ContentTypeJSON) {
+		httputil.Error(w, fmt.Sprintf("wanted Content-Type: %s", httputil.ContentTypeJSON), http.StatusBadRequest)
+		return "", false
+	}
+	dec := json.NewDecoder(r.Body)
+	if err := dec.Decode(&req); err != nil {
+		httputil.Error(w, err.Error(), http.StatusBadRequest)
+		return "", false
+	}
+	if req.Password == "" || len(req.Password) > service.MaxPasswordLength {
+		httputil.Error(w, fmt.Sprintf("password must be 1 to %d bytes", service.MaxPasswordLength), http.StatusBadRequest)
+		return "", false
+	}
+	return req.Password, true
+}
@@ -0,0 +1,122 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"slices"
+
+	"github.com/domurdoc/shortener/internal/httputil"
+	"github.com/domurdoc/shortener/internal/model"
+)
+
+// problemFor maps model errors to problems with stable types. It returns nil
+// for errors clients should not learn about.
+func problemFor(err error) *httputil.Problem {
+	var (
+		invalidURLErr          *model.InvalidURLError
+		blockedErr             *model.DestinationBlockedError
+		notFoundErr            *model.ShortCodeNotFoundError
+		deletedErr             *model.ShortCodeDeletedError
+		clickLimitErr          *model.ClickLimitReachedError
+		pendingErr             *model.LinkPendingError
+		expiredErr             *model.LinkExpiredError
+		passwordErr            *model.LinkPasswordError
+		attemptsErr            *model.TooManyAttemptsError
+		urlExistsErr           *model.OriginalURLExistsError
+		batchURLExistsErr      model.BatchOriginalURLExistsError
+		userNotFoundErr        *model.UserNotFoundError
+		teamNotFoundErr        *model.TeamNotFoundError
+		memberNotFoundErr      *model.TeamMemberNotFoundError
+		permissionErr          *model.TeamPermissionError
+		lastOwnerErr           *model.LastTeamOwnerError
+		idempotencyMismatchErr *model.IdempotencyKeyMismatchError
+		idempotencyPendingErr  *model.IdempotencyKeyPendingError
+	)
+	switch {
+	case errors.As(err, &invalidURLErr):
+		return httputil.NewProblem(http.StatusBadRequest, "invalid-url", "Invalid URL", invalidURLErr.Msg).
+			With("url", invalidURLErr.URL)
+	case errors.As(err, &blockedErr):
+		return httputil.NewProblem(http.StatusBadRequest, "destination-blocked", "Destination not allowed", blockedErr.Msg).
+			With("url", blockedErr.URL).
+			With("rule", blockedErr.Rule)
+	case errors.As(err, &notFoundErr):
+		return httputil.NewProblem(http.StatusNotFound, "short-code-not-found", "Short code not found", err.Error()).
+			With("short_code", notFoundErr.ShortCode)
+	case errors.As(err, &deletedErr):
+		return httputil.NewProblem(http.StatusGone, "short-code-deleted", "Link deleted", err.Error()).
+			With("short_code", deletedErr.ShortCode)
+	case errors.As(err, &clickLimitErr):
+		return httputil.NewProblem(http.StatusGone, "click-limit-reached", "Link used up", err.Error()).
+			With("short_code", clickLimitErr.ShortCode).
+			With("max_clicks", clickLimitErr.MaxClicks)
+	case errors.As(err, &pendingErr):
+		return httputil.NewProblem(http.StatusNotFound, "link-not-yet-active", "Link not yet available", err.Error()).
+			With("short_code", pendingErr.ShortCode).
+			With("active_from", pendingErr.ActiveFrom)
+	case errors.As(err, &expiredErr):
+		return httputil.NewProblem(http.StatusGone, "link-expired", "Link expired", err.Error()).
+			With("short_code", expiredErr.ShortCode).
+			With("active_until", expiredErr.ActiveUntil)
+	case errors.As(err, &passwordErr) && passwordErr.Given:
+		return httputil.NewProblem(http.StatusUnauthorized, "wrong-password", "Wrong link password", err.Error()).
+			With("short_code", passwordErr.ShortCode)
+	case errors.As(err, &passwordErr):
+		return httputil.NewProblem(http.StatusUnauthorized, "password-required", "Link password required", err.Error()).
+			With("short_code", passwordErr.ShortCode)
+	case errors.As(err, &attemptsErr):
+		return httputil.NewProblem(http.StatusTooManyRequests, "too-many-attempts", "Too many password attempts", err.Error()).
+			With("short_code", attemptsErr.ShortCode).
+			With("retry_after", retryAfterSeconds(attemptsErr.RetryAfter))
+	case errors.As(err, &urlExistsErr):
+		problem := httputil.NewProblem(http.StatusConflict, "url-exists", "URL already shortened", err.Error()).
+			With("short_code", urlExistsErr.ShortCode)
+		if urlExistsErr.OptionsIgnored {
+			problem.With("options_applied", false)
+		}
+		return problem
+	case errors.As(err, &batchURLExistsErr):
+		detail := "some URLs are already shortened"
+		if slices.ContainsFunc(batchURLExistsErr, func(e *model.OriginalURLExistsError) bool { return e.OptionsIgnored }) {
+			detail += ", link options were not applied to them"
+		}
+		return httputil.NewProblem(http.StatusConflict, "url-exists", "URL already shortened", detail)
+	case errors.As(err, &userNotFoundErr):
+		return httputil.NewProblem(http.StatusNotFound, "user-not-found", "User not found", err.Error()).
+			With("user_id", userNotFoundErr.UserID)
+	case errors.As(err, &teamNotFoundErr):
+		return httputil.NewProblem(http.StatusNotFound, "team-not-found", "Team not found", err.Error()).
+			With("team_id", teamNotFoundErr.TeamID)
+	case errors.As(err, &memberNotFoundErr):
+		return httputil.NewProblem(http.StatusNotFound, "team-member-not-found", "Team member not found", err.Error()).
+			With("team_id", memberNotFoundErr.TeamID).
+			With("user_id", memberNotFoundErr.UserID)
+	case errors.As(err, &permissionErr):
+		return httputil.NewProblem(http.StatusForbidden, "team-permission-denied", "Team permission denied", err.Error()).
+			With("team_id", permissionErr.TeamID).
+			With("permission", permissionErr.Permission)
+	case errors.As(err, &lastOwnerErr):
+		return httputil.NewProblem(http.StatusConflict, "last-team-owner", "Team needs an owner", err.Error()).
+			With("team_id", lastOwnerErr.TeamID)
+	case errors.As(err, &idempotencyMismatchErr):
+		return httputil.NewProblem(
+			http.StatusUnprocessableEntity,
+			"idempotency-key-mismatch",
+			"Idempotency key reused",
+			err.Error(),
+		).With("idempotency_key", idempotencyMismatchErr.Key)
+	case errors.As(err, &idempotencyPendingErr):
+		return httputil.NewProblem(http.StatusConflict, "idempotency-key-pending", "Request in progress", err.Error()).
+			With("idempotency_key", idempotencyPendingErr.Key)
+	}
+	return nil
+}
+
+// writeError answers with the problem for err, or a bare 500 for unknown errors.
+func writeError(w http.ResponseWriter, err error) {
+	if problem := problemFor(err); problem != nil {
+		httputil.WriteProblem(w, problem)
+		return
+	}
+	httputil.InternalError(w)
+}
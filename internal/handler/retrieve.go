@@ -3,27 +3,64 @@ package handler
 import (
 	"errors"
 	"net/http"
+	"strconv"
 
+	"github.com/domurdoc/shortener/internal/httputil"
 	"github.com/domurdoc/shortener/internal/model"
 )
 
+// HeaderLinkPassword carries the password of a protected link for API clients.
+const HeaderLinkPassword = "X-Link-Password"
+
+// maxPasswordFormSize bounds the body of the password form.
+const maxPasswordFormSize = 4 << 10
+
+// Retrieve redirects to the original URL. Protected links take the password
+// from X-Link-Password, or from the password form posted back to the link,
+// browsers get the form instead of a problem.
 func (h *Handler) Retrieve(w http.ResponseWriter, r *http.Request) {
 	shortCode := r.PathValue("shortCode")
-	longURL, err := h.service.GetByShortCode(r.Context(), shortCode)
-	var notFoundErr *model.ShortCodeNotFoundError
-	if errors.As(err, &notFoundErr) {
-		http.Error(w, err.Error(), http.StatusNotFound)
+	password := r.Header.Get(HeaderLinkPassword)
+	if r.Method == http.MethodPost {
+		r.Body = http.MaxBytesReader(w, r.Body, maxPasswordFormSize)
+		password = r.PostFormValue("password")
+	}
+	redirect, err := h.service.GetByShortCode(r.Context(), shortCode, password, httputil.RemoteHost(r.RemoteAddr))
+	var blockedErr *model.DestinationBlockedError
+	if errors.As(err, &blockedErr) {
+		writeWarningPage(w, blockedErr)
 		return
 	}
-	var isDeletedErr *model.ShortCodeDeletedError
-	if errors.As(err, &isDeletedErr) {
-		http.Error(w, "", http.StatusGone)
+	var attemptsErr *model.TooManyAttemptsError
+	if errors.As(err, &attemptsErr) {
+		w.Header().Set("Retry-After", strconv.Itoa(retryAfterSeconds(attemptsErr.RetryAfter)))
+	}
+	var passwordErr *model.LinkPasswordError
+	if (errors.As(err, &passwordErr) || attemptsErr != nil) && httputil.Accepts(r.Header, "text/html") {
+		writePasswordPage(w, err)
 		return
 	}
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		writeError(w, err)
 		return
 	}
-	w.Header().Set("Location", longURL)
-	w.WriteHeader(http.StatusTemporaryRedirect)
+	w.Header().Set("Location", redirect.URL)
+	// 307 and 308 would repeat the form POST at the destination, the answer
+	// to a password is not cached either.
+	if r.Method == http.MethodPost {
+		w.Header().Set("Cache-Control", "private, no-store")
+		w.WriteHeader(http.StatusSeeOther)
+		return
+	}
+	w.Header().Set("Cache-Control", redirectCacheControl(redirect))
+	w.WriteHeader(redirect.Status)
+}
+
+// redirectCacheControl lets shared caches keep cacheable redirects, the
+// others are refetched so every click reaches the server.
+func redirectCacheControl(redirect *model.Redirect) string {
+	if redirect.MaxAge <= 0 {
+		return "private, no-store"
+	}
+	return "public, max-age=" + strconv.Itoa(int(redirect.MaxAge.Seconds()))
 }
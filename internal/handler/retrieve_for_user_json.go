@@ -1,25 +1,66 @@
 package handler
 
 import (
+	"fmt"
 	"net/http"
+	"strconv"
 
 	"github.com/domurdoc/shortener/internal/auth"
+	"github.com/domurdoc/shortener/internal/httputil"
 	"github.com/domurdoc/shortener/internal/model"
 )
 
+const (
+	ownerPersonal = "personal"
+	ownerTeam     = "team"
+	ownerAll      = "all"
+)
+
 type jsonURLRecord struct {
 	ShortURL    model.ShortURL    `json:"short_url"`
 	OriginalURL model.OriginalURL `json:"original_url"`
+	TeamID      model.TeamID      `json:"team_id,omitempty"`
 }
 
 func (h *Handler) RetrieveForUser(w http.ResponseWriter, r *http.Request) {
+	var urlRecords []model.URLRecord
+	var total int
+	var err error
+
 	user := auth.GetUser(r)
 
-	urlRecords, err := h.service.GetForUser(r.Context(), user)
+	limit, err := queryInt(r, "limit", 0)
+	if err != nil || limit < 0 {
+		httputil.Error(w, "limit must be a non-negative integer", http.StatusBadRequest)
+		return
+	}
+	offset, err := queryInt(r, "offset", 0)
+	if err != nil || offset < 0 {
+		httputil.Error(w, "offset must be a non-negative integer", http.StatusBadRequest)
+		return
+	}
+
+	switch owner := r.URL.Query().Get("owner"); owner {
+	case "", ownerPersonal:
+		urlRecords, total, err = h.service.GetPageForUser(r.Context(), user, limit, offset)
+	case ownerTeam:
+		teamID, parseErr := strconv.Atoi(r.URL.Query().Get("team_id"))
+		if parseErr != nil {
+			httputil.Error(w, "team_id must be set for team links", http.StatusBadRequest)
+			return
+		}
+		urlRecords, total, err = h.teams.GetURLs(r.Context(), user, model.TeamID(teamID), limit, offset)
+	case ownerAll:
+		urlRecords, total, err = h.teams.GetAllURLs(r.Context(), user, limit, offset)
+	default:
+		httputil.Error(w, fmt.Sprintf("owner must be one of: %s, %s, %s", ownerPersonal, ownerTeam, ownerAll), http.StatusBadRequest)
+		return
+	}
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		writeError(w, err)
 		return
 	}
+	w.Header().Set("X-Total-Count", strconv.Itoa(total))
 	jsonURLRecords := make([]jsonURLRecord, 0, len(urlRecords))
 	for _, ur := range urlRecords {
 		jsonURLRecords = append(jsonURLRecords, jsonURLRecord(ur))
@@ -30,5 +71,5 @@ func (h *Handler) RetrieveForUser(w http.ResponseWriter, r *http.Request) {
 		status = http.StatusNoContent
 	}
 
-	h.writeJSONResponse(w, jsonURLRecords, status)
+	writeJSONResponse(w, jsonURLRecords, status)
 }
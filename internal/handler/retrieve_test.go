@@ -2,8 +2,16 @@ package handler
 
 import (
 	"context"
+	"encoding/json"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
 	"testing"
 	"time"
 
@@ -14,7 +22,9 @@ import (
 
 	"github.com/domurdoc/shortener/internal/auth/strategy"
 	"github.com/domurdoc/shortener/internal/auth/transport"
+	"github.com/domurdoc/shortener/internal/httputil"
 	"github.com/domurdoc/shortener/internal/model"
+	"github.com/domurdoc/shortener/internal/policy"
 	"github.com/domurdoc/shortener/internal/repository/mem"
 	"github.com/domurdoc/shortener/internal/service"
 )
@@ -55,19 +65,12 @@ func TestShortener_Retrieve(t *testing.T) {
 				debugStrategy,
 				bearerTransport,
 				userRepo,
+				nil,
 			)
 
 			repo := mem.NewMemRecordRepo()
-			service := service.New(
-				"",
-				1,
-				1,
-				time.Second,
-				repo,
-				nil,
-				nil,
-			)
-			handler := New(service)
+			service := newTestService("", repo, service.Options{})
+			handler := New(service, nil, nil)
 
 			if tt.want.statusCode == http.StatusTemporaryRedirect {
 				user, _ := a.Register(context.TODO())
@@ -96,3 +99,331 @@ func TestShortener_Retrieve(t *testing.T) {
 		})
 	}
 }
+
+func TestShortener_RetrieveBlocklisted(t *testing.T) {
+	listPath := filepath.Join(t.TempDir(), "blocklist.txt")
+	require.NoError(t, os.WriteFile(listPath, []byte("# none yet\n"), 0o644))
+	threats, err := policy.LoadBlocklist(listPath, 10*time.Millisecond, nil)
+	require.NoError(t, err)
+	defer threats.Close()
+	destinations, err := policy.New(nil, nil, nil, "http://localhost:8081", nil, threats)
+	require.NoError(t, err)
+	repo := mem.NewMemRecordRepo()
+	service := newTestService("http://localhost:8081", repo, service.Options{Destinations: destinations})
+	handler := New(service, nil, nil)
+	user := &model.User{ID: 1}
+
+	shortURL, err := service.Shorten(context.TODO(), user, "https://turned-bad.example/", model.LinkOptions{})
+	require.NoError(t, err)
+
+	// the domain is reported after the link was created
+	require.NoError(t, os.WriteFile(listPath, []byte("turned-bad.example\n"), 0o644))
+	require.NoError(t, os.Chtimes(listPath, time.Now(), time.Now().Add(time.Minute)))
+	require.Eventually(t, func() bool {
+		u, _ := url.Parse("https://turned-bad.example/")
+		_, ok := threats.Match(u)
+		return ok
+	}, time.Second, 10*time.Millisecond)
+
+	r := httptest.NewRequest(http.MethodGet, "/{shortCode}", nil)
+	r.SetPathValue("shortCode", path.Base(shortURL))
+	w := httptest.NewRecorder()
+	handler.Retrieve(w, r)
+
+	resp := w.Result()
+	defer resp.Body.Close()
+	assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+	assert.Empty(t, resp.Header.Get("Location"))
+	assert.Equal(t, httputil.ContentTypeHTML, resp.Header.Get("Content-Type"))
+	assert.Contains(t, w.Body.String(), "<code>https://turned-bad.example/</code>")
+
+	_, err = service.Shorten(context.TODO(), user, "https://www.turned-bad.example/", model.LinkOptions{})
+	var blockedErr *model.DestinationBlockedError
+	require.ErrorAs(t, err, &blockedErr)
+	assert.Equal(t, policy.RuleMalware, blockedErr.Rule)
+}
+
+func TestShortener_RetrieveProtected(t *testing.T) {
+	repo := mem.NewMemRecordRepo()
+	service := newTestService("http://localhost:8081", repo, service.Options{
+		PasswordAttempts: service.NewAttemptLimiter(2, 3, time.Minute),
+	})
+	handler := New(service, nil, nil)
+	owner := &model.User{ID: 1}
+	require.NoError(t, repo.Store(context.TODO(), &model.BaseRecord{ShortCode: "hr", OriginalURL: "https://docs.example/salaries"}, owner.ID))
+
+	setPassword := func(user *model.User, password string) int {
+		r := httptest.NewRequest(http.MethodPut, "/api/user/urls/{shortCode}/password", strings.NewReader(`{"password":"`+password+`"}`))
+		r.Header.Set("Content-Type", httputil.ContentTypeJSON)
+		r.SetPathValue("shortCode", "hr")
+		w := httptest.NewRecorder()
+		handler.SetLinkPassword(w, auth.AttachUser(r, user))
+		return w.Code
+	}
+	assert.Equal(t, http.StatusNotFound, setPassword(&model.User{ID: 2}, "s3cret"))
+	assert.Equal(t, http.StatusBadRequest, setPassword(owner, ""))
+	assert.Equal(t, http.StatusNoContent, setPassword(owner, "s3cret"))
+
+	remoteAddr := "192.0.2.1:1234"
+	retrieve := func(method, header string, body url.Values, accept string) *httptest.ResponseRecorder {
+		var r *http.Request
+		if body != nil {
+			r = httptest.NewRequest(method, "/{shortCode}", strings.NewReader(body.Encode()))
+			r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		} else {
+			r = httptest.NewRequest(method, "/{shortCode}", nil)
+		}
+		if header != "" {
+			r.Header.Set(HeaderLinkPassword, header)
+		}
+		r.Header.Set("Accept", accept)
+		r.RemoteAddr = remoteAddr
+		r.SetPathValue("shortCode", "hr")
+		w := httptest.NewRecorder()
+		handler.Retrieve(w, r)
+		return w
+	}
+
+	w := retrieve(http.MethodGet, "", nil, "text/html,*/*")
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	assert.Contains(t, w.Body.String(), `<form method="post">`)
+	assert.Empty(t, w.Header().Get("Location"))
+
+	w = retrieve(http.MethodGet, "", nil, "application/json")
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	assert.Contains(t, w.Body.String(), httputil.ProblemTypePrefix+"password-required")
+
+	w = retrieve(http.MethodGet, "s3cret", nil, "")
+	assert.Equal(t, http.StatusTemporaryRedirect, w.Code)
+	assert.Equal(t, "https://docs.example/salaries", w.Header().Get("Location"))
+
+	w = retrieve(http.MethodPost, "", url.Values{"password": {"s3cret"}}, "text/html")
+	assert.Equal(t, http.StatusSeeOther, w.Code)
+	assert.Equal(t, "https://docs.example/salaries", w.Header().Get("Location"))
+
+	w = retrieve(http.MethodGet, "guess", nil, "")
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	assert.Contains(t, w.Body.String(), httputil.ProblemTypePrefix+"wrong-password")
+	w = retrieve(http.MethodPost, "", url.Values{"password": {"guess"}}, "text/html")
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	assert.Contains(t, w.Body.String(), "Wrong password.")
+
+	// the limit holds even for the right password
+	w = retrieve(http.MethodGet, "s3cret", nil, "")
+	assert.Equal(t, http.StatusTooManyRequests, w.Code)
+	retryAfter, err := strconv.Atoi(w.Header().Get("Retry-After"))
+	require.NoError(t, err)
+	assert.InDelta(t, 60, retryAfter, 10)
+
+	// other clients are not locked out until the link cap is reached
+	remoteAddr = "198.51.100.7:4321"
+	w = retrieve(http.MethodGet, "s3cret", nil, "")
+	assert.Equal(t, http.StatusTemporaryRedirect, w.Code)
+	w = retrieve(http.MethodGet, "guess", nil, "")
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	remoteAddr = "203.0.113.9:5555"
+	w = retrieve(http.MethodGet, "s3cret", nil, "")
+	assert.Equal(t, http.StatusTooManyRequests, w.Code)
+
+	r := httptest.NewRequest(http.MethodDelete, "/api/user/urls/{shortCode}/password", nil)
+	r.SetPathValue("shortCode", "hr")
+	handler.RemoveLinkPassword(httptest.NewRecorder(), auth.AttachUser(r, owner))
+	w = retrieve(http.MethodGet, "", nil, "")
+	assert.Equal(t, http.StatusTemporaryRedirect, w.Code)
+}
+
+func TestShortener_RetrieveMaxClicks(t *testing.T) {
+	service := newTestService("http://localhost:8081", mem.NewMemRecordRepo(), service.Options{})
+	handler := New(service, nil, nil)
+	user := &model.User{ID: 1}
+
+	r := httptest.NewRequest(http.MethodPost, "/api/shorten", strings.NewReader(`{"url":"https://invite.example/t0k3n","max_clicks":1}`))
+	r.Header.Set("Content-Type", httputil.ContentTypeJSON)
+	w := httptest.NewRecorder()
+	handler.ShortenJSON(w, auth.AttachUser(r, user))
+	require.Equal(t, http.StatusCreated, w.Code)
+	var res jsonResponse
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&res))
+
+	var wg sync.WaitGroup
+	codes := make(chan int, 10)
+	for range 10 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			r := httptest.NewRequest(http.MethodGet, "/{shortCode}", nil)
+			r.SetPathValue("shortCode", path.Base(res.Result))
+			w := httptest.NewRecorder()
+			handler.Retrieve(w, r)
+			codes <- w.Code
+		}()
+	}
+	wg.Wait()
+	close(codes)
+	counts := make(map[int]int)
+	for code := range codes {
+		counts[code]++
+	}
+	assert.Equal(t, map[int]int{http.StatusTemporaryRedirect: 1, http.StatusGone: 9}, counts)
+
+	r = httptest.NewRequest(http.MethodGet, "/{shortCode}", nil)
+	r.SetPathValue("shortCode", path.Base(res.Result))
+	w = httptest.NewRecorder()
+	handler.Retrieve(w, r)
+	assert.Equal(t, http.StatusGone, w.Code)
+	assert.Contains(t, w.Body.String(), httputil.ProblemTypePrefix+"click-limit-reached")
+
+	r = httptest.NewRequest(http.MethodPost, "/api/shorten", strings.NewReader(`{"url":"https://invite.example/other","max_clicks":-1}`))
+	r.Header.Set("Content-Type", httputil.ContentTypeJSON)
+	w = httptest.NewRecorder()
+	handler.ShortenJSON(w, auth.AttachUser(r, user))
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestShortener_RetrieveActiveWindow(t *testing.T) {
+	repo := mem.NewMemRecordRepo()
+	now := time.Now()
+	records := []model.BaseRecord{
+		{ShortCode: "soon", OriginalURL: "https://launch.example/", LinkOptions: model.LinkOptions{ActiveFrom: now.Add(time.Hour)}},
+		{ShortCode: "over", OriginalURL: "https://sale.example/", LinkOptions: model.LinkOptions{ActiveUntil: now.Add(-time.Hour)}},
+		{ShortCode: "live", OriginalURL: "https://live.example/", LinkOptions: model.LinkOptions{
+			ActiveFrom:  now.Add(-time.Hour),
+			ActiveUntil: now.Add(time.Hour),
+		}},
+	}
+	require.NoError(t, repo.StoreBatch(context.Background(), records, 1))
+
+	tests := []struct {
+		name      string
+		fallback  string
+		shortCode string
+		code      int
+		location  string
+		problem   string
+	}{
+		{name: "pending", shortCode: "soon", code: http.StatusNotFound, problem: "link-not-yet-active"},
+		{
+			name:      "pending with fallback",
+			fallback:  "https://example.com/coming-soon",
+			shortCode: "soon",
+			code:      http.StatusTemporaryRedirect,
+			location:  "https://example.com/coming-soon",
+		},
+		{name: "expired", fallback: "https://example.com/coming-soon", shortCode: "over", code: http.StatusGone, problem: "link-expired"},
+		{name: "active", shortCode: "live", code: http.StatusTemporaryRedirect, location: "https://live.example/"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service := newTestService("http://localhost:8081", repo, service.Options{PendingFallbackURL: tt.fallback})
+			handler := New(service, nil, nil)
+			r := httptest.NewRequest(http.MethodGet, "/{shortCode}", nil)
+			r.SetPathValue("shortCode", tt.shortCode)
+			w := httptest.NewRecorder()
+			handler.Retrieve(w, r)
+			assert.Equal(t, tt.code, w.Code)
+			if tt.location != "" {
+				assert.Equal(t, tt.location, w.Header().Get("Location"))
+			}
+			if tt.problem != "" {
+				assert.Contains(t, w.Body.String(), httputil.ProblemTypePrefix+tt.problem)
+			}
+		})
+	}
+
+	handler := New(newTestService("http://localhost:8081", repo, service.Options{}), nil, nil)
+	body := `{"url":"https://sale.example/next","active_until":"` + now.Add(-time.Minute).Format(time.RFC3339) + `"}`
+	r := httptest.NewRequest(http.MethodPost, "/api/shorten", strings.NewReader(body))
+	r.Header.Set("Content-Type", httputil.ContentTypeJSON)
+	w := httptest.NewRecorder()
+	handler.ShortenJSON(w, auth.AttachUser(r, &model.User{ID: 1}))
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestShortener_RetrieveRedirectStatus(t *testing.T) {
+	tests := []struct {
+		name         string
+		defaultCode  int
+		body         string
+		code         int
+		cacheControl string
+	}{
+		{name: "server default", body: `{"url":"https://a.example/"}`, code: http.StatusTemporaryRedirect, cacheControl: "private, no-store"},
+		{
+			name:         "permanent server default",
+			defaultCode:  http.StatusPermanentRedirect,
+			body:         `{"url":"https://a.example/"}`,
+			code:         http.StatusPermanentRedirect,
+			cacheControl: "public, max-age=3600",
+		},
+		{name: "moved permanently", body: `{"url":"https://a.example/","redirect_status":301}`, code: http.StatusMovedPermanently, cacheControl: "public, max-age=3600"},
+		{name: "found", body: `{"url":"https://a.example/","redirect_status":302}`, code: http.StatusFound, cacheControl: "private, no-store"},
+		{
+			name:         "permanent with click limit",
+			body:         `{"url":"https://a.example/","redirect_status":301,"max_clicks":5}`,
+			code:         http.StatusMovedPermanently,
+			cacheControl: "private, no-store",
+		},
+		{name: "unsupported status", body: `{"url":"https://a.example/","redirect_status":303}`, code: http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service := newTestService("http://localhost:8081", mem.NewMemRecordRepo(), service.Options{
+				RedirectStatus: tt.defaultCode,
+				RedirectMaxAge: time.Hour,
+			})
+			handler := New(service, nil, nil)
+			r := httptest.NewRequest(http.MethodPost, "/api/shorten", strings.NewReader(tt.body))
+			r.Header.Set("Content-Type", httputil.ContentTypeJSON)
+			w := httptest.NewRecorder()
+			handler.ShortenJSON(w, auth.AttachUser(r, &model.User{ID: 1}))
+			if tt.code == http.StatusBadRequest {
+				assert.Equal(t, http.StatusBadRequest, w.Code)
+				return
+			}
+			require.Equal(t, http.StatusCreated, w.Code)
+			var res jsonResponse
+			require.NoError(t, json.NewDecoder(w.Body).Decode(&res))
+
+			r = httptest.NewRequest(http.MethodGet, "/{shortCode}", nil)
+			r.SetPathValue("shortCode", path.Base(res.Result))
+			w = httptest.NewRecorder()
+			handler.Retrieve(w, r)
+			assert.Equal(t, tt.code, w.Code)
+			assert.Equal(t, "https://a.example/", w.Header().Get("Location"))
+			assert.Equal(t, tt.cacheControl, w.Header().Get("Cache-Control"))
+		})
+	}
+}
+
+func TestShortener_RetrieveProtectedConcurrentGuesses(t *testing.T) {
+	repo := mem.NewMemRecordRepo()
+	service := newTestService("http://localhost:8081", repo, service.Options{
+		PasswordAttempts: service.NewAttemptLimiter(2, 100, time.Minute),
+	})
+	handler := New(service, nil, nil)
+	owner := &model.User{ID: 1}
+	require.NoError(t, repo.Store(context.TODO(), &model.BaseRecord{ShortCode: "hr", OriginalURL: "https://docs.example/"}, owner.ID))
+	require.NoError(t, service.SetPassword(context.TODO(), owner, "hr", "s3cret"))
+
+	var wg sync.WaitGroup
+	codes := make(chan int, 10)
+	for range 10 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			r := httptest.NewRequest(http.MethodGet, "/{shortCode}", nil)
+			r.Header.Set(HeaderLinkPassword, "guess")
+			r.SetPathValue("shortCode", "hr")
+			w := httptest.NewRecorder()
+			handler.Retrieve(w, r)
+			codes <- w.Code
+		}()
+	}
+	wg.Wait()
+	close(codes)
+	counts := make(map[int]int)
+	for code := range codes {
+		counts[code]++
+	}
+	assert.Equal(t, map[int]int{http.StatusUnauthorized: 2, http.StatusTooManyRequests: 8}, counts)
+}
@@ -14,6 +14,7 @@ import (
 type jsonBatchRequestItem struct {
 	CorrelationID string `json:"correlation_id"`
 	OriginalURL   string `json:"original_url"`
+	jsonLinkOptions
 }
 
 type jsonBatchResponseItem struct {
@@ -21,46 +22,86 @@ type jsonBatchResponseItem struct {
 	ShortURL      string `json:"short_url"`
 }
 
+type jsonBatchPartialResponseItem struct {
+	CorrelationID string                `json:"correlation_id"`
+	ShortURL      model.ShortURL        `json:"short_url,omitempty"`
+	Status        model.BatchItemStatus `json:"status"`
+	Error         string                `json:"error,omitempty"`
+}
+
 func (h *Handler) ShortenBatchJSON(w http.ResponseWriter, r *http.Request) {
 	var reqItems []jsonBatchRequestItem
 
 	user := auth.GetUser(r)
 
 	if !httputil.HasContentType(r.Header, httputil.ContentTypeJSON) {
-		http.Error(w, fmt.Sprintf("wanted Content-Type: %s", httputil.ContentTypeJSON), http.StatusBadRequest)
+		httputil.Error(w, fmt.Sprintf("wanted Content-Type: %s", httputil.ContentTypeJSON), http.StatusBadRequest)
 		return
 	}
 	dec := json.NewDecoder(r.Body)
 	if err := dec.Decode(&reqItems); err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		httputil.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
 	if len(reqItems) == 0 {
-		http.Error(w, "at least one item must be passed", http.StatusBadRequest)
+		httputil.Error(w, "at least one item must be passed", http.StatusBadRequest)
 		return
 	}
 	originalURLS := make([]string, len(reqItems))
+	opts := make([]model.LinkOptions, len(reqItems))
 	for i, jsonRequest := range reqItems {
 		originalURLS[i] = jsonRequest.OriginalURL
+		var err error
+		if opts[i], err = jsonRequest.linkOptions(); err != nil {
+			httputil.Error(w, fmt.Sprintf("correlation_id %s: %s", jsonRequest.CorrelationID, err), http.StatusBadRequest)
+			return
+		}
 	}
-	shortURLS, err := h.service.ShortenBatch(r.Context(), user, originalURLS)
-	var invalidURLErr *model.InvalidURLError
-	if errors.As(err, &invalidURLErr) {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+	if r.URL.Query().Get("partial") == "true" {
+		h.shortenBatchPartial(w, r, reqItems, originalURLS, opts)
 		return
 	}
+	shortURLS, err := h.service.ShortenBatch(r.Context(), user, originalURLS, opts)
 	var urlExistsErr model.BatchOriginalURLExistsError
 	if err != nil && !errors.As(err, &urlExistsErr) {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		writeError(w, err)
 		return
 	}
 	resItems := make([]jsonBatchResponseItem, len(reqItems))
 	for i, jsonRequest := range reqItems {
 		resItems[i] = jsonBatchResponseItem{CorrelationID: jsonRequest.CorrelationID, ShortURL: shortURLS[i]}
 	}
-	status := http.StatusCreated
 	if err != nil {
-		status = http.StatusConflict
+		httputil.WriteProblem(w, problemFor(err).With("items", resItems))
+		return
+	}
+	writeJSONResponse(w, resItems, http.StatusCreated)
+}
+
+// shortenBatchPartial stores the valid items of a batch and answers
+// 207 Multi-Status with a status per item.
+func (h *Handler) shortenBatchPartial(
+	w http.ResponseWriter,
+	r *http.Request,
+	reqItems []jsonBatchRequestItem,
+	originalURLS []string,
+	opts []model.LinkOptions,
+) {
+	items, err := h.service.ShortenBatchPartial(r.Context(), auth.GetUser(r), originalURLS, opts)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	resItems := make([]jsonBatchPartialResponseItem, len(reqItems))
+	for i, jsonRequest := range reqItems {
+		resItems[i] = jsonBatchPartialResponseItem{
+			CorrelationID: jsonRequest.CorrelationID,
+			ShortURL:      items[i].ShortURL,
+			Status:        items[i].Status,
+		}
+		if items[i].Error != nil {
+			resItems[i].Error = items[i].Error.Error()
+		}
 	}
-	h.writeJSONResponse(w, resItems, status)
+	writeJSONResponse(w, resItems, http.StatusMultiStatus)
 }
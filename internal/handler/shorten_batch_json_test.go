@@ -0,0 +1,65 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/domurdoc/shortener/internal/auth"
+	"github.com/domurdoc/shortener/internal/httputil"
+	"github.com/domurdoc/shortener/internal/model"
+	"github.com/domurdoc/shortener/internal/repository/mem"
+	"github.com/domurdoc/shortener/internal/service"
+)
+
+func TestShortener_ShortenBatchJSONPartial(t *testing.T) {
+	userRepo := mem.NewMemUserRepo()
+	user, err := userRepo.CreateUser(context.TODO())
+	require.NoError(t, err)
+	service := newTestService("http://localhost:8081", mem.NewMemRecordRepo(), service.Options{})
+	handler := New(service, nil, nil)
+
+	existingURL, err := service.Shorten(context.TODO(), user, "http://a.com", model.LinkOptions{})
+	require.NoError(t, err)
+
+	body := `[
+		{"correlation_id": "1", "original_url": "http://a.com"},
+		{"correlation_id": "2", "original_url": "not a url"},
+		{"correlation_id": "3", "original_url": "http://b.com"},
+		{"correlation_id": "4", "original_url": "http://a.com", "max_clicks": 1}
+	]`
+	r := httptest.NewRequest(http.MethodPost, "/api/shorten/batch?partial=true", strings.NewReader(body))
+	r.Header.Set(httputil.HeaderContentType, httputil.ContentTypeJSON)
+	w := httptest.NewRecorder()
+	handler.ShortenBatchJSON(w, auth.AttachUser(r, user))
+
+	resp := w.Result()
+	defer resp.Body.Close()
+	require.Equal(t, http.StatusMultiStatus, resp.StatusCode)
+
+	var items []jsonBatchPartialResponseItem
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&items))
+	require.Len(t, items, 4)
+	assert.Equal(t, jsonBatchPartialResponseItem{
+		CorrelationID: "1",
+		ShortURL:      model.ShortURL(existingURL),
+		Status:        model.BatchItemExists,
+	}, items[0])
+	assert.Equal(t, model.BatchItemInvalid, items[1].Status)
+	assert.NotEmpty(t, items[1].Error)
+	assert.Empty(t, items[1].ShortURL)
+	assert.Equal(t, model.BatchItemCreated, items[2].Status)
+	assert.Equal(t, model.BatchItemExists, items[3].Status)
+	assert.Equal(t, model.ShortURL(existingURL), items[3].ShortURL)
+	assert.Contains(t, items[3].Error, "link options were not applied")
+
+	redirect, err := service.GetByShortCode(context.TODO(), string(items[2].ShortURL)[len("http://localhost:8081/"):], "", "")
+	require.NoError(t, err)
+	assert.Equal(t, "http://b.com", redirect.URL)
+}
@@ -0,0 +1,245 @@
+package handler
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+
+	"github.com/domurdoc/shortener/internal/auth"
+	"github.com/domurdoc/shortener/internal/httputil"
+	"github.com/domurdoc/shortener/internal/model"
+	"github.com/domurdoc/shortener/internal/service"
+)
+
+const importChunkSize = 1000
+
+// importStatusFailed marks rows of a chunk the repository failed to store.
+const importStatusFailed model.BatchItemStatus = "failed"
+
+type importRow struct {
+	CorrelationID string `json:"correlation_id"`
+	OriginalURL   string `json:"original_url"`
+}
+
+type importResult struct {
+	CorrelationID string                `json:"correlation_id"`
+	OriginalURL   string                `json:"original_url"`
+	ShortURL      model.ShortURL        `json:"short_url,omitempty"`
+	Status        model.BatchItemStatus `json:"status"`
+	Error         string                `json:"error,omitempty"`
+}
+
+var importCSVHeader = []string{"correlation_id", "original_url", "short_url", "status", "error"}
+
+// rowReader returns io.EOF after the last row.
+type rowReader func() (*importRow, error)
+
+type resultWriter interface {
+	Write(importResult) error
+	Flush() error
+}
+
+// ShortenImport streams CSV or NDJSON rows in, stores them in chunks and
+// streams a result per row back in the same format. Rows never fail the whole
+// request: once the response has started, a storage error is reported in the
+// rows of the failed chunk and the import stops.
+func (h *Handler) ShortenImport(w http.ResponseWriter, r *http.Request) {
+	user := auth.GetUser(r)
+
+	var readRow rowReader
+	var results resultWriter
+	switch {
+	case httputil.HasContentType(r.Header, httputil.ContentTypeCSV):
+		readRow = newCSVRowReader(r.Body)
+		results = newCSVResultWriter(w)
+		httputil.SetContentType(w.Header(), httputil.ContentTypeCSV)
+	case httputil.HasContentType(r.Header, httputil.ContentTypeNDJSON):
+		readRow = newNDJSONRowReader(r.Body)
+		results = newNDJSONResultWriter(w)
+		httputil.SetContentType(w.Header(), httputil.ContentTypeNDJSON)
+	default:
+		httputil.Error(
+			w,
+			fmt.Sprintf("wanted Content-Type: %s or %s", httputil.ContentTypeCSV, httputil.ContentTypeNDJSON),
+			http.StatusBadRequest,
+		)
+		return
+	}
+	// HTTP/1 stops reading the body once the response starts, HTTP/2 always
+	// allows both directions and reports http.ErrNotSupported here.
+	_ = http.NewResponseController(w).EnableFullDuplex()
+	w.WriteHeader(http.StatusOK)
+
+	chunk := make([]importRow, 0, importChunkSize)
+	for {
+		row, err := readRow()
+		if err != nil && !errors.Is(err, io.EOF) {
+			results.Write(importResult{Status: model.BatchItemInvalid, Error: err.Error()})
+			break
+		}
+		if row != nil {
+			chunk = append(chunk, *row)
+		}
+		if len(chunk) == importChunkSize || errors.Is(err, io.EOF) && len(chunk) > 0 {
+			if !h.importChunk(r, user, chunk, results) {
+				break
+			}
+			results.Flush()
+			http.NewResponseController(w).Flush()
+			chunk = chunk[:0]
+		}
+		if errors.Is(err, io.EOF) {
+			break
+		}
+	}
+	results.Flush()
+}
+
+func (h *Handler) importChunk(r *http.Request, user *model.User, chunk []importRow, results resultWriter) bool {
+	originalURLS := make([]string, len(chunk))
+	for i, row := range chunk {
+		originalURLS[i] = row.OriginalURL
+	}
+	items, err := h.service.ShortenBatchPartial(r.Context(), user, originalURLS, nil)
+	for i, row := range chunk {
+		result := importResult{CorrelationID: row.CorrelationID, OriginalURL: row.OriginalURL}
+		if err != nil {
+			result.Status = importStatusFailed
+			result.Error = err.Error()
+		} else {
+			result.ShortURL = items[i].ShortURL
+			result.Status = items[i].Status
+			if items[i].Error != nil {
+				result.Error = items[i].Error.Error()
+			}
+		}
+		results.Write(result)
+	}
+	return err == nil
+}
+
+// newCSVRowReader takes an optional header naming original_url and
+// correlation_id columns. Without it the last column is the URL and the first
+// one, if there are two, the correlation ID. Row numbers are used as
+// correlation IDs when none is given.
+func newCSVRowReader(body io.Reader) rowReader {
+	reader := csv.NewReader(body)
+	reader.FieldsPerRecord = -1
+	reader.ReuseRecord = true
+	urlColumn, idColumn := -1, -1
+	line := 0
+	return func() (*importRow, error) {
+		for {
+			record, err := reader.Read()
+			if err != nil {
+				return nil, err
+			}
+			line++
+			if line == 1 {
+				for i, name := range record {
+					switch name {
+					case "original_url":
+						urlColumn = i
+					case "correlation_id":
+						idColumn = i
+					}
+				}
+				if urlColumn >= 0 {
+					continue
+				}
+			}
+			row := &importRow{CorrelationID: strconv.Itoa(line)}
+			switch {
+			case urlColumn >= 0 && urlColumn < len(record):
+				row.OriginalURL = record[urlColumn]
+				if idColumn >= 0 && idColumn < len(record) && record[idColumn] != "" {
+					row.CorrelationID = record[idColumn]
+				}
+			case urlColumn < 0 && len(record) == 2:
+				row.CorrelationID, row.OriginalURL = record[0], record[1]
+			case urlColumn < 0 && len(record) > 0:
+				row.OriginalURL = record[len(record)-1]
+			}
+			return row, nil
+		}
+	}
+}
+
+func newNDJSONRowReader(body io.Reader) rowReader {
+	scanner := bufio.NewScanner(body)
+	scanner.Buffer(make([]byte, 0, 4096), 4*service.URLMaxLength)
+	line := 0
+	return func() (*importRow, error) {
+		for scanner.Scan() {
+			line++
+			if len(scanner.Bytes()) == 0 {
+				continue
+			}
+			row := &importRow{}
+			if err := json.Unmarshal(scanner.Bytes(), row); err != nil {
+				return &importRow{CorrelationID: strconv.Itoa(line)}, nil
+			}
+			if row.CorrelationID == "" {
+				row.CorrelationID = strconv.Itoa(line)
+			}
+			return row, nil
+		}
+		if err := scanner.Err(); err != nil {
+			return nil, err
+		}
+		return nil, io.EOF
+	}
+}
+
+type csvResultWriter struct {
+	w             *csv.Writer
+	headerWritten bool
+}
+
+func newCSVResultWriter(w io.Writer) *csvResultWriter {
+	return &csvResultWriter{w: csv.NewWriter(w)}
+}
+
+func (c *csvResultWriter) Write(result importResult) error {
+	if !c.headerWritten {
+		c.headerWritten = true
+		if err := c.w.Write(importCSVHeader); err != nil {
+			return err
+		}
+	}
+	return c.w.Write([]string{
+		result.CorrelationID,
+		result.OriginalURL,
+		string(result.ShortURL),
+		string(result.Status),
+		result.Error,
+	})
+}
+
+func (c *csvResultWriter) Flush() error {
+	c.w.Flush()
+	return c.w.Error()
+}
+
+type ndjsonResultWriter struct {
+	w   *bufio.Writer
+	enc *json.Encoder
+}
+
+func newNDJSONResultWriter(w io.Writer) *ndjsonResultWriter {
+	bw := bufio.NewWriter(w)
+	return &ndjsonResultWriter{w: bw, enc: json.NewEncoder(bw)}
+}
+
+func (n *ndjsonResultWriter) Write(result importResult) error {
+	return n.enc.Encode(result)
+}
+
+func (n *ndjsonResultWriter) Flush() error {
+	return n.w.Flush()
+}
@@ -0,0 +1,139 @@
+package handler
+
+import (
+	"bufio"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/domurdoc/shortener/internal/auth"
+	"github.com/domurdoc/shortener/internal/compressor"
+	"github.com/domurdoc/shortener/internal/httputil"
+	"github.com/domurdoc/shortener/internal/logger"
+	"github.com/domurdoc/shortener/internal/model"
+	"github.com/domurdoc/shortener/internal/repository/mem"
+	"github.com/domurdoc/shortener/internal/service"
+)
+
+func TestShortener_ShortenImport(t *testing.T) {
+	userRepo := mem.NewMemUserRepo()
+	user, err := userRepo.CreateUser(context.TODO())
+	require.NoError(t, err)
+	service := newTestService("http://localhost:8081", mem.NewMemRecordRepo(), service.Options{})
+	handler := New(service, nil, nil)
+	server := httptest.NewServer(compressor.GZIPMiddleware(logger.NewRequestLogger(zap.NewNop().Sugar())(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			handler.ShortenImport(w, auth.AttachUser(r, user))
+		}),
+	)))
+	defer server.Close()
+
+	post := func(t *testing.T, ctx context.Context, contentType string, body io.Reader) *http.Response {
+		r, err := http.NewRequestWithContext(ctx, http.MethodPost, server.URL+"/api/shorten/import", body)
+		require.NoError(t, err)
+		r.Header.Set(httputil.HeaderContentType, contentType)
+		resp, err := server.Client().Do(r)
+		require.NoError(t, err)
+		return resp
+	}
+	doImport := func(t *testing.T, contentType, body string) *http.Response {
+		return post(t, context.TODO(), contentType, strings.NewReader(body))
+	}
+
+	t.Run("Invalid Content-Type", func(t *testing.T) {
+		resp := doImport(t, httputil.ContentTypeJSON, "[]")
+		defer resp.Body.Close()
+		assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+	})
+
+	t.Run("CSV", func(t *testing.T) {
+		resp := doImport(t, httputil.ContentTypeCSV, "original_url,correlation_id\nhttp://a.com,a\nnot a url,b\nhttp://b.com,\n")
+		defer resp.Body.Close()
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Equal(t, httputil.ContentTypeCSV, resp.Header.Get(httputil.HeaderContentType))
+
+		records, err := csv.NewReader(resp.Body).ReadAll()
+		require.NoError(t, err)
+		require.Len(t, records, 4)
+		assert.Equal(t, importCSVHeader, records[0])
+		assert.Equal(t, "a", records[1][0])
+		assert.Equal(t, string(model.BatchItemCreated), records[1][3])
+		assert.NotEmpty(t, records[1][2])
+		assert.Equal(t, []string{"b", "not a url"}, records[2][:2])
+		assert.Equal(t, string(model.BatchItemInvalid), records[2][3])
+		assert.Equal(t, "4", records[3][0])
+	})
+
+	t.Run("NDJSON", func(t *testing.T) {
+		body := `{"correlation_id":"x","original_url":"http://a.com"}` + "\n" +
+			`{"original_url":"http://c.com"}` + "\n" +
+			"{broken\n"
+		resp := doImport(t, httputil.ContentTypeNDJSON, body)
+		defer resp.Body.Close()
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+
+		var results []importResult
+		scanner := bufio.NewScanner(resp.Body)
+		for scanner.Scan() {
+			var result importResult
+			require.NoError(t, json.Unmarshal(scanner.Bytes(), &result))
+			results = append(results, result)
+		}
+		require.Len(t, results, 3)
+		assert.Equal(t, "x", results[0].CorrelationID)
+		assert.Equal(t, model.BatchItemExists, results[0].Status)
+		assert.Equal(t, "2", results[1].CorrelationID)
+		assert.Equal(t, model.BatchItemCreated, results[1].Status)
+		assert.Equal(t, importResult{CorrelationID: "3", Status: model.BatchItemInvalid, Error: results[2].Error}, results[2])
+	})
+	t.Run("Streams results while reading", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		body, rows := io.Pipe()
+		// the client keeps waiting for the body otherwise
+		stop := context.AfterFunc(ctx, func() { rows.CloseWithError(ctx.Err()) })
+		defer stop()
+		writeRows := func(from, to int) {
+			for i := from; i < to; i++ {
+				if _, err := fmt.Fprintf(rows, `{"original_url":"http://stream.example/%d"}`+"\n", i); err != nil {
+					return
+				}
+			}
+		}
+		go writeRows(0, importChunkSize)
+		resp := post(t, ctx, httputil.ContentTypeNDJSON, body)
+		defer resp.Body.Close()
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+
+		// the first chunk arrives while the request body is still open
+		scanner := bufio.NewScanner(resp.Body)
+		readResults := func(n int) {
+			for range n {
+				require.True(t, scanner.Scan(), scanner.Err())
+				var result importResult
+				require.NoError(t, json.Unmarshal(scanner.Bytes(), &result))
+				assert.Equal(t, model.BatchItemCreated, result.Status)
+			}
+		}
+		readResults(importChunkSize)
+
+		go func() {
+			writeRows(importChunkSize, importChunkSize+500)
+			rows.Close()
+		}()
+		readResults(500)
+		assert.False(t, scanner.Scan())
+		assert.NoError(t, scanner.Err())
+	})
+}
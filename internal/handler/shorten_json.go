@@ -5,6 +5,7 @@ import (
 	"errors"
 	"fmt"
 	"net/http"
+	"time"
 
 	"github.com/domurdoc/shortener/internal/auth"
 	"github.com/domurdoc/shortener/internal/httputil"
@@ -12,7 +13,17 @@ import (
 )
 
 type jsonRequest struct {
-	URL string `json:"url"`
+	URL    string       `json:"url"`
+	TeamID model.TeamID `json:"team_id,omitempty"`
+	jsonLinkOptions
+}
+
+// jsonLinkOptions are the link options shorten requests accept.
+type jsonLinkOptions struct {
+	MaxClicks      int64      `json:"max_clicks,omitempty"`
+	ActiveFrom     *time.Time `json:"active_from,omitempty"`
+	ActiveUntil    *time.Time `json:"active_until,omitempty"`
+	RedirectStatus int        `json:"redirect_status,omitempty"`
 }
 
 type jsonResponse struct {
@@ -25,28 +36,57 @@ func (h *Handler) ShortenJSON(w http.ResponseWriter, r *http.Request) {
 	user := auth.GetUser(r)
 
 	if !httputil.HasContentType(r.Header, httputil.ContentTypeJSON) {
-		http.Error(w, fmt.Sprintf("wanted Content-Type: %s", httputil.ContentTypeJSON), http.StatusBadRequest)
+		httputil.Error(w, fmt.Sprintf("wanted Content-Type: %s", httputil.ContentTypeJSON), http.StatusBadRequest)
 		return
 	}
 	dec := json.NewDecoder(r.Body)
 	if err := dec.Decode(&req); err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		httputil.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
-	shortURL, err := h.service.Shorten(r.Context(), user, req.URL)
-	var invalidURLErr *model.InvalidURLError
-	if errors.As(err, &invalidURLErr) {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+	opts, err := req.linkOptions()
+	if err != nil {
+		httputil.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
+	var shortURL string
+	if req.TeamID != 0 {
+		shortURL, err = h.teams.Shorten(r.Context(), user, req.TeamID, req.URL, opts)
+	} else {
+		shortURL, err = h.service.Shorten(r.Context(), user, req.URL, opts)
+	}
+	// result keeps conflicts readable for clients of the pre-problem API.
 	var urlExistsErr *model.OriginalURLExistsError
-	if err != nil && !errors.As(err, &urlExistsErr) {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+	if errors.As(err, &urlExistsErr) {
+		httputil.WriteProblem(w, problemFor(err).With("short_url", shortURL).With("result", shortURL))
 		return
 	}
-	status := http.StatusCreated
 	if err != nil {
-		status = http.StatusConflict
+		writeError(w, err)
+		return
+	}
+	writeJSONResponse(w, jsonResponse{Result: shortURL}, http.StatusCreated)
+}
+
+func (o jsonLinkOptions) linkOptions() (model.LinkOptions, error) {
+	opts := model.LinkOptions{MaxClicks: o.MaxClicks, RedirectStatus: o.RedirectStatus}
+	if o.MaxClicks < 0 {
+		return opts, errors.New("max_clicks must not be negative")
+	}
+	if o.RedirectStatus != 0 && !model.IsRedirectStatus(o.RedirectStatus) {
+		return opts, fmt.Errorf("redirect_status must be one of %v", model.RedirectStatuses)
+	}
+	if o.ActiveFrom != nil {
+		opts.ActiveFrom = *o.ActiveFrom
+	}
+	if o.ActiveUntil != nil {
+		opts.ActiveUntil = *o.ActiveUntil
+		if !opts.ActiveUntil.After(time.Now()) {
+			return opts, errors.New("active_until must be in the future")
+		}
+		if !opts.ActiveUntil.After(opts.ActiveFrom) {
+			return opts, errors.New("active_until must be after active_from")
+		}
 	}
-	h.writeJSONResponse(w, jsonResponse{Result: shortURL}, status)
+	return opts, nil
 }
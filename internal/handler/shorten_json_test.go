@@ -3,12 +3,12 @@ package handler
 import (
 	"context"
 	"encoding/json"
+	"fmt"
 	"io"
 	"net/http"
 	"net/http/httptest"
 	"strings"
 	"testing"
-	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -16,7 +16,9 @@ import (
 	"github.com/domurdoc/shortener/internal/auth"
 	"github.com/domurdoc/shortener/internal/auth/strategy"
 	"github.com/domurdoc/shortener/internal/auth/transport"
+	"github.com/domurdoc/shortener/internal/denylist"
 	"github.com/domurdoc/shortener/internal/httputil"
+	"github.com/domurdoc/shortener/internal/model"
 	"github.com/domurdoc/shortener/internal/repository/mem"
 	"github.com/domurdoc/shortener/internal/service"
 )
@@ -100,17 +102,10 @@ func TestShortener_ShortenJSON(t *testing.T) {
 				debugStrategy,
 				bearerTransport,
 				userRepo,
-			)
-			service := service.New(
-				"http://localhost:8081",
-				1,
-				1,
-				time.Second,
-				mem.NewMemRecordRepo(),
-				nil,
 				nil,
 			)
-			handler := New(service)
+			service := newTestService("http://localhost:8081", mem.NewMemRecordRepo(), service.Options{})
+			handler := New(service, nil, nil)
 
 			r := httptest.NewRequest(http.MethodPost, "/api/shorten", strings.NewReader(tt.body))
 			r.Header.Set(httputil.HeaderContentType, tt.contentType)
@@ -125,6 +120,13 @@ func TestShortener_ShortenJSON(t *testing.T) {
 			assert.Equal(t, tt.want.statusCode, resp.StatusCode)
 
 			if resp.StatusCode == http.StatusBadRequest {
+				assert.Equal(t, httputil.ContentTypeProblemJSON, resp.Header.Get(httputil.HeaderContentType))
+				var problem map[string]any
+				require.NoError(t, json.NewDecoder(resp.Body).Decode(&problem))
+				require.NoError(t, resp.Body.Close())
+				assert.EqualValues(t, http.StatusBadRequest, problem["status"])
+				assert.NotEmpty(t, problem["type"])
+				assert.NotEmpty(t, problem["title"])
 				return
 			}
 			assert.Equal(t, httputil.ContentTypeJSON, resp.Header.Get(httputil.HeaderContentType))
@@ -142,3 +144,243 @@ func TestShortener_ShortenJSON(t *testing.T) {
 		})
 	}
 }
+
+func TestShortener_ShortenJSONConflictProblem(t *testing.T) {
+	userRepo := mem.NewMemUserRepo()
+	user, err := userRepo.CreateUser(context.TODO())
+	require.NoError(t, err)
+	shortener := newTestService("http://localhost:8081", mem.NewMemRecordRepo(), service.Options{})
+	handler := New(shortener, nil, nil)
+
+	shorten := func(body string) *http.Response {
+		r := httptest.NewRequest(http.MethodPost, "/api/shorten", strings.NewReader(body))
+		r.Header.Set(httputil.HeaderContentType, httputil.ContentTypeJSON)
+		w := httptest.NewRecorder()
+		handler.ShortenJSON(w, auth.AttachUser(r, user))
+		return w.Result()
+	}
+	resp := shorten(`{"url": "http://yandex.com"}`)
+	var created jsonResponse
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&created))
+	require.NoError(t, resp.Body.Close())
+
+	resp = shorten(`{"url": "http://yandex.com"}`)
+	assert.Equal(t, http.StatusConflict, resp.StatusCode)
+	assert.Equal(t, httputil.ContentTypeProblemJSON, resp.Header.Get(httputil.HeaderContentType))
+	var problem map[string]any
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&problem))
+	require.NoError(t, resp.Body.Close())
+	assert.Equal(t, httputil.ProblemTypePrefix+"url-exists", problem["type"])
+	assert.EqualValues(t, http.StatusConflict, problem["status"])
+	assert.Equal(t, created.Result, problem["short_url"])
+	assert.Equal(t, created.Result, problem["result"])
+	assert.NotContains(t, problem, "options_applied")
+
+	resp = shorten(`{"url": "http://yandex.com", "max_clicks": 1}`)
+	assert.Equal(t, http.StatusConflict, resp.StatusCode)
+	problem = nil
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&problem))
+	require.NoError(t, resp.Body.Close())
+	assert.Equal(t, created.Result, problem["short_url"])
+	assert.Equal(t, false, problem["options_applied"])
+}
+
+// collidingCodes returns the same code on the first attempt for every URL.
+type collidingCodes struct{}
+
+func (collidingCodes) Generate(_ context.Context, originalURL string, attempt int) (model.ShortCode, error) {
+	if attempt == 0 {
+		return "taken", nil
+	}
+	return model.ShortCode(fmt.Sprintf("c%d", len(originalURL))), nil
+}
+
+func TestShortener_ShortenJSONCodeCollision(t *testing.T) {
+	userRepo := mem.NewMemUserRepo()
+	user, err := userRepo.CreateUser(context.TODO())
+	require.NoError(t, err)
+	shortener := newTestService("http://localhost:8081", mem.NewMemRecordRepo(), service.Options{Codes: collidingCodes{}})
+	handler := New(shortener, nil, nil)
+
+	for _, originalURL := range []string{"http://a.com", "http://bb.com"} {
+		r := httptest.NewRequest(http.MethodPost, "/api/shorten", strings.NewReader(`{"url": "`+originalURL+`"}`))
+		r.Header.Set(httputil.HeaderContentType, httputil.ContentTypeJSON)
+		w := httptest.NewRecorder()
+		handler.ShortenJSON(w, auth.AttachUser(r, user))
+		resp := w.Result()
+		require.Equal(t, http.StatusCreated, resp.StatusCode)
+		require.NoError(t, resp.Body.Close())
+	}
+
+	r := httptest.NewRequest(
+		http.MethodPost,
+		"/api/shorten/batch",
+		strings.NewReader(`[{"correlation_id": "1", "original_url": "http://ccc.com/"}]`),
+	)
+	r.Header.Set(httputil.HeaderContentType, httputil.ContentTypeJSON)
+	w := httptest.NewRecorder()
+	handler.ShortenBatchJSON(w, auth.AttachUser(r, user))
+	resp := w.Result()
+	var items []jsonBatchResponseItem
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&items))
+	require.NoError(t, resp.Body.Close())
+	assert.Equal(t, http.StatusCreated, resp.StatusCode)
+	assert.Equal(t, "http://localhost:8081/c15", items[0].ShortURL)
+}
+
+func TestShortener_ShortenJSONCodeGrowth(t *testing.T) {
+	userRepo := mem.NewMemUserRepo()
+	user, err := userRepo.CreateUser(context.TODO())
+	require.NoError(t, err)
+	shortener := newTestService("http://localhost:8081", mem.NewMemRecordRepo(), service.Options{
+		Codes: service.NewRandomCodeGenerator("ab", 1, 0.001),
+	})
+	handler := New(shortener, nil, nil)
+
+	for i := range 50 {
+		body := fmt.Sprintf(`{"url": "http://%d.com"}`, i)
+		r := httptest.NewRequest(http.MethodPost, "/api/shorten", strings.NewReader(body))
+		r.Header.Set(httputil.HeaderContentType, httputil.ContentTypeJSON)
+		w := httptest.NewRecorder()
+		handler.ShortenJSON(w, auth.AttachUser(r, user))
+		resp := w.Result()
+		require.NoError(t, resp.Body.Close())
+		require.Equal(t, http.StatusCreated, resp.StatusCode, "url %d", i)
+	}
+}
+
+// deniedCodes returns a denylisted code on the first attempt.
+type deniedCodes struct{}
+
+func (deniedCodes) Generate(_ context.Context, _ string, attempt int) (model.ShortCode, error) {
+	if attempt == 0 {
+		return "sh1tty", nil
+	}
+	return model.ShortCode(fmt.Sprintf("fine%d", attempt)), nil
+}
+
+func TestShortener_ShortenJSONDenylist(t *testing.T) {
+	userRepo := mem.NewMemUserRepo()
+	user, err := userRepo.CreateUser(context.TODO())
+	require.NoError(t, err)
+	shortener := newTestService("http://localhost:8081", mem.NewMemRecordRepo(), service.Options{
+		Codes:    deniedCodes{},
+		Denylist: denylist.New(nil, true),
+	})
+	handler := New(shortener, nil, nil)
+
+	r := httptest.NewRequest(http.MethodPost, "/api/shorten", strings.NewReader(`{"url": "http://a.com"}`))
+	r.Header.Set(httputil.HeaderContentType, httputil.ContentTypeJSON)
+	w := httptest.NewRecorder()
+	handler.ShortenJSON(w, auth.AttachUser(r, user))
+	resp := w.Result()
+	var res jsonResponse
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&res))
+	require.NoError(t, resp.Body.Close())
+	assert.Equal(t, http.StatusCreated, resp.StatusCode)
+	assert.Equal(t, "http://localhost:8081/fine1", res.Result)
+}
+
+func TestShortener_ShortenJSONCanonical(t *testing.T) {
+	tests := []struct {
+		name          string
+		canonicalizer *service.Canonicalizer
+		first         string
+		second        string
+		wantStatus    int
+		wantLocation  string
+	}{
+		{
+			name:         "scheme, host and port",
+			first:        "HTTP://Example.COM:80",
+			second:       "http://example.com/",
+			wantStatus:   http.StatusConflict,
+			wantLocation: "HTTP://Example.COM:80",
+		},
+		{
+			name:         "percent-encoding case",
+			first:        "https://example.com:443/a%2fb",
+			second:       "https://example.com/a%2Fb",
+			wantStatus:   http.StatusConflict,
+			wantLocation: "https://example.com:443/a%2fb",
+		},
+		{
+			name:         "query order kept by default",
+			first:        "http://example.com/?b=2&a=1",
+			second:       "http://example.com/?a=1&b=2",
+			wantStatus:   http.StatusCreated,
+			wantLocation: "http://example.com/?b=2&a=1",
+		},
+		{
+			name:          "sorted query without tracking, canonical redirect",
+			canonicalizer: service.NewCanonicalizer(true, true, true),
+			first:         "http://example.com/?b=2&utm_source=x&a=1&fbclid=y",
+			second:        "http://example.com/?a=1&b=2&UTM_medium=z",
+			wantStatus:    http.StatusConflict,
+			wantLocation:  "http://example.com/?a=1&b=2",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			userRepo := mem.NewMemUserRepo()
+			user, err := userRepo.CreateUser(context.TODO())
+			require.NoError(t, err)
+			shortener := newTestService("http://localhost:8081", mem.NewMemRecordRepo(), service.Options{Canonicalizer: tt.canonicalizer})
+			handler := New(shortener, nil, nil)
+
+			shorten := func(originalURL string) *http.Response {
+				body, err := json.Marshal(jsonRequest{URL: originalURL})
+				require.NoError(t, err)
+				r := httptest.NewRequest(http.MethodPost, "/api/shorten", strings.NewReader(string(body)))
+				r.Header.Set(httputil.HeaderContentType, httputil.ContentTypeJSON)
+				w := httptest.NewRecorder()
+				handler.ShortenJSON(w, auth.AttachUser(r, user))
+				return w.Result()
+			}
+			resp := shorten(tt.first)
+			require.Equal(t, http.StatusCreated, resp.StatusCode)
+			var created jsonResponse
+			require.NoError(t, json.NewDecoder(resp.Body).Decode(&created))
+			require.NoError(t, resp.Body.Close())
+
+			resp = shorten(tt.second)
+			require.NoError(t, resp.Body.Close())
+			assert.Equal(t, tt.wantStatus, resp.StatusCode)
+
+			shortCode := strings.TrimPrefix(created.Result, "http://localhost:8081/")
+			redirect, err := shortener.GetByShortCode(context.TODO(), shortCode, "", "")
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantLocation, redirect.URL)
+		})
+	}
+}
+
+func TestShortener_ShortenJSONCanonicalBackfill(t *testing.T) {
+	repo := mem.NewMemRecordRepo()
+	// records stored before canonicalization, without a canonical form
+	for shortCode, originalURL := range map[model.ShortCode]model.OriginalURL{
+		"a": "HTTP://Example.COM:80",
+		"b": "http://example.com",
+		"c": "https://x.example/a%2fb",
+	} {
+		require.NoError(t, repo.Store(context.TODO(), &model.BaseRecord{ShortCode: shortCode, OriginalURL: originalURL}, 1))
+	}
+	updated, skipped, err := service.NewCanonicalizer(false, false, false).Backfill(context.TODO(), repo)
+	require.NoError(t, err)
+	assert.Equal(t, 2, updated)
+	assert.Equal(t, 1, skipped, "b has the canonical form of a")
+
+	userRepo := mem.NewMemUserRepo()
+	user, err := userRepo.CreateUser(context.TODO())
+	require.NoError(t, err)
+	handler := New(newTestService("http://localhost:8081", repo, service.Options{}), nil, nil)
+	for _, originalURL := range []string{"http://example.com/", "https://x.example/a%2Fb"} {
+		body, err := json.Marshal(jsonRequest{URL: originalURL})
+		require.NoError(t, err)
+		r := httptest.NewRequest(http.MethodPost, "/api/shorten", strings.NewReader(string(body)))
+		r.Header.Set(httputil.HeaderContentType, httputil.ContentTypeJSON)
+		w := httptest.NewRecorder()
+		handler.ShortenJSON(w, auth.AttachUser(r, user))
+		assert.Equal(t, http.StatusConflict, w.Code, originalURL)
+	}
+}
@@ -11,6 +11,7 @@ import (
 	"github.com/domurdoc/shortener/internal/service"
 )
 
+// Shorten keeps plain text errors, it is the legacy API.
 func (h *Handler) Shorten(w http.ResponseWriter, r *http.Request) {
 	user := auth.GetUser(r)
 
@@ -21,15 +22,16 @@ func (h *Handler) Shorten(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	longURL := string(buf[:n])
-	shortURL, err := h.service.Shorten(r.Context(), user, longURL)
+	shortURL, err := h.service.Shorten(r.Context(), user, longURL, model.LinkOptions{})
 	var invalidURLErr *model.InvalidURLError
-	if errors.As(err, &invalidURLErr) {
+	var blockedErr *model.DestinationBlockedError
+	if errors.As(err, &invalidURLErr) || errors.As(err, &blockedErr) {
 		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
 	var urlExistsErr *model.OriginalURLExistsError
 	if err != nil && !errors.As(err, &urlExistsErr) {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
 		return
 	}
 	status := http.StatusCreated
@@ -8,7 +8,6 @@ import (
 	"net/url"
 	"strings"
 	"testing"
-	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -76,17 +75,10 @@ func TestShortener_Shorten(t *testing.T) {
 				debugStrategy,
 				bearerTransport,
 				userRepo,
-			)
-			service := service.New(
-				tt.baseURL,
-				1,
-				1,
-				time.Second,
-				mem.NewMemRecordRepo(),
-				nil,
 				nil,
 			)
-			handler := New(service)
+			service := newTestService(tt.baseURL, mem.NewMemRecordRepo(), service.Options{})
+			handler := New(service, nil, nil)
 
 			r := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(tt.longURL))
 			w := httptest.NewRecorder()
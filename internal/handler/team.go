@@ -0,0 +1,185 @@
+package handler
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/domurdoc/shortener/internal/auth"
+	"github.com/domurdoc/shortener/internal/httputil"
+	"github.com/domurdoc/shortener/internal/model"
+)
+
+type jsonTeamRequest struct {
+	Name string `json:"name"`
+}
+
+type jsonTeam struct {
+	ID   model.TeamID   `json:"id"`
+	Name string         `json:"name"`
+	Role model.TeamRole `json:"role"`
+}
+
+type jsonMemberRequest struct {
+	Role model.TeamRole `json:"role"`
+}
+
+type jsonMember struct {
+	UserID model.UserID   `json:"user_id"`
+	Role   model.TeamRole `json:"role"`
+}
+
+func (h *Handler) CreateTeam(w http.ResponseWriter, r *http.Request) {
+	var req jsonTeamRequest
+
+	user := auth.GetUser(r)
+
+	if !httputil.HasContentType(r.Header, httputil.ContentTypeJSON) {
+		httputil.Error(w, fmt.Sprintf("wanted Content-Type: %s", httputil.ContentTypeJSON), http.StatusBadRequest)
+		return
+	}
+	dec := json.NewDecoder(r.Body)
+	if err := dec.Decode(&req); err != nil {
+		httputil.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if req.Name == "" {
+		httputil.Error(w, "team name must be set", http.StatusBadRequest)
+		return
+	}
+	team, err := h.teams.CreateTeam(r.Context(), user, req.Name)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSONResponse(w, jsonTeam{ID: team.ID, Name: team.Name, Role: model.TeamRoleOwner}, http.StatusCreated)
+}
+
+func (h *Handler) RetrieveTeams(w http.ResponseWriter, r *http.Request) {
+	user := auth.GetUser(r)
+
+	memberships, err := h.teams.GetTeams(r.Context(), user)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	jsonTeams := make([]jsonTeam, 0, len(memberships))
+	for _, m := range memberships {
+		jsonTeams = append(jsonTeams, jsonTeam{ID: m.ID, Name: m.Name, Role: m.Role})
+	}
+	writeJSONResponse(w, jsonTeams, http.StatusOK)
+}
+
+func (h *Handler) RetrieveTeamMembers(w http.ResponseWriter, r *http.Request) {
+	user := auth.GetUser(r)
+
+	teamID, err := pathTeamID(r)
+	if err != nil {
+		httputil.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	members, err := h.teams.GetMembers(r.Context(), user, teamID)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	jsonMembers := make([]jsonMember, 0, len(members))
+	for _, m := range members {
+		jsonMembers = append(jsonMembers, jsonMember{UserID: m.UserID, Role: m.Role})
+	}
+	writeJSONResponse(w, jsonMembers, http.StatusOK)
+}
+
+func (h *Handler) SetTeamMember(w http.ResponseWriter, r *http.Request) {
+	var req jsonMemberRequest
+
+	user := auth.GetUser(r)
+
+	teamID, err := pathTeamID(r)
+	if err != nil {
+		httputil.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	memberID, err := strconv.Atoi(r.PathValue("userID"))
+	if err != nil {
+		httputil.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if !httputil.HasContentType(r.Header, httputil.ContentTypeJSON) {
+		httputil.Error(w, fmt.Sprintf("wanted Content-Type: %s", httputil.ContentTypeJSON), http.StatusBadRequest)
+		return
+	}
+	dec := json.NewDecoder(r.Body)
+	if err := dec.Decode(&req); err != nil {
+		httputil.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if !req.Role.Valid() {
+		httputil.Error(w, fmt.Sprintf("unknown role %q", req.Role), http.StatusBadRequest)
+		return
+	}
+	member := &model.TeamMember{TeamID: teamID, UserID: model.UserID(memberID), Role: req.Role}
+	err = h.teams.SetMember(r.Context(), user, member)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *Handler) RemoveTeamMember(w http.ResponseWriter, r *http.Request) {
+	user := auth.GetUser(r)
+
+	teamID, err := pathTeamID(r)
+	if err != nil {
+		httputil.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	memberID, err := strconv.Atoi(r.PathValue("userID"))
+	if err != nil {
+		httputil.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	err = h.teams.RemoveMember(r.Context(), user, teamID, model.UserID(memberID))
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *Handler) DeleteTeamShortCodes(w http.ResponseWriter, r *http.Request) {
+	var shortCodes []string
+
+	user := auth.GetUser(r)
+
+	teamID, err := pathTeamID(r)
+	if err != nil {
+		httputil.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if !httputil.HasContentType(r.Header, httputil.ContentTypeJSON) {
+		httputil.Error(w, fmt.Sprintf("wanted Content-Type: %s", httputil.ContentTypeJSON), http.StatusBadRequest)
+		return
+	}
+	dec := json.NewDecoder(r.Body)
+	if err := dec.Decode(&shortCodes); err != nil {
+		httputil.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	_, err = h.teams.DeleteShortCodes(r.Context(), user, teamID, shortCodes)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func pathTeamID(r *http.Request) (model.TeamID, error) {
+	teamID, err := strconv.Atoi(r.PathValue("teamID"))
+	if err != nil {
+		return 0, err
+	}
+	return model.TeamID(teamID), nil
+}
@@ -0,0 +1,149 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/domurdoc/shortener/internal/auth"
+	"github.com/domurdoc/shortener/internal/httputil"
+	"github.com/domurdoc/shortener/internal/model"
+	"github.com/domurdoc/shortener/internal/repository/mem"
+	"github.com/domurdoc/shortener/internal/service"
+)
+
+func TestShortener_TeamLinks(t *testing.T) {
+	userRepo := mem.NewMemUserRepo()
+	shortener := newTestService("http://localhost:8080", mem.NewMemRecordRepo(), service.Options{})
+	handler := New(shortener, service.NewTeam(mem.NewMemTeamRepo(), shortener), nil)
+
+	owner, err := userRepo.CreateUser(context.TODO())
+	require.NoError(t, err)
+	member, err := userRepo.CreateUser(context.TODO())
+	require.NoError(t, err)
+
+	do := func(h http.HandlerFunc, user *model.User, method, target, body string, pathValues ...string) *http.Response {
+		r := httptest.NewRequest(method, target, strings.NewReader(body))
+		r.Header.Set(httputil.HeaderContentType, httputil.ContentTypeJSON)
+		for i := 0; i+1 < len(pathValues); i += 2 {
+			r.SetPathValue(pathValues[i], pathValues[i+1])
+		}
+		w := httptest.NewRecorder()
+		h(w, auth.AttachUser(r, user))
+		return w.Result()
+	}
+
+	resp := do(handler.CreateTeam, owner, http.MethodPost, "/api/teams", `{"name": "marketing"}`)
+	require.Equal(t, http.StatusCreated, resp.StatusCode)
+	var team jsonTeam
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&team))
+	require.NoError(t, resp.Body.Close())
+	teamID := fmt.Sprint(team.ID)
+	memberID := fmt.Sprint(member.ID)
+
+	shortenBody := fmt.Sprintf(`{"url": "http://yandex.com", "team_id": %d}`, team.ID)
+	resp = do(handler.ShortenJSON, member, http.MethodPost, "/api/shorten", shortenBody)
+	assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+
+	resp = do(handler.SetTeamMember, owner, http.MethodPut, "/", `{"role": "viewer"}`, "teamID", teamID, "userID", memberID)
+	assert.Equal(t, http.StatusNoContent, resp.StatusCode)
+	resp = do(handler.ShortenJSON, member, http.MethodPost, "/api/shorten", shortenBody)
+	assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+
+	resp = do(handler.SetTeamMember, owner, http.MethodPut, "/", `{"role": "editor"}`, "teamID", teamID, "userID", memberID)
+	assert.Equal(t, http.StatusNoContent, resp.StatusCode)
+	resp = do(handler.ShortenJSON, member, http.MethodPost, "/api/shorten", shortenBody)
+	assert.Equal(t, http.StatusCreated, resp.StatusCode)
+
+	resp = do(handler.DeleteTeamShortCodes, member, http.MethodDelete, "/", `["x"]`, "teamID", teamID)
+	assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+
+	resp = do(handler.RemoveTeamMember, member, http.MethodDelete, "/", "", "teamID", teamID, "userID", memberID)
+	assert.Equal(t, http.StatusNoContent, resp.StatusCode)
+	resp = do(handler.RemoveTeamMember, owner, http.MethodDelete, "/", "", "teamID", teamID, "userID", fmt.Sprint(owner.ID))
+	assert.Equal(t, http.StatusConflict, resp.StatusCode)
+
+	resp = do(handler.RetrieveForUser, owner, http.MethodGet, "/api/user/urls?owner=team&team_id="+teamID, "")
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	var urls []jsonURLRecord
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&urls))
+	require.NoError(t, resp.Body.Close())
+	require.Len(t, urls, 1)
+	assert.Equal(t, team.ID, urls[0].TeamID)
+
+	resp = do(handler.RetrieveForUser, owner, http.MethodGet, "/api/user/urls", "")
+	assert.Equal(t, http.StatusNoContent, resp.StatusCode)
+
+	for _, u := range []string{"http://ya.ru", "http://go.dev"} {
+		resp = do(handler.ShortenJSON, owner, http.MethodPost, "/api/shorten", `{"url": "`+u+`"}`)
+		require.Equal(t, http.StatusCreated, resp.StatusCode)
+	}
+	resp = do(handler.RetrieveForUser, owner, http.MethodGet, "/api/user/urls?owner=all&limit=2&offset=1", "")
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "3", resp.Header.Get("X-Total-Count"))
+	urls = nil
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&urls))
+	require.NoError(t, resp.Body.Close())
+	require.Len(t, urls, 2)
+	assert.Equal(t, model.OriginalURL("http://go.dev"), urls[0].OriginalURL)
+	assert.Equal(t, team.ID, urls[1].TeamID)
+}
+
+func TestShortener_TeamLinkPassword(t *testing.T) {
+	userRepo := mem.NewMemUserRepo()
+	shortener := newTestService("http://localhost:8080", mem.NewMemRecordRepo(), service.Options{})
+	teams := service.NewTeam(mem.NewMemTeamRepo(), shortener)
+	handler := New(shortener, teams, nil)
+
+	owner, err := userRepo.CreateUser(context.TODO())
+	require.NoError(t, err)
+	member, err := userRepo.CreateUser(context.TODO())
+	require.NoError(t, err)
+	team, err := teams.CreateTeam(context.TODO(), owner, "marketing")
+	require.NoError(t, err)
+	require.NoError(t, teams.SetMember(context.TODO(), owner, &model.TeamMember{TeamID: team.ID, UserID: member.ID, Role: model.TeamRoleViewer}))
+
+	shortURL, err := teams.Shorten(context.TODO(), owner, team.ID, "http://yandex.com", model.LinkOptions{})
+	require.NoError(t, err)
+	shortCode := path.Base(shortURL)
+	personalURL, err := shortener.Shorten(context.TODO(), owner, "http://ya.ru", model.LinkOptions{})
+	require.NoError(t, err)
+	_, err = teams.Shorten(context.TODO(), owner, team.ID, "http://ya.ru", model.LinkOptions{})
+	require.Error(t, err)
+
+	setPassword := func(user *model.User, shortCode string) int {
+		r := httptest.NewRequest(http.MethodPut, "/", strings.NewReader(`{"password": "s3cret"}`))
+		r.Header.Set(httputil.HeaderContentType, httputil.ContentTypeJSON)
+		r.SetPathValue("teamID", fmt.Sprint(team.ID))
+		r.SetPathValue("shortCode", shortCode)
+		w := httptest.NewRecorder()
+		handler.SetTeamLinkPassword(w, auth.AttachUser(r, user))
+		return w.Code
+	}
+	assert.Equal(t, http.StatusForbidden, setPassword(member, shortCode))
+	// the personal link the team deduplicated to stays with its creator
+	assert.Equal(t, http.StatusNotFound, setPassword(owner, path.Base(personalURL)))
+
+	require.NoError(t, teams.SetMember(context.TODO(), owner, &model.TeamMember{TeamID: team.ID, UserID: member.ID, Role: model.TeamRoleEditor}))
+	assert.Equal(t, http.StatusNoContent, setPassword(member, shortCode))
+	_, err = shortener.GetByShortCode(context.TODO(), shortCode, "", "client")
+	var passwordErr *model.LinkPasswordError
+	assert.ErrorAs(t, err, &passwordErr)
+
+	r := httptest.NewRequest(http.MethodDelete, "/", nil)
+	r.SetPathValue("teamID", fmt.Sprint(team.ID))
+	r.SetPathValue("shortCode", shortCode)
+	w := httptest.NewRecorder()
+	handler.RemoveTeamLinkPassword(w, auth.AttachUser(r, member))
+	assert.Equal(t, http.StatusNoContent, w.Code)
+	_, err = shortener.GetByShortCode(context.TODO(), shortCode, "", "client")
+	assert.NoError(t, err)
+}
@@ -0,0 +1,33 @@
+package handler
+
+import (
+	"html/template"
+	"net/http"
+
+	"github.com/domurdoc/shortener/internal/httputil"
+	"github.com/domurdoc/shortener/internal/model"
+)
+
+// warningPage replaces the redirect to a destination found on the malware
+// blocklist. The destination is shown as text, never as a link.
+var warningPage = template.Must(template.New("warning").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<meta name="robots" content="noindex">
+<title>Dangerous link blocked</title>
+</head>
+<body>
+<h1>Dangerous link blocked</h1>
+<p>This short link leads to a site reported for malware or phishing, so we stopped the redirect.</p>
+<p>Destination: <code>{{.URL}}</code></p>
+</body>
+</html>
+`))
+
+func writeWarningPage(w http.ResponseWriter, blockedErr *model.DestinationBlockedError) {
+	httputil.SetContentType(w.Header(), httputil.ContentTypeHTML)
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(http.StatusForbidden)
+	warningPage.Execute(w, blockedErr)
+}
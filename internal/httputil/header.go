@@ -1,6 +1,7 @@
 package httputil
 
 import (
+	"net"
 	"net/http"
 	"strings"
 )
@@ -8,6 +9,9 @@ import (
 const (
 	ContentTypeJSON      = "application/json"
 	ContentTypeTextPlain = "text/plain; charset=utf-8"
+	ContentTypeCSV       = "text/csv"
+	ContentTypeNDJSON    = "application/x-ndjson"
+	ContentTypeHTML      = "text/html; charset=utf-8"
 )
 const (
 	EncodingGZIP = "gzip"
@@ -16,6 +20,7 @@ const (
 	HeaderContentType     = "Content-Type"
 	HeaderContentEncoding = "Content-Encoding"
 	HeaderAcceptEncoding  = "Accept-Encoding"
+	HeaderAccept          = "Accept"
 )
 
 func HasHeader(headers http.Header, header string) bool {
@@ -30,6 +35,10 @@ func HasAcceptsEncoding(headers http.Header, encodings ...string) bool {
 	return hasHeaderValue(headers, HeaderAcceptEncoding, encodings...)
 }
 
+func Accepts(headers http.Header, contentTypes ...string) bool {
+	return hasHeaderValue(headers, HeaderAccept, contentTypes...)
+}
+
 func HasContentEncoding(headers http.Header, encodings ...string) bool {
 	return hasHeaderValue(headers, HeaderContentEncoding, encodings...)
 }
@@ -51,3 +60,13 @@ func SetContentType(headers http.Header, contentType string) {
 func SetContentEncoding(headers http.Header, encoding string) {
 	headers.Set(HeaderContentEncoding, encoding)
 }
+
+// RemoteHost drops the port of a remote address, it changes with every
+// connection of a client.
+func RemoteHost(remoteAddr string) string {
+	host, _, err := net.SplitHostPort(remoteAddr)
+	if err != nil {
+		return remoteAddr
+	}
+	return host
+}
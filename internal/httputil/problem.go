@@ -0,0 +1,80 @@
+package httputil
+
+import (
+	"encoding/json"
+	"maps"
+	"net/http"
+)
+
+const ContentTypeProblemJSON = "application/problem+json"
+
+// ProblemTypePrefix namespaces the problem types of this service. Generic
+// HTTP errors use about:blank as RFC 7807 suggests.
+const (
+	ProblemTypePrefix = "urn:shortener:problem:"
+	ProblemTypeBlank  = "about:blank"
+)
+
+// Problem is an RFC 7807 problem details object. Extensions are merged into
+// the top level of the JSON body.
+type Problem struct {
+	Type       string
+	Title      string
+	Status     int
+	Detail     string
+	Extensions map[string]any
+}
+
+// NewProblem builds a problem of a service specific type such as
+// "invalid-url".
+func NewProblem(status int, problemType, title, detail string) *Problem {
+	return &Problem{
+		Type:   ProblemTypePrefix + problemType,
+		Title:  title,
+		Status: status,
+		Detail: detail,
+	}
+}
+
+func (p *Problem) With(key string, value any) *Problem {
+	if p.Extensions == nil {
+		p.Extensions = make(map[string]any)
+	}
+	p.Extensions[key] = value
+	return p
+}
+
+func (p *Problem) MarshalJSON() ([]byte, error) {
+	fields := make(map[string]any, len(p.Extensions)+4)
+	maps.Copy(fields, p.Extensions)
+	fields["type"] = p.Type
+	fields["title"] = p.Title
+	fields["status"] = p.Status
+	if p.Detail != "" {
+		fields["detail"] = p.Detail
+	}
+	return json.Marshal(fields)
+}
+
+func WriteProblem(w http.ResponseWriter, p *Problem) {
+	SetContentType(w.Header(), ContentTypeProblemJSON)
+	w.Header().Del("Content-Length")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(p.Status)
+	json.NewEncoder(w).Encode(p)
+}
+
+// Error is http.Error answering with an about:blank problem.
+func Error(w http.ResponseWriter, detail string, status int) {
+	WriteProblem(w, &Problem{
+		Type:   ProblemTypeBlank,
+		Title:  http.StatusText(status),
+		Status: status,
+		Detail: detail,
+	})
+}
+
+// InternalError hides the cause of a server error from clients.
+func InternalError(w http.ResponseWriter) {
+	Error(w, "", http.StatusInternalServerError)
+}
@@ -3,6 +3,7 @@ package model
 import (
 	"fmt"
 	"strings"
+	"time"
 )
 
 type InvalidURLError struct {
@@ -14,6 +15,18 @@ func (e InvalidURLError) Error() string {
 	return fmt.Sprintf("Invalid URL %q: %s", e.URL, e.Msg)
 }
 
+// DestinationBlockedError means a valid URL points somewhere the destination
+// policy does not allow, Rule names the failed check.
+type DestinationBlockedError struct {
+	URL  string
+	Rule string
+	Msg  string
+}
+
+func (e *DestinationBlockedError) Error() string {
+	return fmt.Sprintf("Destination %q blocked: %s", e.URL, e.Msg)
+}
+
 type ShortCodeNotFoundError struct {
 	ShortCode ShortCode
 }
@@ -30,14 +43,86 @@ func (e ShortCodeDeletedError) Error() string {
 	return fmt.Sprintf("ShortCode %q deleted", e.ShortCode)
 }
 
-type OriginalURLExistsError struct {
-	OriginalURL OriginalURL
+// LinkPasswordError means a protected link was opened without its password,
+// or with a wrong one when Given is set.
+type LinkPasswordError struct {
+	ShortCode ShortCode
+	Given     bool
+}
+
+func (e *LinkPasswordError) Error() string {
+	if e.Given {
+		return fmt.Sprintf("Wrong password for ShortCode %q", e.ShortCode)
+	}
+	return fmt.Sprintf("ShortCode %q needs a password", e.ShortCode)
+}
+
+// TooManyAttemptsError means password attempts for a link are paused.
+type TooManyAttemptsError struct {
+	ShortCode  ShortCode
+	RetryAfter time.Duration
+}
+
+func (e *TooManyAttemptsError) Error() string {
+	return fmt.Sprintf("Too many password attempts for ShortCode %q, retry in %s", e.ShortCode, e.RetryAfter)
+}
+
+// ClickLimitReachedError means a link served all of its MaxClicks redirects.
+type ClickLimitReachedError struct {
+	ShortCode ShortCode
+	MaxClicks int64
+}
+
+func (e *ClickLimitReachedError) Error() string {
+	return fmt.Sprintf("ShortCode %q reached its limit of %d clicks", e.ShortCode, e.MaxClicks)
+}
+
+// LinkPendingError means a link is opened before its ActiveFrom.
+type LinkPendingError struct {
+	ShortCode  ShortCode
+	ActiveFrom time.Time
+}
+
+func (e *LinkPendingError) Error() string {
+	return fmt.Sprintf("ShortCode %q is active from %s", e.ShortCode, e.ActiveFrom.UTC().Format(time.RFC3339))
+}
+
+// LinkExpiredError means a link is opened after its ActiveUntil.
+type LinkExpiredError struct {
 	ShortCode   ShortCode
-	BatchPos    int
+	ActiveUntil time.Time
+}
+
+func (e *LinkExpiredError) Error() string {
+	return fmt.Sprintf("ShortCode %q expired at %s", e.ShortCode, e.ActiveUntil.UTC().Format(time.RFC3339))
+}
+
+// OriginalURLExistsError names the link a URL was deduplicated to.
+// OptionsIgnored is set when the request carried link options, the existing
+// link keeps its own.
+type OriginalURLExistsError struct {
+	OriginalURL    OriginalURL
+	ShortCode      ShortCode
+	BatchPos       int
+	OptionsIgnored bool
 }
 
 func (e OriginalURLExistsError) Error() string {
-	return fmt.Sprintf("OriginalURL %q already exists with ShortCode %q", e.OriginalURL, e.ShortCode)
+	msg := fmt.Sprintf("OriginalURL %q already exists with ShortCode %q", e.OriginalURL, e.ShortCode)
+	if e.OptionsIgnored {
+		msg += ", link options were not applied"
+	}
+	return msg
+}
+
+// ShortCodeExistsError means a generated short code is taken by another URL.
+type ShortCodeExistsError struct {
+	ShortCode ShortCode
+	BatchPos  int
+}
+
+func (e *ShortCodeExistsError) Error() string {
+	return fmt.Sprintf("ShortCode %q already exists", e.ShortCode)
 }
 
 type BatchOriginalURLExistsError []*OriginalURLExistsError
@@ -57,3 +142,86 @@ type UserNotFoundError struct {
 func (e *UserNotFoundError) Error() string {
 	return fmt.Sprintf("User %q not found", e.UserID)
 }
+
+type RefreshTokenNotFoundError struct{}
+
+func (e *RefreshTokenNotFoundError) Error() string {
+	return "RefreshToken not found"
+}
+
+type RefreshTokenReusedError struct {
+	Family string
+}
+
+func (e *RefreshTokenReusedError) Error() string {
+	return fmt.Sprintf("RefreshToken reused in family %q", e.Family)
+}
+
+type TeamNotFoundError struct {
+	TeamID TeamID
+}
+
+func (e *TeamNotFoundError) Error() string {
+	return fmt.Sprintf("Team %d not found", e.TeamID)
+}
+
+type TeamMemberNotFoundError struct {
+	TeamID TeamID
+	UserID UserID
+}
+
+func (e *TeamMemberNotFoundError) Error() string {
+	return fmt.Sprintf("User %d is not a member of Team %d", e.UserID, e.TeamID)
+}
+
+type TeamPermissionError struct {
+	TeamID     TeamID
+	UserID     UserID
+	Permission TeamPermission
+}
+
+func (e *TeamPermissionError) Error() string {
+	return fmt.Sprintf("User %d has no %q permission in Team %d", e.UserID, e.Permission, e.TeamID)
+}
+
+type LastTeamOwnerError struct {
+	TeamID TeamID
+}
+
+func (e *LastTeamOwnerError) Error() string {
+	return fmt.Sprintf("Team %d must keep at least one owner", e.TeamID)
+}
+
+type IdentityNotFoundError struct {
+	Issuer  string
+	Subject string
+}
+
+func (e *IdentityNotFoundError) Error() string {
+	return fmt.Sprintf("Identity %q of %q not found", e.Subject, e.Issuer)
+}
+
+type IdempotencyKeyNotFoundError struct {
+	Key string
+}
+
+func (e *IdempotencyKeyNotFoundError) Error() string {
+	return fmt.Sprintf("Idempotency key %q not found", e.Key)
+}
+
+type IdempotencyKeyMismatchError struct {
+	Key string
+}
+
+func (e *IdempotencyKeyMismatchError) Error() string {
+	return fmt.Sprintf("Idempotency key %q was used for a different request", e.Key)
+}
+
+// IdempotencyKeyPendingError means the first request with the key still runs.
+type IdempotencyKeyPendingError struct {
+	Key string
+}
+
+func (e *IdempotencyKeyPendingError) Error() string {
+	return fmt.Sprintf("Idempotency key %q is used by a request in progress", e.Key)
+}
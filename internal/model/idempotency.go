@@ -0,0 +1,20 @@
+package model
+
+import "time"
+
+// IdempotencyKey keeps the response to a request sent with an Idempotency-Key
+// header, so a retry is answered without running the request again. A key
+// with a zero StatusCode is reserved by a request that still runs.
+type IdempotencyKey struct {
+	UserID      UserID
+	Key         string
+	RequestHash string
+	StatusCode  int
+	ContentType string
+	Body        []byte
+	ExpiresAt   time.Time
+}
+
+func (k *IdempotencyKey) Pending() bool {
+	return k.StatusCode == 0
+}
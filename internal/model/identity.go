@@ -0,0 +1,7 @@
+package model
+
+type Identity struct {
+	Issuer  string
+	Subject string
+	UserID  UserID
+}
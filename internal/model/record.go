@@ -1,14 +1,70 @@
 package model
 
+import (
+	"net/http"
+	"slices"
+	"time"
+)
+
 type (
 	OriginalURL string
 	ShortCode   string
 	ShortURL    string
 )
 
+// BaseRecord redirects to OriginalURL. CanonicalURL, when set, is the form
+// records are deduplicated on. PasswordHash is the bcrypt hash of the link
+// password, empty for open links.
 type BaseRecord struct {
-	ShortCode   ShortCode
-	OriginalURL OriginalURL
+	ShortCode    ShortCode
+	OriginalURL  OriginalURL
+	CanonicalURL OriginalURL
+	PasswordHash string
+	LinkOptions
+}
+
+// LinkOptions are chosen when a link is created, zero values mean no limits
+// and the server defaults.
+type LinkOptions struct {
+	// MaxClicks is how many redirects the link serves.
+	MaxClicks int64
+	// ActiveFrom and ActiveUntil bound when the link redirects.
+	ActiveFrom  time.Time
+	ActiveUntil time.Time
+	// RedirectStatus is one of RedirectStatuses.
+	RedirectStatus int
+}
+
+func (o LinkOptions) IsZero() bool {
+	return o.MaxClicks == 0 && o.ActiveFrom.IsZero() && o.ActiveUntil.IsZero() && o.RedirectStatus == 0
+}
+
+// RedirectStatuses are the status codes links can redirect with.
+var RedirectStatuses = []int{
+	http.StatusMovedPermanently,
+	http.StatusFound,
+	http.StatusTemporaryRedirect,
+	http.StatusPermanentRedirect,
+}
+
+func IsRedirectStatus(status int) bool {
+	return slices.Contains(RedirectStatuses, status)
+}
+
+// Redirect is how a link answers. MaxAge is how long clients and proxies
+// may cache the answer, zero forbids it.
+type Redirect struct {
+	URL    string
+	Status int
+	MaxAge time.Duration
+}
+
+// DedupURL is CanonicalURL, or OriginalURL for records stored without one.
+func (r BaseRecord) DedupURL() OriginalURL {
+	if r.CanonicalURL != "" {
+		return r.CanonicalURL
+	}
+	return r.OriginalURL
 }
 
 type UserRecord struct {
@@ -19,4 +75,47 @@ type UserRecord struct {
 type URLRecord struct {
 	ShortURL    ShortURL
 	OriginalURL OriginalURL
+	TeamID      TeamID
+}
+
+type RecordDetails struct {
+	BaseRecord
+	ShortURL  ShortURL
+	Owners    []UserID
+	Teams     []TeamID
+	IsDeleted bool
+	// ForceDeleted is set by an admin takedown, IsDeleted also covers links all owners deleted.
+	ForceDeleted bool
+	CreatedAt    time.Time
+	Clicks       int64
+	// Creator is the owner who shortened the link first, only they may change
+	// its password. It is 0 for team links.
+	Creator UserID
+}
+
+type BatchItemStatus string
+
+const (
+	BatchItemCreated BatchItemStatus = "created"
+	BatchItemExists  BatchItemStatus = "exists"
+	BatchItemInvalid BatchItemStatus = "invalid"
+)
+
+// BatchItem is the outcome for one URL of a partially applied batch, Error is
+// set for invalid items, and for existing links the options of the item were
+// not applied to.
+type BatchItem struct {
+	ShortURL ShortURL
+	Status   BatchItemStatus
+	Error    error
+}
+
+type RecordStats struct {
+	Records       int
+	ActiveRecords int
+}
+
+type Stats struct {
+	RecordStats
+	Users int
 }
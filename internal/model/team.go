@@ -0,0 +1,69 @@
+package model
+
+type TeamID int
+
+type TeamRole string
+
+const (
+	TeamRoleOwner  TeamRole = "owner"
+	TeamRoleEditor TeamRole = "editor"
+	TeamRoleViewer TeamRole = "viewer"
+)
+
+type TeamPermission string
+
+const (
+	TeamPermissionRead   TeamPermission = "read"
+	TeamPermissionCreate TeamPermission = "create"
+	TeamPermissionEdit   TeamPermission = "edit"
+	TeamPermissionDelete TeamPermission = "delete"
+	TeamPermissionManage TeamPermission = "manage"
+)
+
+var teamRolePermissions = map[TeamRole][]TeamPermission{
+	TeamRoleOwner: {
+		TeamPermissionRead,
+		TeamPermissionCreate,
+		TeamPermissionEdit,
+		TeamPermissionDelete,
+		TeamPermissionManage,
+	},
+	TeamRoleEditor: {
+		TeamPermissionRead,
+		TeamPermissionCreate,
+		TeamPermissionEdit,
+	},
+	TeamRoleViewer: {
+		TeamPermissionRead,
+	},
+}
+
+func (r TeamRole) Valid() bool {
+	_, ok := teamRolePermissions[r]
+	return ok
+}
+
+func (r TeamRole) Can(permission TeamPermission) bool {
+	for _, p := range teamRolePermissions[r] {
+		if p == permission {
+			return true
+		}
+	}
+	return false
+}
+
+type Team struct {
+	ID   TeamID
+	Name string
+}
+
+type TeamMember struct {
+	TeamID TeamID
+	UserID UserID
+	Role   TeamRole
+}
+
+type TeamMembership struct {
+	Team
+	Role TeamRole
+}
@@ -0,0 +1,12 @@
+package model
+
+import "time"
+
+type RefreshToken struct {
+	Hash      string
+	UserID    UserID
+	Family    string
+	ExpiresAt time.Time
+	Used      bool
+	UsedAt    time.Time
+}
@@ -2,6 +2,15 @@ package model
 
 type UserID int
 
+type Role string
+
+const (
+	RoleUser  Role = "user"
+	RoleAdmin Role = "admin"
+)
+
 type User struct {
-	ID UserID
+	ID       UserID
+	Role     Role
+	Disabled bool
 }
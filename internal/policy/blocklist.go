@@ -0,0 +1,118 @@
+package policy
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/netip"
+	"net/url"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+const hashTag = "sha256:"
+
+// Blocklist holds known malware and phishing destinations from a file that
+// is reloaded like DomainList. A line is either a host, which also blocks
+// its subdomains, or "sha256:" and the hex SHA-256 of a URL expression in the
+// Safe Browsing style: a host suffix followed by a path prefix, such as
+// "evil.example.com/login" or "example.com/". Hash prefixes are rejected, a
+// prefix match only means the full hash has to be checked.
+type Blocklist struct {
+	file *watchedFile[*threats]
+}
+
+type threats struct {
+	hosts  domainSet
+	hashes map[[sha256.Size]byte]bool
+}
+
+func LoadBlocklist(path string, interval time.Duration, log *zap.SugaredLogger) (*Blocklist, error) {
+	file, err := loadWatchedFile(path, interval, parseThreats, log)
+	if err != nil {
+		return nil, err
+	}
+	return &Blocklist{file: file}, nil
+}
+
+// Close stops watching the file, a nil list is a no-op.
+func (b *Blocklist) Close() error {
+	if b == nil {
+		return nil
+	}
+	return b.file.Close()
+}
+
+// Match reports the entry that blocks u.
+func (b *Blocklist) Match(u *url.URL) (string, bool) {
+	t := b.file.get()
+	host := strings.TrimSuffix(strings.ToLower(u.Hostname()), ".")
+	if domain, ok := t.hosts.match(host); ok {
+		return domain, true
+	}
+	if len(t.hashes) == 0 {
+		return "", false
+	}
+	for _, expr := range urlExpressions(host, u) {
+		if t.hashes[sha256.Sum256([]byte(expr))] {
+			return expr, true
+		}
+	}
+	return "", false
+}
+
+func parseThreats(lines []string) (*threats, error) {
+	t := &threats{hosts: make(domainSet), hashes: make(map[[sha256.Size]byte]bool)}
+	for _, line := range lines {
+		encoded, isHash := strings.CutPrefix(strings.ToLower(line), hashTag)
+		if !isHash {
+			t.hosts.add(line)
+			continue
+		}
+		hash, err := hex.DecodeString(encoded)
+		if err != nil || len(hash) != sha256.Size {
+			return nil, fmt.Errorf("invalid hash %q, need the full %d bytes", line, sha256.Size)
+		}
+		t.hashes[[sha256.Size]byte(hash)] = true
+	}
+	return t, nil
+}
+
+// urlExpressions combines the host and up to four of its parent domains with
+// the path and query, the path, and the root and up to four leading
+// directories of the path.
+func urlExpressions(host string, u *url.URL) []string {
+	hosts := []string{host}
+	if _, err := netip.ParseAddr(host); err != nil {
+		labels := strings.Split(host, ".")
+		for i := max(1, len(labels)-5); i < len(labels)-1; i++ {
+			hosts = append(hosts, strings.Join(labels[i:], "."))
+		}
+	}
+	path := u.EscapedPath()
+	if path == "" {
+		path = "/"
+	}
+	var paths []string
+	if u.RawQuery != "" {
+		paths = append(paths, path+"?"+u.RawQuery)
+	}
+	paths = append(paths, path)
+	segments := strings.Split(strings.Trim(path, "/"), "/")
+	prefix := "/"
+	for i := 0; i < len(segments) && i < 5; i++ {
+		if prefix != path {
+			paths = append(paths, prefix)
+		}
+		prefix += segments[i] + "/"
+	}
+	expressions := make([]string, 0, len(hosts)*len(paths))
+	for _, h := range hosts {
+		for _, p := range paths {
+			expressions = append(expressions, h+p)
+		}
+	}
+	return expressions
+}
@@ -0,0 +1,43 @@
+package policy
+
+import (
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// DomainList blocks the domains listed in a file, one per line with # comments,
+// and their subdomains. The file is checked for changes in the background once
+// per interval; when a reload fails the previous list stays in use.
+type DomainList struct {
+	file *watchedFile[domainSet]
+}
+
+func LoadDomainList(path string, interval time.Duration, log *zap.SugaredLogger) (*DomainList, error) {
+	file, err := loadWatchedFile(path, interval, parseDomains, log)
+	if err != nil {
+		return nil, err
+	}
+	return &DomainList{file: file}, nil
+}
+
+// Close stops watching the file, a nil list is a no-op.
+func (l *DomainList) Close() error {
+	if l == nil {
+		return nil
+	}
+	return l.file.Close()
+}
+
+// Match returns the listed domain host falls under.
+func (l *DomainList) Match(host string) (string, bool) {
+	return l.file.get().match(host)
+}
+
+func parseDomains(lines []string) (domainSet, error) {
+	domains := make(domainSet, len(lines))
+	for _, line := range lines {
+		domains.add(line)
+	}
+	return domains, nil
+}
@@ -0,0 +1,134 @@
+package policy
+
+import (
+	"bufio"
+	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// watchedFile holds the value parsed from a file of lines with # comments.
+// A goroutine checks the file for changes once per interval, a zero interval
+// loads it only once. When a reload fails the previous value stays in use.
+type watchedFile[T any] struct {
+	path     string
+	interval time.Duration
+	parse    func(lines []string) (T, error)
+	log      *zap.SugaredLogger
+	value    atomic.Pointer[T]
+	modTime  time.Time
+	done     chan struct{}
+	close    sync.Once
+}
+
+func loadWatchedFile[T any](
+	path string,
+	interval time.Duration,
+	parse func([]string) (T, error),
+	log *zap.SugaredLogger,
+) (*watchedFile[T], error) {
+	if log == nil {
+		log = zap.NewNop().Sugar()
+	}
+	f := &watchedFile[T]{path: path, interval: interval, parse: parse, log: log, done: make(chan struct{})}
+	if err := f.reload(); err != nil {
+		return nil, err
+	}
+	if interval > 0 {
+		go f.watch()
+	}
+	return f, nil
+}
+
+func (f *watchedFile[T]) get() T {
+	return *f.value.Load()
+}
+
+// Close stops watching the file.
+func (f *watchedFile[T]) Close() error {
+	f.close.Do(func() { close(f.done) })
+	return nil
+}
+
+func (f *watchedFile[T]) watch() {
+	t := time.NewTicker(f.interval)
+	defer t.Stop()
+	for {
+		select {
+		case <-f.done:
+			return
+		case <-t.C:
+			if err := f.reload(); err != nil {
+				f.log.Warnw("reload failed, keeping the previous list", "path", f.path, "error", err)
+			}
+		}
+	}
+}
+
+// reload runs in one goroutine at a time, readers only see the swapped value.
+func (f *watchedFile[T]) reload() error {
+	info, err := os.Stat(f.path)
+	if err != nil {
+		return err
+	}
+	if f.value.Load() != nil && info.ModTime().Equal(f.modTime) {
+		return nil
+	}
+	lines, err := readLines(f.path)
+	if err != nil {
+		return err
+	}
+	value, err := f.parse(lines)
+	if err != nil {
+		return err
+	}
+	f.value.Store(&value)
+	f.modTime = info.ModTime()
+	f.log.Infow("list loaded", "path", f.path, "lines", len(lines))
+	return nil
+}
+
+// readLines returns the trimmed lines of a file without blanks and comments.
+func readLines(path string) ([]string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	var lines []string
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		lines = append(lines, line)
+	}
+	return lines, scanner.Err()
+}
+
+// domainSet holds lowercase domains without the trailing dot.
+type domainSet map[string]bool
+
+func (s domainSet) add(domain string) {
+	s[strings.TrimSuffix(strings.ToLower(domain), ".")] = true
+}
+
+// match returns the domain of the set host equals or is a subdomain of.
+func (s domainSet) match(host string) (string, bool) {
+	for domain := host; domain != ""; {
+		if s[domain] {
+			return domain, true
+		}
+		_, parent, found := strings.Cut(domain, ".")
+		if !found {
+			break
+		}
+		domain = parent
+	}
+	return "", false
+}
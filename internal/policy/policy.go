@@ -0,0 +1,186 @@
+// Package policy decides which destinations may be shortened.
+package policy
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/netip"
+	"net/url"
+	"slices"
+	"strings"
+
+	"github.com/domurdoc/shortener/internal/model"
+)
+
+// Rules reported by model.DestinationBlockedError.
+const (
+	RuleScheme  = "scheme"
+	RuleLoop    = "loop"
+	RuleDomain  = "domain"
+	RuleNetwork = "network"
+	RuleMalware = "malware"
+)
+
+// DefaultBlockedRanges are loopback, private, link-local and other
+// addresses that are not reachable from the internet.
+var DefaultBlockedRanges = []string{
+	"0.0.0.0/8",
+	"10.0.0.0/8",
+	"100.64.0.0/10",
+	"127.0.0.0/8",
+	"169.254.0.0/16",
+	"172.16.0.0/12",
+	"192.168.0.0/16",
+	"::/128",
+	"::1/128",
+	"fc00::/7",
+	"fe80::/10",
+}
+
+// Resolver looks up host addresses, *net.Resolver implements it.
+type Resolver interface {
+	LookupNetIP(ctx context.Context, network, host string) ([]netip.Addr, error)
+}
+
+// Policy checks destinations against allowed schemes, the service's own
+// address, a domain denylist, a malware blocklist and blocked address ranges.
+// Host names are resolved only when a resolver is set, lookup failures let the
+// URL through.
+type Policy struct {
+	schemes  []string
+	blocked  []netip.Prefix
+	resolver Resolver
+	self     string
+	domains  *DomainList
+	threats  *Blocklist
+}
+
+// New builds a policy, empty schemes allow any scheme and nil lists block
+// nothing.
+func New(
+	schemes []string,
+	blocked []netip.Prefix,
+	resolver Resolver,
+	baseURL string,
+	domains *DomainList,
+	threats *Blocklist,
+) (*Policy, error) {
+	base, err := url.Parse(baseURL)
+	if err != nil {
+		return nil, err
+	}
+	lowered := make([]string, len(schemes))
+	for i, scheme := range schemes {
+		lowered[i] = strings.ToLower(scheme)
+	}
+	return &Policy{
+		schemes:  lowered,
+		blocked:  blocked,
+		resolver: resolver,
+		self:     hostName(base),
+		domains:  domains,
+		threats:  threats,
+	}, nil
+}
+
+// Close stops watching the list files.
+func (p *Policy) Close() error {
+	return errors.Join(p.domains.Close(), p.threats.Close())
+}
+
+// ParsePrefixes parses CIDR ranges such as DefaultBlockedRanges.
+func ParsePrefixes(ranges []string) ([]netip.Prefix, error) {
+	prefixes := make([]netip.Prefix, 0, len(ranges))
+	for _, r := range ranges {
+		prefix, err := netip.ParsePrefix(r)
+		if err != nil {
+			return nil, err
+		}
+		prefixes = append(prefixes, prefix.Masked())
+	}
+	return prefixes, nil
+}
+
+// Check returns *model.DestinationBlockedError for a URL the policy does not
+// allow. rawURL is expected to be valid and absolute.
+func (p *Policy) Check(ctx context.Context, rawURL string) error {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return err
+	}
+	blocked := func(rule, msg string) error {
+		return &model.DestinationBlockedError{URL: rawURL, Rule: rule, Msg: msg}
+	}
+	if len(p.schemes) != 0 && !slices.Contains(p.schemes, strings.ToLower(u.Scheme)) {
+		return blocked(RuleScheme, fmt.Sprintf("scheme %q is not allowed", u.Scheme))
+	}
+	host := hostName(u)
+	if host == p.self {
+		return blocked(RuleLoop, "links to this service would redirect in a loop")
+	}
+	if p.domains != nil {
+		if domain, ok := p.domains.Match(host); ok {
+			return blocked(RuleDomain, fmt.Sprintf("domain %q is blocked", domain))
+		}
+	}
+	if err := p.checkThreats(rawURL, u); err != nil {
+		return err
+	}
+	for _, addr := range p.addrs(ctx, host) {
+		addr = addr.Unmap()
+		for _, prefix := range p.blocked {
+			if prefix.Contains(addr) {
+				return blocked(RuleNetwork, fmt.Sprintf("address %s is in blocked range %s", addr, prefix))
+			}
+		}
+	}
+	return nil
+}
+
+// CheckRedirect checks a stored destination again before redirecting to it,
+// only the malware blocklist applies since it changes after links are made.
+func (p *Policy) CheckRedirect(rawURL string) error {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return err
+	}
+	return p.checkThreats(rawURL, u)
+}
+
+func (p *Policy) checkThreats(rawURL string, u *url.URL) error {
+	if p.threats == nil {
+		return nil
+	}
+	if entry, ok := p.threats.Match(u); ok {
+		return &model.DestinationBlockedError{
+			URL:  rawURL,
+			Rule: RuleMalware,
+			Msg:  fmt.Sprintf("destination is a known malware or phishing site (%s)", entry),
+		}
+	}
+	return nil
+}
+
+func (p *Policy) addrs(ctx context.Context, host string) []netip.Addr {
+	if addr, err := netip.ParseAddr(host); err == nil {
+		return []netip.Addr{addr}
+	}
+	if host == "localhost" || strings.HasSuffix(host, ".localhost") {
+		return []netip.Addr{netip.IPv6Loopback(), netip.AddrFrom4([4]byte{127, 0, 0, 1})}
+	}
+	if p.resolver == nil || len(p.blocked) == 0 {
+		return nil
+	}
+	addrs, err := p.resolver.LookupNetIP(ctx, "ip", host)
+	if err != nil {
+		return nil
+	}
+	return addrs
+}
+
+// hostName is the lowercase host without the port. Ports are not compared,
+// the service is usually reachable over more schemes than its base URL names.
+func hostName(u *url.URL) string {
+	return strings.TrimSuffix(strings.ToLower(u.Hostname()), ".")
+}
@@ -0,0 +1,133 @@
+package policy
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"net/netip"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/domurdoc/shortener/internal/model"
+)
+
+type fakeResolver map[string][]netip.Addr
+
+func (r fakeResolver) LookupNetIP(_ context.Context, _, host string) ([]netip.Addr, error) {
+	addrs, ok := r[host]
+	if !ok {
+		return nil, errors.New("no such host")
+	}
+	return addrs, nil
+}
+
+func TestPolicy_Check(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "domains.txt")
+	require.NoError(t, os.WriteFile(path, []byte("# phishing\nevil.com\n"), 0o644))
+	domains, err := LoadDomainList(path, time.Hour, nil)
+	require.NoError(t, err)
+	blocked, err := ParsePrefixes(DefaultBlockedRanges)
+	require.NoError(t, err)
+	resolver := fakeResolver{
+		"intranet.example": {netip.MustParseAddr("10.1.2.3")},
+		"public.example":   {netip.MustParseAddr("93.184.216.34")},
+	}
+	p, err := New([]string{"http", "https"}, blocked, resolver, "http://sho.rt", domains, nil)
+	require.NoError(t, err)
+
+	tests := []struct {
+		url  string
+		rule string
+	}{
+		{url: "https://public.example/path"},
+		{url: "http://unknown.example/"},
+		{url: "ftp://public.example/file", rule: RuleScheme},
+		{url: "file://host/etc/passwd", rule: RuleScheme},
+		{url: "HTTP://SHO.RT:80/abc", rule: RuleLoop},
+		{url: "https://sho.rt/abc", rule: RuleLoop},
+		{url: "http://sho.rt:8080/abc", rule: RuleLoop},
+		{url: "http://evil.com/", rule: RuleDomain},
+		{url: "http://login.Evil.com./", rule: RuleDomain},
+		{url: "http://localhost:3000/", rule: RuleNetwork},
+		{url: "http://127.0.0.1/", rule: RuleNetwork},
+		{url: "http://[::ffff:192.168.0.1]/", rule: RuleNetwork},
+		{url: "http://intranet.example/", rule: RuleNetwork},
+	}
+	for _, tt := range tests {
+		t.Run(tt.url, func(t *testing.T) {
+			err := p.Check(context.Background(), tt.url)
+			if tt.rule == "" {
+				assert.NoError(t, err)
+				return
+			}
+			var blockedErr *model.DestinationBlockedError
+			require.ErrorAs(t, err, &blockedErr)
+			assert.Equal(t, tt.rule, blockedErr.Rule)
+		})
+	}
+}
+
+func TestDomainList_Reload(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "domains.txt")
+	require.NoError(t, os.WriteFile(path, []byte("a.com\n"), 0o644))
+	l, err := LoadDomainList(path, 10*time.Millisecond, nil)
+	require.NoError(t, err)
+	defer l.Close()
+	_, ok := l.Match("b.com")
+	assert.False(t, ok)
+
+	require.NoError(t, os.WriteFile(path, []byte("b.com\n"), 0o644))
+	require.NoError(t, os.Chtimes(path, time.Now(), time.Now().Add(time.Minute)))
+	assert.Eventually(t, func() bool {
+		_, ok := l.Match("b.com")
+		return ok
+	}, time.Second, 10*time.Millisecond)
+
+	require.NoError(t, os.Remove(path))
+	time.Sleep(50 * time.Millisecond)
+	_, ok = l.Match("b.com")
+	assert.True(t, ok, "failed reload keeps the previous list")
+}
+
+func TestBlocklist_Match(t *testing.T) {
+	sum := sha256.Sum256([]byte("files.example/downloads/"))
+	path := filepath.Join(t.TempDir(), "blocklist.txt")
+	list := "# hosts\nphish.example\n# url hashes\nsha256:" + hex.EncodeToString(sum[:]) + "\n"
+	require.NoError(t, os.WriteFile(path, []byte(list), 0o644))
+	b, err := LoadBlocklist(path, time.Hour, nil)
+	require.NoError(t, err)
+	defer b.Close()
+
+	tests := []struct {
+		url  string
+		want bool
+	}{
+		{url: "https://phish.example/", want: true},
+		{url: "https://login.phish.example/account", want: true},
+		{url: "https://files.example/downloads/setup.exe?v=1", want: true},
+		{url: "https://cdn.files.example/downloads/", want: true},
+		{url: "https://files.example/docs/", want: false},
+		{url: "https://example.com/", want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.url, func(t *testing.T) {
+			u, err := url.Parse(tt.url)
+			require.NoError(t, err)
+			_, ok := b.Match(u)
+			assert.Equal(t, tt.want, ok)
+		})
+	}
+
+	for _, line := range []string{"sha256:abc", "sha256:" + hex.EncodeToString(sum[:4])} {
+		require.NoError(t, os.WriteFile(path, []byte(line+"\n"), 0o644))
+		_, err = LoadBlocklist(path, time.Hour, nil)
+		assert.Error(t, err, line)
+	}
+}
@@ -2,6 +2,7 @@ package repository
 
 import (
 	"context"
+	"time"
 
 	"github.com/domurdoc/shortener/internal/model"
 )
@@ -12,9 +13,84 @@ type RecordRepo interface {
 	FetchForUser(context.Context, model.UserID) ([]model.BaseRecord, error)
 	StoreBatch(context.Context, []model.BaseRecord, model.UserID) error
 	Delete(context.Context, []model.UserRecord) (int, error)
+	FetchDetails(context.Context, model.ShortCode) (*model.RecordDetails, error)
+	ForceDelete(context.Context, model.ShortCode) error
+	Restore(context.Context, model.ShortCode) error
+	Stats(context.Context) (*model.RecordStats, error)
+	StoreForTeam(context.Context, *model.BaseRecord, model.TeamID) error
+	FetchForTeam(context.Context, model.TeamID) ([]model.BaseRecord, error)
+	// FetchPageForUser and FetchPageForTeam order links by creation time, a
+	// limit of 0 returns all links from offset on.
+	FetchPageForUser(ctx context.Context, userID model.UserID, limit, offset int) ([]model.BaseRecord, error)
+	FetchPageForTeam(ctx context.Context, teamID model.TeamID, limit, offset int) ([]model.BaseRecord, error)
+	CountForUser(context.Context, model.UserID) (int, error)
+	CountForTeam(context.Context, model.TeamID) (int, error)
+	DeleteForTeam(context.Context, model.TeamID, []model.ShortCode) (int, error)
+	List(ctx context.Context, limit, offset int) ([]model.RecordDetails, error)
+	Import(context.Context, []model.RecordDetails) error
+	ListForUser(ctx context.Context, userID model.UserID, limit, offset int) ([]model.RecordDetails, error)
+	ListForTeam(ctx context.Context, teamID model.TeamID, limit, offset int) ([]model.RecordDetails, error)
+	RecordClick(context.Context, model.ShortCode) error
+	// AddClicks adds counted clicks to the links, ignoring click limits and
+	// links that no longer exist.
+	AddClicks(context.Context, map[model.ShortCode]int64) error
+	NextShortCodeID(context.Context) (int64, error)
+	// SetPassword replaces the password hash of a link the user owns, an
+	// empty hash removes the password.
+	SetPassword(ctx context.Context, shortCode model.ShortCode, userID model.UserID, passwordHash string) error
+	// SetTeamPassword is SetPassword for links created for the team.
+	SetTeamPassword(ctx context.Context, shortCode model.ShortCode, teamID model.TeamID, passwordHash string) error
+	// SetCanonicalURL replaces the form a record is deduplicated on,
+	// *model.OriginalURLExistsError names the record already holding it.
+	SetCanonicalURL(ctx context.Context, shortCode model.ShortCode, canonicalURL model.OriginalURL) error
 }
 
 type UserRepo interface {
 	GetUser(context.Context, model.UserID) (*model.User, error)
 	CreateUser(context.Context) (*model.User, error)
+	ListUsers(ctx context.Context, limit, offset int) ([]model.User, error)
+	CountUsers(context.Context) (int, error)
+	SetUserRole(context.Context, model.UserID, model.Role) error
+	SetUserDisabled(context.Context, model.UserID, bool) error
+	StoreUser(context.Context, *model.User) error
+}
+
+type RefreshTokenRepo interface {
+	StoreRefreshToken(context.Context, *model.RefreshToken) error
+	// UseRefreshToken marks the token used. A token used less than grace ago
+	// is returned again, so parallel requests rotating it all succeed.
+	UseRefreshToken(ctx context.Context, hash string, grace time.Duration) (*model.RefreshToken, error)
+	RevokeRefreshTokenFamily(context.Context, string) error
+	ListRefreshTokens(ctx context.Context, limit, offset int) ([]model.RefreshToken, error)
+}
+
+type TeamRepo interface {
+	CreateTeam(context.Context, string, model.UserID) (*model.Team, error)
+	ListTeams(context.Context, model.UserID) ([]model.TeamMembership, error)
+	ListMembers(context.Context, model.TeamID) ([]model.TeamMember, error)
+	GetMember(context.Context, model.TeamID, model.UserID) (*model.TeamMember, error)
+	SetMember(context.Context, *model.TeamMember) error
+	RemoveMember(context.Context, model.TeamID, model.UserID) error
+	ListAllTeams(ctx context.Context, limit, offset int) ([]model.Team, error)
+	// StoreTeam creates or renames the team with the given ID.
+	StoreTeam(context.Context, *model.Team) error
+}
+
+type IdentityRepo interface {
+	FetchIdentity(ctx context.Context, issuer, subject string) (*model.Identity, error)
+	StoreIdentity(context.Context, *model.Identity) error
+	HasIdentity(context.Context, model.UserID) (bool, error)
+	ListIdentities(ctx context.Context, limit, offset int) ([]model.Identity, error)
+}
+
+type IdempotencyRepo interface {
+	FetchIdempotencyKey(ctx context.Context, userID model.UserID, key string) (*model.IdempotencyKey, error)
+	// ReserveIdempotencyKey stores the pending key unless a key that has not
+	// expired at now exists, and reports whether it did.
+	ReserveIdempotencyKey(ctx context.Context, pending *model.IdempotencyKey, now time.Time) (bool, error)
+	// StoreIdempotencyKey completes a pending key with the response.
+	StoreIdempotencyKey(context.Context, *model.IdempotencyKey) error
+	// ReleaseIdempotencyKey deletes a pending key so the request can be retried.
+	ReleaseIdempotencyKey(ctx context.Context, userID model.UserID, key string) error
+	DeleteExpiredIdempotencyKeys(context.Context, time.Time) (int, error)
 }
@@ -0,0 +1,146 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/domurdoc/shortener/internal/config/db"
+	"github.com/domurdoc/shortener/internal/model"
+)
+
+type DBIdempotencyRepo struct {
+	db       *sql.DB
+	newArger func() db.Arger
+}
+
+func NewDBIdempotencyRepo(db *sql.DB, newArger func() db.Arger) *DBIdempotencyRepo {
+	return &DBIdempotencyRepo{db, newArger}
+}
+
+const (
+	queryFetchIdempotencyKey = `
+SELECT request_hash, status_code, content_type, body, expires_at FROM idempotency_keys
+WHERE user_id = %s AND key = %s
+`
+	queryReserveIdempotencyKey = `
+INSERT INTO idempotency_keys (user_id, key, request_hash, status_code, content_type, body, expires_at)
+VALUES (%s, %s, %s, 0, '', '', %s)
+ON CONFLICT (user_id, key) DO UPDATE SET
+	request_hash = EXCLUDED.request_hash,
+	status_code = 0,
+	content_type = '',
+	body = '',
+	expires_at = EXCLUDED.expires_at
+WHERE idempotency_keys.expires_at <= %s
+`
+	queryStoreIdempotencyKey = `
+UPDATE idempotency_keys SET status_code = %s, content_type = %s, body = %s, expires_at = %s
+WHERE user_id = %s AND key = %s AND request_hash = %s AND status_code = 0
+`
+	queryReleaseIdempotencyKey = `
+DELETE FROM idempotency_keys WHERE user_id = %s AND key = %s AND status_code = 0
+`
+	queryDeleteExpiredIdempotencyKeys = `
+DELETE FROM idempotency_keys WHERE expires_at <= %s
+`
+)
+
+func (r *DBIdempotencyRepo) FetchIdempotencyKey(ctx context.Context, userID model.UserID, key string) (*model.IdempotencyKey, error) {
+	stored := model.IdempotencyKey{UserID: userID, Key: key}
+
+	arger := r.newArger()
+	query := fmt.Sprintf(queryFetchIdempotencyKey, arger.Next(), arger.Next())
+
+	row := r.db.QueryRowContext(ctx, query, userID, key)
+	err := row.Scan(
+		&stored.RequestHash,
+		&stored.StatusCode,
+		&stored.ContentType,
+		&stored.Body,
+		&stored.ExpiresAt,
+	)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, &model.IdempotencyKeyNotFoundError{Key: key}
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &stored, nil
+}
+
+func (r *DBIdempotencyRepo) ReserveIdempotencyKey(ctx context.Context, pending *model.IdempotencyKey, now time.Time) (bool, error) {
+	arger := r.newArger()
+	query := fmt.Sprintf(
+		queryReserveIdempotencyKey,
+		arger.Next(),
+		arger.Next(),
+		arger.Next(),
+		arger.Next(),
+		arger.Next(),
+	)
+
+	res, err := r.db.ExecContext(
+		ctx,
+		query,
+		pending.UserID,
+		pending.Key,
+		pending.RequestHash,
+		pending.ExpiresAt,
+		now,
+	)
+	if err != nil {
+		return false, err
+	}
+	count, err := res.RowsAffected()
+	return count == 1, err
+}
+
+func (r *DBIdempotencyRepo) StoreIdempotencyKey(ctx context.Context, stored *model.IdempotencyKey) error {
+	arger := r.newArger()
+	query := fmt.Sprintf(
+		queryStoreIdempotencyKey,
+		arger.Next(),
+		arger.Next(),
+		arger.Next(),
+		arger.Next(),
+		arger.Next(),
+		arger.Next(),
+		arger.Next(),
+	)
+
+	_, err := r.db.ExecContext(
+		ctx,
+		query,
+		stored.StatusCode,
+		stored.ContentType,
+		stored.Body,
+		stored.ExpiresAt,
+		stored.UserID,
+		stored.Key,
+		stored.RequestHash,
+	)
+	return err
+}
+
+func (r *DBIdempotencyRepo) ReleaseIdempotencyKey(ctx context.Context, userID model.UserID, key string) error {
+	arger := r.newArger()
+	query := fmt.Sprintf(queryReleaseIdempotencyKey, arger.Next(), arger.Next())
+
+	_, err := r.db.ExecContext(ctx, query, userID, key)
+	return err
+}
+
+func (r *DBIdempotencyRepo) DeleteExpiredIdempotencyKeys(ctx context.Context, now time.Time) (int, error) {
+	arger := r.newArger()
+	query := fmt.Sprintf(queryDeleteExpiredIdempotencyKeys, arger.Next())
+
+	res, err := r.db.ExecContext(ctx, query, now)
+	if err != nil {
+		return 0, err
+	}
+	count, err := res.RowsAffected()
+	return int(count), err
+}
@@ -0,0 +1,98 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/domurdoc/shortener/internal/config/db"
+	"github.com/domurdoc/shortener/internal/model"
+)
+
+type DBIdentityRepo struct {
+	db       *sql.DB
+	newArger func() db.Arger
+}
+
+func NewDBIdentityRepo(db *sql.DB, newArger func() db.Arger) *DBIdentityRepo {
+	return &DBIdentityRepo{db, newArger}
+}
+
+const (
+	queryFetchIdentity = `
+SELECT user_id FROM identities WHERE issuer = %s AND subject = %s
+`
+	queryInsertIdentity = `
+INSERT INTO identities (issuer, subject, user_id) VALUES (%s, %s, %s)
+`
+	queryHasIdentity = `
+SELECT EXISTS(SELECT 1 FROM identities WHERE user_id = %s)
+`
+	queryListIdentities = `
+SELECT issuer, subject, user_id FROM identities ORDER BY issuer, subject LIMIT %s OFFSET %s
+`
+)
+
+func (r *DBIdentityRepo) FetchIdentity(ctx context.Context, issuer, subject string) (*model.Identity, error) {
+	identity := model.Identity{Issuer: issuer, Subject: subject}
+
+	arger := r.newArger()
+	query := fmt.Sprintf(queryFetchIdentity, arger.Next(), arger.Next())
+
+	row := r.db.QueryRowContext(ctx, query, issuer, subject)
+	err := row.Scan(&identity.UserID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, &model.IdentityNotFoundError{Issuer: issuer, Subject: subject}
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &identity, nil
+}
+
+func (r *DBIdentityRepo) StoreIdentity(ctx context.Context, identity *model.Identity) error {
+	arger := r.newArger()
+	query := fmt.Sprintf(queryInsertIdentity, arger.Next(), arger.Next(), arger.Next())
+
+	_, err := r.db.ExecContext(ctx, query, identity.Issuer, identity.Subject, identity.UserID)
+	return err
+}
+
+func (r *DBIdentityRepo) HasIdentity(ctx context.Context, userID model.UserID) (bool, error) {
+	var exists bool
+
+	arger := r.newArger()
+	query := fmt.Sprintf(queryHasIdentity, arger.Next())
+
+	row := r.db.QueryRowContext(ctx, query, userID)
+	if err := row.Scan(&exists); err != nil {
+		return false, err
+	}
+	return exists, nil
+}
+
+func (r *DBIdentityRepo) ListIdentities(ctx context.Context, limit, offset int) ([]model.Identity, error) {
+	var identities []model.Identity
+
+	arger := r.newArger()
+	query := fmt.Sprintf(queryListIdentities, arger.Next(), arger.Next())
+
+	rows, err := r.db.QueryContext(ctx, query, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		identity := model.Identity{}
+		if err := rows.Scan(&identity.Issuer, &identity.Subject, &identity.UserID); err != nil {
+			return identities, err
+		}
+		identities = append(identities, identity)
+	}
+	if err := rows.Err(); err != nil {
+		return identities, err
+	}
+	return identities, nil
+}
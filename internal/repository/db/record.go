@@ -6,6 +6,9 @@ import (
 	"errors"
 	"fmt"
 	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgconn"
 
 	"github.com/domurdoc/shortener/internal/config/db"
 	"github.com/domurdoc/shortener/internal/model"
@@ -20,22 +23,70 @@ func NewDBRecordRepo(db *sql.DB, newArger func() db.Arger) *DBRecordRepo {
 	return &DBRecordRepo{db, newArger}
 }
 
+const pgerrUniqueViolation = "23505"
+
 const (
 	queryInsertRecord = `
-INSERT INTO records (key, value) VALUES (%s, %s)
-ON CONFLICT (value) DO UPDATE SET key = records.key
-RETURNING id, key
+INSERT INTO records (key, value, canonical, max_clicks, active_from, active_until, redirect_status, creator_id)
+VALUES (%s, %s, %s, %s, %s, %s, %s, %s)
+ON CONFLICT (canonical) DO UPDATE SET key = records.key
+RETURNING id, key, xmax = 0 AS inserted
+`
+	queryNextShortCodeID = `
+SELECT nextval('short_code_seq')
 `
 	queryInsertOwnership = `
 INSERT INTO ownership (user_id, record_id) VALUES (%s, %s)
 ON CONFLICT (user_id, record_id) DO NOTHING
 `
 	queryFetchRecord = `
-SELECT value, NOT EXISTS(SELECT 1 FROM ownership o WHERE o.record_id = r.id) AS is_deleted FROM records r WHERE key = %s
+SELECT value, password_hash, max_clicks, active_from, active_until, redirect_status, force_deleted OR NOT (
+	EXISTS(SELECT 1 FROM ownership o WHERE o.record_id = r.id) OR
+	EXISTS(SELECT 1 FROM team_ownership t WHERE t.record_id = r.id)
+) AS is_deleted FROM records r WHERE key = %s
+`
+	queryInsertTeamOwnership = `
+INSERT INTO team_ownership (team_id, record_id) VALUES (%s, %s)
+ON CONFLICT (team_id, record_id) DO NOTHING
+`
+	queryFetchForTeam = `
+SELECT key, value FROM records r JOIN team_ownership t ON r.id = t.record_id
+WHERE t.team_id = %s AND NOT r.force_deleted
+`
+	queryDeleteTeamOwnership = `
+DELETE FROM
+	team_ownership t
+USING
+	records r
+WHERE
+	r.id = t.record_id AND t.team_id = %s AND r.key IN (%s)
+`
+	queryFetchPageForTeam = `
+SELECT r.key, r.value FROM records r JOIN team_ownership t ON r.id = t.record_id
+WHERE t.team_id = %s AND NOT r.force_deleted
+ORDER BY r.created_at, r.id LIMIT %s OFFSET %s
+`
+	queryCountForTeam = `
+SELECT COUNT(*) FROM records r JOIN team_ownership t ON r.id = t.record_id
+WHERE t.team_id = %s AND NOT r.force_deleted
+`
+	queryFetchRecordTeams = `
+SELECT r.key, t.team_id FROM team_ownership t JOIN records r ON r.id = t.record_id
+WHERE r.key IN (%s)
+ORDER BY t.team_id
 `
 	queryFetchForUser = `
 SELECT key, value FROM records r JOIN ownership o ON r.id = o.record_id
-WHERE o.user_id = %s
+WHERE o.user_id = %s AND NOT r.force_deleted
+`
+	queryFetchPageForUser = `
+SELECT r.key, r.value FROM records r JOIN ownership o ON r.id = o.record_id
+WHERE o.user_id = %s AND NOT r.force_deleted
+ORDER BY r.created_at, r.id LIMIT %s OFFSET %s
+`
+	queryCountForUser = `
+SELECT COUNT(*) FROM records r JOIN ownership o ON r.id = o.record_id
+WHERE o.user_id = %s AND NOT r.force_deleted
 `
 	queryDeleteOwnership = `
 DELETE FROM
@@ -54,16 +105,119 @@ WHERE
 		WHERE
 			(o.user_id, r.key) IN (%s)
 	)
+`
+	queryFetchDetails = `
+SELECT r.key, r.value, r.canonical, r.password_hash, r.max_clicks, r.active_from, r.active_until, r.redirect_status, r.force_deleted, r.created_at, r.clicks, r.creator_id, o.user_id FROM records r LEFT JOIN ownership o ON r.id = o.record_id
+WHERE r.key IN (%s)
+ORDER BY o.user_id
+`
+	querySetForceDeleted = `
+UPDATE records SET force_deleted = %s WHERE key = %s
+`
+	queryListKeys = `
+SELECT key FROM records ORDER BY id LIMIT %s OFFSET %s
+`
+	queryListKeysForTeam = `
+SELECT r.key FROM records r JOIN team_ownership t ON r.id = t.record_id
+WHERE t.team_id = %s AND NOT r.force_deleted
+ORDER BY r.created_at, r.id LIMIT %s OFFSET %s
+`
+	queryListKeysForUser = `
+SELECT r.key FROM records r JOIN ownership o ON r.id = o.record_id
+WHERE o.user_id = %s AND NOT r.force_deleted
+ORDER BY r.created_at, r.id LIMIT %s OFFSET %s
+`
+	queryRecordClick = `
+UPDATE records SET clicks = clicks + 1
+WHERE key = %s AND (max_clicks = 0 OR clicks < max_clicks)
+RETURNING clicks
+`
+	queryAddClicks = `
+UPDATE records r SET clicks = r.clicks + v.clicks
+FROM (VALUES %s) AS v(key, clicks)
+WHERE r.key = v.key
+`
+	queryFetchMaxClicks = `
+SELECT max_clicks FROM records WHERE key = %s
+`
+	queryImportRecord = `
+INSERT INTO records (
+	key, value, canonical, password_hash, max_clicks, active_from, active_until, redirect_status, force_deleted, created_at, clicks, creator_id
+)
+VALUES (%s, %s, %s, %s, %s, %s, %s, %s, %s, %s, %s, %s)
+ON CONFLICT (key) DO UPDATE SET
+	password_hash = EXCLUDED.password_hash,
+	max_clicks = EXCLUDED.max_clicks,
+	active_from = EXCLUDED.active_from,
+	active_until = EXCLUDED.active_until,
+	redirect_status = EXCLUDED.redirect_status,
+	force_deleted = EXCLUDED.force_deleted,
+	created_at = EXCLUDED.created_at,
+	clicks = EXCLUDED.clicks,
+	creator_id = EXCLUDED.creator_id
+RETURNING id, value
+`
+	querySetCanonical = `
+UPDATE records SET canonical = %s WHERE key = %s
+`
+	queryFetchKeyByCanonical = `
+SELECT key FROM records WHERE canonical = %s
+`
+	querySetPassword = `
+UPDATE records r SET password_hash = %s
+FROM ownership o
+WHERE o.record_id = r.id AND o.user_id = %s AND r.creator_id = o.user_id AND r.key = %s AND NOT r.force_deleted
+`
+	querySetTeamPassword = `
+UPDATE records r SET password_hash = %s
+FROM team_ownership t
+WHERE t.record_id = r.id AND t.team_id = %s AND r.creator_id IS NULL AND r.key = %s AND NOT r.force_deleted
+`
+	queryImportTeamOwnership = `
+INSERT INTO team_ownership (team_id, record_id) VALUES (%s, %s)
+ON CONFLICT (team_id, record_id) DO NOTHING
+`
+	queryRecordStats = `
+SELECT
+	COUNT(*),
+	COUNT(*) FILTER (
+		WHERE NOT r.force_deleted AND (
+			EXISTS(SELECT 1 FROM ownership o WHERE o.record_id = r.id) OR
+			EXISTS(SELECT 1 FROM team_ownership t WHERE t.record_id = r.id)
+		)
+	)
+FROM
+	records r
 `
 )
 
 func (r *DBRecordRepo) Store(ctx context.Context, record *model.BaseRecord, userID model.UserID) error {
+	return r.store(ctx, record, queryInsertOwnership, userID, nullUserID(userID))
+}
+
+func (r *DBRecordRepo) StoreForTeam(ctx context.Context, record *model.BaseRecord, teamID model.TeamID) error {
+	return r.store(ctx, record, queryInsertTeamOwnership, teamID, sql.NullInt64{})
+}
+
+// store records creatorID only when it inserts the record, a deduplicated
+// record keeps its creator.
+func (r *DBRecordRepo) store(ctx context.Context, record *model.BaseRecord, ownershipQuery string, ownerID any, creatorID sql.NullInt64) error {
 	var arger db.Arger
 
 	arger = r.newArger()
-	insertRecordQuery := fmt.Sprintf(queryInsertRecord, arger.Next(), arger.Next())
+	insertRecordQuery := fmt.Sprintf(
+		queryInsertRecord,
+		arger.Next(),
+		arger.Next(),
+		arger.Next(),
+		arger.Next(),
+		arger.Next(),
+		arger.Next(),
+		arger.Next(),
+		arger.Next(),
+	)
 	arger = r.newArger()
-	insertOwnershipQuery := fmt.Sprintf(queryInsertOwnership, arger.Next(), arger.Next())
+	insertOwnershipQuery := fmt.Sprintf(ownershipQuery, arger.Next(), arger.Next())
 
 	tx, err := r.db.BeginTx(ctx, nil)
 	if err != nil {
@@ -76,23 +230,31 @@ func (r *DBRecordRepo) Store(ctx context.Context, record *model.BaseRecord, user
 		insertRecordQuery,
 		record.ShortCode,
 		record.OriginalURL,
+		record.DedupURL(),
+		record.MaxClicks,
+		nullTime(record.ActiveFrom),
+		nullTime(record.ActiveUntil),
+		record.RedirectStatus,
+		creatorID,
 	)
 
 	var recordID int
 	var shortCode model.ShortCode
+	var inserted bool
 
 	err = row.Scan(
 		&recordID,
 		&shortCode,
+		&inserted,
 	)
 	if err != nil {
-		return err
+		return shortCodeExistsError(err, record.ShortCode, 0)
 	}
 
 	_, err = tx.ExecContext(
 		ctx,
 		insertOwnershipQuery,
-		userID,
+		ownerID,
 		recordID,
 	)
 	if err != nil {
@@ -104,7 +266,7 @@ func (r *DBRecordRepo) Store(ctx context.Context, record *model.BaseRecord, user
 		return err
 	}
 
-	if shortCode != record.ShortCode {
+	if !inserted {
 		return &model.OriginalURLExistsError{
 			OriginalURL: record.OriginalURL,
 			ShortCode:   shortCode,
@@ -117,7 +279,17 @@ func (r *DBRecordRepo) StoreBatch(ctx context.Context, records []model.BaseRecor
 	var arger db.Arger
 
 	arger = r.newArger()
-	insertRecordQuery := fmt.Sprintf(queryInsertRecord, arger.Next(), arger.Next())
+	insertRecordQuery := fmt.Sprintf(
+		queryInsertRecord,
+		arger.Next(),
+		arger.Next(),
+		arger.Next(),
+		arger.Next(),
+		arger.Next(),
+		arger.Next(),
+		arger.Next(),
+		arger.Next(),
+	)
 	arger = r.newArger()
 	insertOwnershipQuery := fmt.Sprintf(queryInsertOwnership, arger.Next(), arger.Next())
 
@@ -145,15 +317,23 @@ func (r *DBRecordRepo) StoreBatch(ctx context.Context, records []model.BaseRecor
 			ctx,
 			record.ShortCode,
 			record.OriginalURL,
+			record.DedupURL(),
+			record.MaxClicks,
+			nullTime(record.ActiveFrom),
+			nullTime(record.ActiveUntil),
+			record.RedirectStatus,
+			nullUserID(userID),
 		)
 		var recordID int
 		var shortCode model.ShortCode
+		var inserted bool
 		err = row.Scan(
 			&recordID,
 			&shortCode,
+			&inserted,
 		)
 		if err != nil {
-			return err
+			return shortCodeExistsError(err, record.ShortCode, pos)
 		}
 		_, err = insertOwnershipStmt.ExecContext(ctx,
 			userID,
@@ -162,7 +342,7 @@ func (r *DBRecordRepo) StoreBatch(ctx context.Context, records []model.BaseRecor
 		if err != nil {
 			return err
 		}
-		if shortCode != record.ShortCode {
+		if !inserted {
 			valueErr := &model.OriginalURLExistsError{
 				OriginalURL: record.OriginalURL,
 				ShortCode:   shortCode,
@@ -182,8 +362,27 @@ func (r *DBRecordRepo) StoreBatch(ctx context.Context, records []model.BaseRecor
 	return nil
 }
 
+func (r *DBRecordRepo) NextShortCodeID(ctx context.Context) (int64, error) {
+	var id int64
+	if err := r.db.QueryRowContext(ctx, queryNextShortCodeID).Scan(&id); err != nil {
+		return 0, err
+	}
+	return id, nil
+}
+
+// shortCodeExistsError turns the unique key violation of a record insert into
+// *model.ShortCodeExistsError so the service can retry with another code.
+func shortCodeExistsError(err error, shortCode model.ShortCode, pos int) error {
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == pgerrUniqueViolation && pgErr.ConstraintName == "unique_records_key" {
+		return &model.ShortCodeExistsError{ShortCode: shortCode, BatchPos: pos}
+	}
+	return err
+}
+
 func (r *DBRecordRepo) Fetch(ctx context.Context, shortCode model.ShortCode) (*model.BaseRecord, error) {
 	record := model.BaseRecord{ShortCode: shortCode}
+	var activeFrom, activeUntil sql.NullTime
 	var isDeleted bool
 
 	arger := r.newArger()
@@ -197,6 +396,11 @@ func (r *DBRecordRepo) Fetch(ctx context.Context, shortCode model.ShortCode) (*m
 
 	err := row.Scan(
 		&record.OriginalURL,
+		&record.PasswordHash,
+		&record.MaxClicks,
+		&activeFrom,
+		&activeUntil,
+		&record.RedirectStatus,
 		&isDeleted,
 	)
 	if errors.Is(err, sql.ErrNoRows) {
@@ -208,16 +412,59 @@ func (r *DBRecordRepo) Fetch(ctx context.Context, shortCode model.ShortCode) (*m
 	if isDeleted {
 		return nil, &model.ShortCodeDeletedError{ShortCode: shortCode}
 	}
+	record.ActiveFrom, record.ActiveUntil = activeFrom.Time, activeUntil.Time
 	return &record, nil
 }
 
 func (r *DBRecordRepo) FetchForUser(ctx context.Context, userID model.UserID) ([]model.BaseRecord, error) {
-	var records []model.BaseRecord
+	arger := r.newArger()
+	query := fmt.Sprintf(queryFetchForUser, arger.Next())
+	return r.fetchRecords(ctx, query, userID)
+}
 
+func (r *DBRecordRepo) FetchForTeam(ctx context.Context, teamID model.TeamID) ([]model.BaseRecord, error) {
 	arger := r.newArger()
-	fetchForUserQuery := fmt.Sprintf(queryFetchForUser, arger.Next())
+	query := fmt.Sprintf(queryFetchForTeam, arger.Next())
+	return r.fetchRecords(ctx, query, teamID)
+}
+
+func (r *DBRecordRepo) FetchPageForUser(ctx context.Context, userID model.UserID, limit, offset int) ([]model.BaseRecord, error) {
+	arger := r.newArger()
+	query := fmt.Sprintf(queryFetchPageForUser, arger.Next(), arger.Next(), arger.Next())
+	return r.fetchRecords(ctx, query, userID, nullLimit(limit), offset)
+}
+
+func (r *DBRecordRepo) FetchPageForTeam(ctx context.Context, teamID model.TeamID, limit, offset int) ([]model.BaseRecord, error) {
+	arger := r.newArger()
+	query := fmt.Sprintf(queryFetchPageForTeam, arger.Next(), arger.Next(), arger.Next())
+	return r.fetchRecords(ctx, query, teamID, nullLimit(limit), offset)
+}
+
+func (r *DBRecordRepo) CountForUser(ctx context.Context, userID model.UserID) (int, error) {
+	arger := r.newArger()
+	query := fmt.Sprintf(queryCountForUser, arger.Next())
+	return r.count(ctx, query, userID)
+}
+
+func (r *DBRecordRepo) CountForTeam(ctx context.Context, teamID model.TeamID) (int, error) {
+	arger := r.newArger()
+	query := fmt.Sprintf(queryCountForTeam, arger.Next())
+	return r.count(ctx, query, teamID)
+}
+
+func (r *DBRecordRepo) count(ctx context.Context, query string, args ...any) (int, error) {
+	var count int
+	if err := r.db.QueryRowContext(ctx, query, args...).Scan(&count); err != nil {
+		return 0, err
+	}
+	return count, nil
+}
+
+// fetchRecords scans the key and value selected by query.
+func (r *DBRecordRepo) fetchRecords(ctx context.Context, query string, args ...any) ([]model.BaseRecord, error) {
+	var records []model.BaseRecord
 
-	rows, err := r.db.QueryContext(ctx, fetchForUserQuery, userID)
+	rows, err := r.db.QueryContext(ctx, query, args...)
 	if err != nil {
 		return nil, err
 	}
@@ -258,3 +505,402 @@ func (r *DBRecordRepo) Delete(ctx context.Context, records []model.UserRecord) (
 	count, err := res.RowsAffected()
 	return int(count), err
 }
+
+func (r *DBRecordRepo) FetchDetails(ctx context.Context, shortCode model.ShortCode) (*model.RecordDetails, error) {
+	details, err := r.fetchDetails(ctx, []model.ShortCode{shortCode})
+	if err != nil {
+		return nil, err
+	}
+	if len(details) == 0 {
+		return nil, &model.ShortCodeNotFoundError{ShortCode: shortCode}
+	}
+	return &details[0], nil
+}
+
+// fetchDetails joins records with their owners and teams in two queries, in
+// the order of shortCodes; missing short codes are left out.
+func (r *DBRecordRepo) fetchDetails(ctx context.Context, shortCodes []model.ShortCode) ([]model.RecordDetails, error) {
+	if len(shortCodes) == 0 {
+		return nil, nil
+	}
+	arger := r.newArger()
+	placeholders := make([]string, len(shortCodes))
+	args := make([]any, len(shortCodes))
+	for i, shortCode := range shortCodes {
+		placeholders[i] = arger.Next()
+		args[i] = shortCode
+	}
+	in := strings.Join(placeholders, ", ")
+
+	rows, err := r.db.QueryContext(ctx, fmt.Sprintf(queryFetchDetails, in), args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	byShortCode := make(map[model.ShortCode]*model.RecordDetails, len(shortCodes))
+	for rows.Next() {
+		var details model.RecordDetails
+		var creatorID, userID sql.NullInt64
+		var activeFrom, activeUntil sql.NullTime
+		if err := rows.Scan(
+			&details.ShortCode,
+			&details.OriginalURL,
+			&details.CanonicalURL,
+			&details.PasswordHash,
+			&details.MaxClicks,
+			&activeFrom,
+			&activeUntil,
+			&details.RedirectStatus,
+			&details.ForceDeleted,
+			&details.CreatedAt,
+			&details.Clicks,
+			&creatorID,
+			&userID,
+		); err != nil {
+			return nil, err
+		}
+		found, ok := byShortCode[details.ShortCode]
+		if !ok {
+			details.ActiveFrom, details.ActiveUntil = activeFrom.Time, activeUntil.Time
+			details.Creator = model.UserID(creatorID.Int64)
+			found = &details
+			byShortCode[details.ShortCode] = found
+		}
+		if userID.Valid {
+			found.Owners = append(found.Owners, model.UserID(userID.Int64))
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	teamRows, err := r.db.QueryContext(ctx, fmt.Sprintf(queryFetchRecordTeams, in), args...)
+	if err != nil {
+		return nil, err
+	}
+	defer teamRows.Close()
+
+	for teamRows.Next() {
+		var shortCode model.ShortCode
+		var teamID model.TeamID
+		if err := teamRows.Scan(&shortCode, &teamID); err != nil {
+			return nil, err
+		}
+		if details, ok := byShortCode[shortCode]; ok {
+			details.Teams = append(details.Teams, teamID)
+		}
+	}
+	if err := teamRows.Err(); err != nil {
+		return nil, err
+	}
+
+	records := make([]model.RecordDetails, 0, len(byShortCode))
+	for _, shortCode := range shortCodes {
+		details, ok := byShortCode[shortCode]
+		if !ok {
+			continue
+		}
+		details.IsDeleted = details.ForceDeleted || len(details.Owners) == 0 && len(details.Teams) == 0
+		records = append(records, *details)
+	}
+	return records, nil
+}
+
+func (r *DBRecordRepo) DeleteForTeam(ctx context.Context, teamID model.TeamID, shortCodes []model.ShortCode) (int, error) {
+	arger := r.newArger()
+
+	teamArg := arger.Next()
+	values := make([]string, 0, len(shortCodes))
+	args := make([]any, 0, len(shortCodes)+1)
+	args = append(args, teamID)
+
+	for _, shortCode := range shortCodes {
+		values = append(values, arger.Next())
+		args = append(args, shortCode)
+	}
+
+	deleteTeamOwnershipQuery := fmt.Sprintf(queryDeleteTeamOwnership, teamArg, strings.Join(values, ","))
+	res, err := r.db.ExecContext(ctx, deleteTeamOwnershipQuery, args...)
+	if err != nil {
+		return 0, err
+	}
+	count, err := res.RowsAffected()
+	return int(count), err
+}
+
+func (r *DBRecordRepo) ForceDelete(ctx context.Context, shortCode model.ShortCode) error {
+	return r.setForceDeleted(ctx, shortCode, true)
+}
+
+func (r *DBRecordRepo) Restore(ctx context.Context, shortCode model.ShortCode) error {
+	return r.setForceDeleted(ctx, shortCode, false)
+}
+
+func (r *DBRecordRepo) Stats(ctx context.Context) (*model.RecordStats, error) {
+	var stats model.RecordStats
+
+	row := r.db.QueryRowContext(ctx, queryRecordStats)
+	if err := row.Scan(&stats.Records, &stats.ActiveRecords); err != nil {
+		return nil, err
+	}
+	return &stats, nil
+}
+
+func (r *DBRecordRepo) setForceDeleted(ctx context.Context, shortCode model.ShortCode, forceDeleted bool) error {
+	arger := r.newArger()
+	query := fmt.Sprintf(querySetForceDeleted, arger.Next(), arger.Next())
+
+	res, err := r.db.ExecContext(ctx, query, forceDeleted, shortCode)
+	if err != nil {
+		return err
+	}
+	count, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if count == 0 {
+		return &model.ShortCodeNotFoundError{ShortCode: shortCode}
+	}
+	return nil
+}
+
+func (r *DBRecordRepo) List(ctx context.Context, limit, offset int) ([]model.RecordDetails, error) {
+	arger := r.newArger()
+	query := fmt.Sprintf(queryListKeys, arger.Next(), arger.Next())
+	return r.listDetails(ctx, query, limit, offset)
+}
+
+func (r *DBRecordRepo) ListForUser(ctx context.Context, userID model.UserID, limit, offset int) ([]model.RecordDetails, error) {
+	arger := r.newArger()
+	query := fmt.Sprintf(queryListKeysForUser, arger.Next(), arger.Next(), arger.Next())
+	return r.listDetails(ctx, query, userID, nullLimit(limit), offset)
+}
+
+func (r *DBRecordRepo) ListForTeam(ctx context.Context, teamID model.TeamID, limit, offset int) ([]model.RecordDetails, error) {
+	arger := r.newArger()
+	query := fmt.Sprintf(queryListKeysForTeam, arger.Next(), arger.Next(), arger.Next())
+	return r.listDetails(ctx, query, teamID, nullLimit(limit), offset)
+}
+
+// listDetails fetches details for the keys selected by query.
+func (r *DBRecordRepo) listDetails(ctx context.Context, query string, args ...any) ([]model.RecordDetails, error) {
+	var shortCodes []model.ShortCode
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var shortCode model.ShortCode
+		if err := rows.Scan(&shortCode); err != nil {
+			return nil, err
+		}
+		shortCodes = append(shortCodes, shortCode)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return r.fetchDetails(ctx, shortCodes)
+}
+
+// Import fails with *model.TeamNotFoundError for team ownership of a team
+// missing in the database, teams have to be imported first.
+func (r *DBRecordRepo) Import(ctx context.Context, records []model.RecordDetails) error {
+	var arger db.Arger
+
+	arger = r.newArger()
+	importRecordQuery := fmt.Sprintf(
+		queryImportRecord,
+		arger.Next(),
+		arger.Next(),
+		arger.Next(),
+		arger.Next(),
+		arger.Next(),
+		arger.Next(),
+		arger.Next(),
+		arger.Next(),
+		arger.Next(),
+		arger.Next(),
+		arger.Next(),
+		arger.Next(),
+	)
+	arger = r.newArger()
+	insertOwnershipQuery := fmt.Sprintf(queryInsertOwnership, arger.Next(), arger.Next())
+	arger = r.newArger()
+	importTeamOwnershipQuery := fmt.Sprintf(queryImportTeamOwnership, arger.Next(), arger.Next())
+	arger = r.newArger()
+	teamExistsQuery := fmt.Sprintf(queryTeamExists, arger.Next())
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	for _, record := range records {
+		var recordID int
+		var originalURL model.OriginalURL
+		createdAt := record.CreatedAt
+		if createdAt.IsZero() {
+			createdAt = time.Now()
+		}
+		row := tx.QueryRowContext(
+			ctx,
+			importRecordQuery,
+			record.ShortCode,
+			record.OriginalURL,
+			record.DedupURL(),
+			record.PasswordHash,
+			record.MaxClicks,
+			nullTime(record.ActiveFrom),
+			nullTime(record.ActiveUntil),
+			record.RedirectStatus,
+			record.ForceDeleted,
+			createdAt,
+			record.Clicks,
+			nullUserID(record.Creator),
+		)
+		if err := row.Scan(&recordID, &originalURL); err != nil {
+			return err
+		}
+		if originalURL != record.OriginalURL {
+			return &model.ShortCodeExistsError{ShortCode: record.ShortCode}
+		}
+		for _, userID := range record.Owners {
+			if _, err := tx.ExecContext(ctx, insertOwnershipQuery, userID, recordID); err != nil {
+				return err
+			}
+		}
+		for _, teamID := range record.Teams {
+			var exists bool
+			if err := tx.QueryRowContext(ctx, teamExistsQuery, teamID).Scan(&exists); err != nil {
+				return err
+			}
+			if !exists {
+				return &model.TeamNotFoundError{TeamID: teamID}
+			}
+			if _, err := tx.ExecContext(ctx, importTeamOwnershipQuery, teamID, recordID); err != nil {
+				return err
+			}
+		}
+	}
+	return tx.Commit()
+}
+
+// RecordClick counts the click only while the link is under its limit, the
+// conditional update keeps concurrent redirects from overshooting it.
+func (r *DBRecordRepo) RecordClick(ctx context.Context, shortCode model.ShortCode) error {
+	arger := r.newArger()
+	query := fmt.Sprintf(queryRecordClick, arger.Next())
+
+	var clicks int64
+	err := r.db.QueryRowContext(ctx, query, shortCode).Scan(&clicks)
+	if !errors.Is(err, sql.ErrNoRows) {
+		return err
+	}
+
+	arger = r.newArger()
+	query = fmt.Sprintf(queryFetchMaxClicks, arger.Next())
+
+	var maxClicks int64
+	err = r.db.QueryRowContext(ctx, query, shortCode).Scan(&maxClicks)
+	if errors.Is(err, sql.ErrNoRows) {
+		return &model.ShortCodeNotFoundError{ShortCode: shortCode}
+	}
+	if err != nil {
+		return err
+	}
+	return &model.ClickLimitReachedError{ShortCode: shortCode, MaxClicks: maxClicks}
+}
+
+// AddClicks updates all links in one statement.
+func (r *DBRecordRepo) AddClicks(ctx context.Context, clicks map[model.ShortCode]int64) error {
+	if len(clicks) == 0 {
+		return nil
+	}
+	arger := r.newArger()
+
+	values := make([]string, 0, len(clicks))
+	args := make([]any, 0, 2*len(clicks))
+
+	for shortCode, n := range clicks {
+		values = append(values, fmt.Sprintf("(%s, %s::bigint)", arger.Next(), arger.Next()))
+		args = append(args, shortCode, n)
+	}
+
+	_, err := r.db.ExecContext(ctx, fmt.Sprintf(queryAddClicks, strings.Join(values, ",")), args...)
+	return err
+}
+
+func (r *DBRecordRepo) SetPassword(ctx context.Context, shortCode model.ShortCode, userID model.UserID, passwordHash string) error {
+	return r.setPassword(ctx, querySetPassword, shortCode, userID, passwordHash)
+}
+
+func (r *DBRecordRepo) SetTeamPassword(ctx context.Context, shortCode model.ShortCode, teamID model.TeamID, passwordHash string) error {
+	return r.setPassword(ctx, querySetTeamPassword, shortCode, teamID, passwordHash)
+}
+
+// setPassword runs query with the owner restriction on ownerID.
+func (r *DBRecordRepo) setPassword(ctx context.Context, query string, shortCode model.ShortCode, ownerID any, passwordHash string) error {
+	arger := r.newArger()
+	query = fmt.Sprintf(query, arger.Next(), arger.Next(), arger.Next())
+
+	res, err := r.db.ExecContext(ctx, query, passwordHash, ownerID, shortCode)
+	if err != nil {
+		return err
+	}
+	count, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if count == 0 {
+		return &model.ShortCodeNotFoundError{ShortCode: shortCode}
+	}
+	return nil
+}
+
+func (r *DBRecordRepo) SetCanonicalURL(ctx context.Context, shortCode model.ShortCode, canonicalURL model.OriginalURL) error {
+	arger := r.newArger()
+	query := fmt.Sprintf(querySetCanonical, arger.Next(), arger.Next())
+
+	res, err := r.db.ExecContext(ctx, query, canonicalURL, shortCode)
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == pgerrUniqueViolation && pgErr.ConstraintName == "unique_records_canonical" {
+		existsErr := &model.OriginalURLExistsError{OriginalURL: canonicalURL}
+		arger = r.newArger()
+		query = fmt.Sprintf(queryFetchKeyByCanonical, arger.Next())
+		if err := r.db.QueryRowContext(ctx, query, canonicalURL).Scan(&existsErr.ShortCode); err != nil {
+			return err
+		}
+		return existsErr
+	}
+	if err != nil {
+		return err
+	}
+	count, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if count == 0 {
+		return &model.ShortCodeNotFoundError{ShortCode: shortCode}
+	}
+	return nil
+}
+
+// nullLimit turns a limit of 0 into LIMIT NULL, i.e. no limit.
+func nullLimit(limit int) sql.NullInt64 {
+	return sql.NullInt64{Int64: int64(limit), Valid: limit > 0}
+}
+
+// nullUserID stores no user as NULL.
+func nullUserID(userID model.UserID) sql.NullInt64 {
+	return sql.NullInt64{Int64: int64(userID), Valid: userID != 0}
+}
+
+// nullTime stores the zero time as NULL.
+func nullTime(t time.Time) sql.NullTime {
+	return sql.NullTime{Time: t, Valid: !t.IsZero()}
+}
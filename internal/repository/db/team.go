@@ -0,0 +1,256 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/domurdoc/shortener/internal/config/db"
+	"github.com/domurdoc/shortener/internal/model"
+)
+
+type DBTeamRepo struct {
+	db       *sql.DB
+	newArger func() db.Arger
+}
+
+func NewDBTeamRepo(db *sql.DB, newArger func() db.Arger) *DBTeamRepo {
+	return &DBTeamRepo{db, newArger}
+}
+
+const (
+	queryCreateTeam = `
+INSERT INTO teams (name) VALUES (%s) RETURNING id
+`
+	queryListTeams = `
+SELECT t.id, t.name, m.role FROM teams t JOIN team_members m ON t.id = m.team_id
+WHERE m.user_id = %s
+ORDER BY t.id
+`
+	queryTeamExists = `
+SELECT EXISTS(SELECT 1 FROM teams WHERE id = %s)
+`
+	queryListMembers = `
+SELECT user_id, role FROM team_members WHERE team_id = %s ORDER BY user_id
+`
+	queryGetMember = `
+SELECT role FROM team_members WHERE team_id = %s AND user_id = %s
+`
+	querySetMember = `
+INSERT INTO team_members (team_id, user_id, role) VALUES (%s, %s, %s)
+ON CONFLICT (team_id, user_id) DO UPDATE SET role = EXCLUDED.role
+`
+	queryRemoveMember = `
+DELETE FROM team_members WHERE team_id = %s AND user_id = %s
+`
+	queryListAllTeams = `
+SELECT id, name FROM teams ORDER BY id LIMIT %s OFFSET %s
+`
+	queryStoreTeam = `
+INSERT INTO teams (id, name) VALUES (%s, %s)
+ON CONFLICT (id) DO UPDATE SET name = EXCLUDED.name
+`
+	queryResetTeamSequence = `
+SELECT setval(pg_get_serial_sequence('teams', 'id'), (SELECT MAX(id) FROM teams))
+`
+)
+
+func (r *DBTeamRepo) CreateTeam(ctx context.Context, name string, ownerID model.UserID) (*model.Team, error) {
+	team := model.Team{Name: name}
+
+	arger := r.newArger()
+	createTeamQuery := fmt.Sprintf(queryCreateTeam, arger.Next())
+	arger = r.newArger()
+	setMemberQuery := fmt.Sprintf(querySetMember, arger.Next(), arger.Next(), arger.Next())
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	row := tx.QueryRowContext(ctx, createTeamQuery, name)
+	if err := row.Scan(&team.ID); err != nil {
+		return nil, err
+	}
+	_, err = tx.ExecContext(ctx, setMemberQuery, team.ID, ownerID, model.TeamRoleOwner)
+	if err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return &team, nil
+}
+
+func (r *DBTeamRepo) ListTeams(ctx context.Context, userID model.UserID) ([]model.TeamMembership, error) {
+	var memberships []model.TeamMembership
+
+	arger := r.newArger()
+	query := fmt.Sprintf(queryListTeams, arger.Next())
+
+	rows, err := r.db.QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		membership := model.TeamMembership{}
+		if err := rows.Scan(
+			&membership.ID,
+			&membership.Name,
+			&membership.Role,
+		); err != nil {
+			return memberships, err
+		}
+		memberships = append(memberships, membership)
+	}
+	if err := rows.Err(); err != nil {
+		return memberships, err
+	}
+	return memberships, nil
+}
+
+func (r *DBTeamRepo) ListMembers(ctx context.Context, teamID model.TeamID) ([]model.TeamMember, error) {
+	var members []model.TeamMember
+
+	if err := r.checkTeamExists(ctx, teamID); err != nil {
+		return nil, err
+	}
+
+	arger := r.newArger()
+	query := fmt.Sprintf(queryListMembers, arger.Next())
+
+	rows, err := r.db.QueryContext(ctx, query, teamID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		member := model.TeamMember{TeamID: teamID}
+		if err := rows.Scan(&member.UserID, &member.Role); err != nil {
+			return members, err
+		}
+		members = append(members, member)
+	}
+	if err := rows.Err(); err != nil {
+		return members, err
+	}
+	return members, nil
+}
+
+func (r *DBTeamRepo) GetMember(ctx context.Context, teamID model.TeamID, userID model.UserID) (*model.TeamMember, error) {
+	member := model.TeamMember{TeamID: teamID, UserID: userID}
+
+	arger := r.newArger()
+	query := fmt.Sprintf(queryGetMember, arger.Next(), arger.Next())
+
+	row := r.db.QueryRowContext(ctx, query, teamID, userID)
+	err := row.Scan(&member.Role)
+	if errors.Is(err, sql.ErrNoRows) {
+		if err := r.checkTeamExists(ctx, teamID); err != nil {
+			return nil, err
+		}
+		return nil, &model.TeamMemberNotFoundError{TeamID: teamID, UserID: userID}
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &member, nil
+}
+
+func (r *DBTeamRepo) SetMember(ctx context.Context, member *model.TeamMember) error {
+	if err := r.checkTeamExists(ctx, member.TeamID); err != nil {
+		return err
+	}
+
+	arger := r.newArger()
+	query := fmt.Sprintf(querySetMember, arger.Next(), arger.Next(), arger.Next())
+
+	_, err := r.db.ExecContext(ctx, query, member.TeamID, member.UserID, member.Role)
+	return err
+}
+
+func (r *DBTeamRepo) RemoveMember(ctx context.Context, teamID model.TeamID, userID model.UserID) error {
+	arger := r.newArger()
+	query := fmt.Sprintf(queryRemoveMember, arger.Next(), arger.Next())
+
+	res, err := r.db.ExecContext(ctx, query, teamID, userID)
+	if err != nil {
+		return err
+	}
+	count, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if count == 0 {
+		if err := r.checkTeamExists(ctx, teamID); err != nil {
+			return err
+		}
+		return &model.TeamMemberNotFoundError{TeamID: teamID, UserID: userID}
+	}
+	return nil
+}
+
+func (r *DBTeamRepo) ListAllTeams(ctx context.Context, limit, offset int) ([]model.Team, error) {
+	var teams []model.Team
+
+	arger := r.newArger()
+	query := fmt.Sprintf(queryListAllTeams, arger.Next(), arger.Next())
+
+	rows, err := r.db.QueryContext(ctx, query, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		team := model.Team{}
+		if err := rows.Scan(&team.ID, &team.Name); err != nil {
+			return teams, err
+		}
+		teams = append(teams, team)
+	}
+	if err := rows.Err(); err != nil {
+		return teams, err
+	}
+	return teams, nil
+}
+
+func (r *DBTeamRepo) StoreTeam(ctx context.Context, team *model.Team) error {
+	arger := r.newArger()
+	query := fmt.Sprintf(queryStoreTeam, arger.Next(), arger.Next())
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, query, team.ID, team.Name); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, queryResetTeamSequence); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (r *DBTeamRepo) checkTeamExists(ctx context.Context, teamID model.TeamID) error {
+	var exists bool
+
+	arger := r.newArger()
+	query := fmt.Sprintf(queryTeamExists, arger.Next())
+
+	row := r.db.QueryRowContext(ctx, query, teamID)
+	if err := row.Scan(&exists); err != nil {
+		return err
+	}
+	if !exists {
+		return &model.TeamNotFoundError{TeamID: teamID}
+	}
+	return nil
+}
@@ -0,0 +1,144 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/domurdoc/shortener/internal/config/db"
+	"github.com/domurdoc/shortener/internal/model"
+)
+
+type DBRefreshTokenRepo struct {
+	db       *sql.DB
+	newArger func() db.Arger
+}
+
+func NewDBRefreshTokenRepo(db *sql.DB, newArger func() db.Arger) *DBRefreshTokenRepo {
+	return &DBRefreshTokenRepo{db, newArger}
+}
+
+const (
+	queryInsertRefreshToken = `
+INSERT INTO refresh_tokens (hash, user_id, family, expires_at, used) VALUES (%s, %s, %s, %s, %s)
+ON CONFLICT (hash) DO UPDATE SET used = refresh_tokens.used OR EXCLUDED.used
+`
+	queryUseRefreshToken = `
+UPDATE refresh_tokens SET used = TRUE, used_at = COALESCE(used_at, %s)
+WHERE hash = %s AND (NOT used OR used_at > %s)
+RETURNING user_id, family, expires_at, used_at
+`
+	queryFetchRefreshTokenFamily = `
+SELECT family FROM refresh_tokens WHERE hash = %s
+`
+	queryRevokeRefreshTokenFamily = `
+DELETE FROM refresh_tokens WHERE family = %s
+`
+	queryListRefreshTokens = `
+SELECT hash, user_id, family, expires_at, used FROM refresh_tokens ORDER BY hash LIMIT %s OFFSET %s
+`
+)
+
+func (r *DBRefreshTokenRepo) StoreRefreshToken(ctx context.Context, token *model.RefreshToken) error {
+	arger := r.newArger()
+	query := fmt.Sprintf(
+		queryInsertRefreshToken,
+		arger.Next(),
+		arger.Next(),
+		arger.Next(),
+		arger.Next(),
+		arger.Next(),
+	)
+
+	_, err := r.db.ExecContext(
+		ctx,
+		query,
+		token.Hash,
+		token.UserID,
+		token.Family,
+		token.ExpiresAt,
+		token.Used,
+	)
+	return err
+}
+
+func (r *DBRefreshTokenRepo) UseRefreshToken(ctx context.Context, hash string, grace time.Duration) (*model.RefreshToken, error) {
+	token := model.RefreshToken{Hash: hash, Used: true}
+
+	arger := r.newArger()
+	query := fmt.Sprintf(queryUseRefreshToken, arger.Next(), arger.Next(), arger.Next())
+
+	now := time.Now()
+	row := r.db.QueryRowContext(ctx, query, now, hash, now.Add(-grace))
+	err := row.Scan(
+		&token.UserID,
+		&token.Family,
+		&token.ExpiresAt,
+		&token.UsedAt,
+	)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, r.usedOrNotFound(ctx, hash)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &token, nil
+}
+
+func (r *DBRefreshTokenRepo) RevokeRefreshTokenFamily(ctx context.Context, family string) error {
+	arger := r.newArger()
+	query := fmt.Sprintf(queryRevokeRefreshTokenFamily, arger.Next())
+
+	_, err := r.db.ExecContext(ctx, query, family)
+	return err
+}
+
+func (r *DBRefreshTokenRepo) ListRefreshTokens(ctx context.Context, limit, offset int) ([]model.RefreshToken, error) {
+	var tokens []model.RefreshToken
+
+	arger := r.newArger()
+	query := fmt.Sprintf(queryListRefreshTokens, arger.Next(), arger.Next())
+
+	rows, err := r.db.QueryContext(ctx, query, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		token := model.RefreshToken{}
+		if err := rows.Scan(
+			&token.Hash,
+			&token.UserID,
+			&token.Family,
+			&token.ExpiresAt,
+			&token.Used,
+		); err != nil {
+			return tokens, err
+		}
+		tokens = append(tokens, token)
+	}
+	if err := rows.Err(); err != nil {
+		return tokens, err
+	}
+	return tokens, nil
+}
+
+func (r *DBRefreshTokenRepo) usedOrNotFound(ctx context.Context, hash string) error {
+	var family string
+
+	arger := r.newArger()
+	query := fmt.Sprintf(queryFetchRefreshTokenFamily, arger.Next())
+
+	row := r.db.QueryRowContext(ctx, query, hash)
+	err := row.Scan(&family)
+	if errors.Is(err, sql.ErrNoRows) {
+		return &model.RefreshTokenNotFoundError{}
+	}
+	if err != nil {
+		return err
+	}
+	return &model.RefreshTokenReusedError{Family: family}
+}
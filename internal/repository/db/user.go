@@ -21,10 +21,29 @@ func NewDBUserRepo(db *sql.DB, newArger func() db.Arger) *DBUserRepo {
 
 const (
 	queryCreateUser = `
-INSERT INTO users DEFAULT VALUES RETURNING id
+INSERT INTO users DEFAULT VALUES RETURNING id, role, disabled
 `
 	queryGetUser = `
-SELECT id FROM users WHERE id = %s
+SELECT id, role, disabled FROM users WHERE id = %s
+`
+	queryListUsers = `
+SELECT id, role, disabled FROM users ORDER BY id LIMIT %s OFFSET %s
+`
+	queryCountUsers = `
+SELECT COUNT(*) FROM users
+`
+	querySetUserRole = `
+UPDATE users SET role = %s WHERE id = %s
+`
+	queryStoreUser = `
+INSERT INTO users (id, role, disabled) VALUES (%s, %s, %s)
+ON CONFLICT (id) DO UPDATE SET role = EXCLUDED.role, disabled = EXCLUDED.disabled
+`
+	queryResetUserSequence = `
+SELECT setval(pg_get_serial_sequence('users', 'id'), (SELECT MAX(id) FROM users))
+`
+	querySetUserDisabled = `
+UPDATE users SET disabled = %s WHERE id = %s
 `
 )
 
@@ -32,7 +51,7 @@ func (r *DBUserRepo) CreateUser(ctx context.Context) (*model.User, error) {
 	var user model.User
 
 	row := r.db.QueryRowContext(ctx, queryCreateUser)
-	if err := row.Scan(&user.ID); err != nil {
+	if err := row.Scan(&user.ID, &user.Role, &user.Disabled); err != nil {
 		return nil, err
 	}
 	return &user, nil
@@ -45,7 +64,7 @@ func (r *DBUserRepo) GetUser(ctx context.Context, userID model.UserID) (*model.U
 	query := fmt.Sprintf(queryGetUser, arger.Next())
 
 	row := r.db.QueryRowContext(ctx, query, userID)
-	err := row.Scan(&user.ID)
+	err := row.Scan(&user.ID, &user.Role, &user.Disabled)
 	if errors.Is(err, sql.ErrNoRows) {
 		return nil, &model.UserNotFoundError{UserID: userID}
 	}
@@ -54,3 +73,85 @@ func (r *DBUserRepo) GetUser(ctx context.Context, userID model.UserID) (*model.U
 	}
 	return &user, nil
 }
+
+func (r *DBUserRepo) ListUsers(ctx context.Context, limit, offset int) ([]model.User, error) {
+	var users []model.User
+
+	arger := r.newArger()
+	query := fmt.Sprintf(queryListUsers, arger.Next(), arger.Next())
+
+	rows, err := r.db.QueryContext(ctx, query, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		user := model.User{}
+		if err := rows.Scan(&user.ID, &user.Role, &user.Disabled); err != nil {
+			return users, err
+		}
+		users = append(users, user)
+	}
+	if err := rows.Err(); err != nil {
+		return users, err
+	}
+	return users, nil
+}
+
+func (r *DBUserRepo) CountUsers(ctx context.Context) (int, error) {
+	var count int
+
+	row := r.db.QueryRowContext(ctx, queryCountUsers)
+	if err := row.Scan(&count); err != nil {
+		return 0, err
+	}
+	return count, nil
+}
+
+func (r *DBUserRepo) SetUserRole(ctx context.Context, userID model.UserID, role model.Role) error {
+	arger := r.newArger()
+	query := fmt.Sprintf(querySetUserRole, arger.Next(), arger.Next())
+	return r.updateUser(ctx, userID, query, role, userID)
+}
+
+func (r *DBUserRepo) SetUserDisabled(ctx context.Context, userID model.UserID, disabled bool) error {
+	arger := r.newArger()
+	query := fmt.Sprintf(querySetUserDisabled, arger.Next(), arger.Next())
+	return r.updateUser(ctx, userID, query, disabled, userID)
+}
+
+func (r *DBUserRepo) updateUser(ctx context.Context, userID model.UserID, query string, args ...any) error {
+	res, err := r.db.ExecContext(ctx, query, args...)
+	if err != nil {
+		return err
+	}
+	count, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if count == 0 {
+		return &model.UserNotFoundError{UserID: userID}
+	}
+	return nil
+}
+
+// StoreUser keeps the user ID, so issued tokens stay valid after a migration.
+func (r *DBUserRepo) StoreUser(ctx context.Context, user *model.User) error {
+	arger := r.newArger()
+	query := fmt.Sprintf(queryStoreUser, arger.Next(), arger.Next(), arger.Next())
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, query, user.ID, user.Role, user.Disabled); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, queryResetUserSequence); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
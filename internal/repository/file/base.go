@@ -105,6 +105,212 @@ func (r *FileRepo) Delete(ctx context.Context, records []model.UserRecord) (int,
 	return count, err
 }
 
+func (r *FileRepo) FetchDetails(ctx context.Context, shortCode model.ShortCode) (*model.RecordDetails, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	memRepo, err := r.loadMemRepo(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return memRepo.FetchDetails(ctx, shortCode)
+}
+
+func (r *FileRepo) ForceDelete(ctx context.Context, shortCode model.ShortCode) error {
+	return r.update(ctx, func(memRepo *mem.MemRecordRepo) error {
+		return memRepo.ForceDelete(ctx, shortCode)
+	})
+}
+
+func (r *FileRepo) Restore(ctx context.Context, shortCode model.ShortCode) error {
+	return r.update(ctx, func(memRepo *mem.MemRecordRepo) error {
+		return memRepo.Restore(ctx, shortCode)
+	})
+}
+
+func (r *FileRepo) Stats(ctx context.Context) (*model.RecordStats, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	memRepo, err := r.loadMemRepo(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return memRepo.Stats(ctx)
+}
+
+func (r *FileRepo) StoreForTeam(ctx context.Context, record *model.BaseRecord, teamID model.TeamID) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	memRepo, err := r.loadMemRepo(ctx)
+	if err != nil {
+		return err
+	}
+	err = memRepo.StoreForTeam(ctx, record, teamID)
+	var urlErr *model.OriginalURLExistsError
+	if err != nil && !errors.As(err, &urlErr) {
+		return err
+	}
+	dumpErr := r.dumpMemRepo(memRepo)
+	if dumpErr != nil {
+		return dumpErr
+	}
+	return err
+}
+
+func (r *FileRepo) FetchForTeam(ctx context.Context, teamID model.TeamID) ([]model.BaseRecord, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	memRepo, err := r.loadMemRepo(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return memRepo.FetchForTeam(ctx, teamID)
+}
+
+func (r *FileRepo) DeleteForTeam(ctx context.Context, teamID model.TeamID, shortCodes []model.ShortCode) (int, error) {
+	var count int
+	err := r.update(ctx, func(memRepo *mem.MemRecordRepo) error {
+		var err error
+		count, err = memRepo.DeleteForTeam(ctx, teamID, shortCodes)
+		return err
+	})
+	if err != nil {
+		return 0, err
+	}
+	return count, nil
+}
+
+func (r *FileRepo) List(ctx context.Context, limit, offset int) ([]model.RecordDetails, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	memRepo, err := r.loadMemRepo(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return memRepo.List(ctx, limit, offset)
+}
+
+func (r *FileRepo) Import(ctx context.Context, records []model.RecordDetails) error {
+	return r.update(ctx, func(memRepo *mem.MemRecordRepo) error {
+		return memRepo.Import(ctx, records)
+	})
+}
+
+func (r *FileRepo) FetchPageForUser(ctx context.Context, userID model.UserID, limit, offset int) ([]model.BaseRecord, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	memRepo, err := r.loadMemRepo(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return memRepo.FetchPageForUser(ctx, userID, limit, offset)
+}
+
+func (r *FileRepo) FetchPageForTeam(ctx context.Context, teamID model.TeamID, limit, offset int) ([]model.BaseRecord, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	memRepo, err := r.loadMemRepo(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return memRepo.FetchPageForTeam(ctx, teamID, limit, offset)
+}
+
+func (r *FileRepo) CountForUser(ctx context.Context, userID model.UserID) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	memRepo, err := r.loadMemRepo(ctx)
+	if err != nil {
+		return 0, err
+	}
+	return memRepo.CountForUser(ctx, userID)
+}
+
+func (r *FileRepo) CountForTeam(ctx context.Context, teamID model.TeamID) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	memRepo, err := r.loadMemRepo(ctx)
+	if err != nil {
+		return 0, err
+	}
+	return memRepo.CountForTeam(ctx, teamID)
+}
+
+func (r *FileRepo) ListForUser(ctx context.Context, userID model.UserID, limit, offset int) ([]model.RecordDetails, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	memRepo, err := r.loadMemRepo(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return memRepo.ListForUser(ctx, userID, limit, offset)
+}
+
+func (r *FileRepo) ListForTeam(ctx context.Context, teamID model.TeamID, limit, offset int) ([]model.RecordDetails, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	memRepo, err := r.loadMemRepo(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return memRepo.ListForTeam(ctx, teamID, limit, offset)
+}
+
+func (r *FileRepo) RecordClick(ctx context.Context, shortCode model.ShortCode) error {
+	return r.update(ctx, func(memRepo *mem.MemRecordRepo) error {
+		return memRepo.RecordClick(ctx, shortCode)
+	})
+}
+
+func (r *FileRepo) AddClicks(ctx context.Context, clicks map[model.ShortCode]int64) error {
+	return r.update(ctx, func(memRepo *mem.MemRecordRepo) error {
+		return memRepo.AddClicks(ctx, clicks)
+	})
+}
+
+func (r *FileRepo) SetPassword(ctx context.Context, shortCode model.ShortCode, userID model.UserID, passwordHash string) error {
+	return r.update(ctx, func(memRepo *mem.MemRecordRepo) error {
+		return memRepo.SetPassword(ctx, shortCode, userID, passwordHash)
+	})
+}
+
+func (r *FileRepo) SetTeamPassword(ctx context.Context, shortCode model.ShortCode, teamID model.TeamID, passwordHash string) error {
+	return r.update(ctx, func(memRepo *mem.MemRecordRepo) error {
+		return memRepo.SetTeamPassword(ctx, shortCode, teamID, passwordHash)
+	})
+}
+
+func (r *FileRepo) SetCanonicalURL(ctx context.Context, shortCode model.ShortCode, canonicalURL model.OriginalURL) error {
+	return r.update(ctx, func(memRepo *mem.MemRecordRepo) error {
+		return memRepo.SetCanonicalURL(ctx, shortCode, canonicalURL)
+	})
+}
+
+func (r *FileRepo) NextShortCodeID(ctx context.Context) (int64, error) {
+	var id int64
+	err := r.update(ctx, func(memRepo *mem.MemRecordRepo) error {
+		var err error
+		id, err = memRepo.NextShortCodeID(ctx)
+		return err
+	})
+	if err != nil {
+		return 0, err
+	}
+	return id, nil
+}
+
+func (r *FileRepo) update(ctx context.Context, update func(*mem.MemRecordRepo) error) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	memRepo, err := r.loadMemRepo(ctx)
+	if err != nil {
+		return err
+	}
+	if err := update(memRepo); err != nil {
+		return err
+	}
+	return r.dumpMemRepo(memRepo)
+}
+
 func (r *FileRepo) loadMemRepo(_ context.Context) (*mem.MemRecordRepo, error) {
 	memRepo := mem.NewMemRecordRepo()
 
@@ -130,7 +336,7 @@ func (r *FileRepo) loadMemRepo(_ context.Context) (*mem.MemRecordRepo, error) {
 	shortCodeUserIDS := make(map[model.ShortCode]map[model.UserID]model.BaseRecord)
 	for _, record := range snapshot.Records {
 		shortCodeRecords[record.ShortCode] = record
-		originalURLRecords[record.OriginalURL] = record
+		originalURLRecords[record.DedupURL()] = record
 		shortCodeUserIDS[record.ShortCode] = make(map[model.UserID]model.BaseRecord)
 	}
 	userIDRecords := make(map[model.UserID]map[model.ShortCode]model.BaseRecord)
@@ -149,6 +355,38 @@ func (r *FileRepo) loadMemRepo(_ context.Context) (*mem.MemRecordRepo, error) {
 	memRepo.UserIDRecords = userIDRecords
 	memRepo.OriginalURLRecords = originalURLRecords
 	memRepo.ShortCodeUserIDS = shortCodeUserIDS
+	for _, shortCode := range snapshot.ForceDeleted {
+		memRepo.ForceDeleted[shortCode] = true
+	}
+	maps.Copy(memRepo.CreatedAt, snapshot.CreatedAt)
+	maps.Copy(memRepo.Clicks, snapshot.Clicks)
+	maps.Copy(memRepo.Creators, snapshot.Creators)
+	memRepo.Sequence = snapshot.Sequence
+	for _, ownership := range snapshot.TeamOwnership {
+		record, ok := shortCodeRecords[ownership.ShortCode]
+		if !ok {
+			return nil, fmt.Errorf("no matching ShortCode")
+		}
+		if _, ok = memRepo.TeamIDRecords[ownership.TeamID]; !ok {
+			memRepo.TeamIDRecords[ownership.TeamID] = make(map[model.ShortCode]model.BaseRecord)
+		}
+		if _, ok = memRepo.ShortCodeTeamIDS[ownership.ShortCode]; !ok {
+			memRepo.ShortCodeTeamIDS[ownership.ShortCode] = make(map[model.TeamID]model.BaseRecord)
+		}
+		memRepo.TeamIDRecords[ownership.TeamID][record.ShortCode] = record
+		memRepo.ShortCodeTeamIDS[ownership.ShortCode][ownership.TeamID] = record
+	}
+	// Files written before creators were kept: only a sole owner of a
+	// personal link is known to be its creator.
+	for shortCode, userIDs := range shortCodeUserIDS {
+		_, hasCreator := memRepo.Creators[shortCode]
+		if hasCreator || len(userIDs) != 1 || len(memRepo.ShortCodeTeamIDS[shortCode]) != 0 {
+			continue
+		}
+		for userID := range userIDs {
+			memRepo.Creators[shortCode] = userID
+		}
+	}
 
 	return memRepo, nil
 }
@@ -172,9 +410,26 @@ func (r *FileRepo) dumpMemRepo(memRepo *mem.MemRecordRepo) error {
 		}
 	}
 
+	var teamOwnership []serializer.TeamOwnership
+	for teamID, shortCodeRecords := range memRepo.TeamIDRecords {
+		for shortCode := range shortCodeRecords {
+			o := serializer.TeamOwnership{
+				TeamID:    teamID,
+				ShortCode: shortCode,
+			}
+			teamOwnership = append(teamOwnership, o)
+		}
+	}
+
 	snapshot := &serializer.Snapshot{
-		Records:   records,
-		Ownership: ownership,
+		Records:       records,
+		Ownership:     ownership,
+		ForceDeleted:  slices.Collect(maps.Keys(memRepo.ForceDeleted)),
+		TeamOwnership: teamOwnership,
+		CreatedAt:     memRepo.CreatedAt,
+		Clicks:        memRepo.Clicks,
+		Creators:      memRepo.Creators,
+		Sequence:      memRepo.Sequence,
 	}
 
 	content, err := r.serializer.Dump(snapshot)
@@ -0,0 +1,54 @@
+package file
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/domurdoc/shortener/internal/model"
+	"github.com/domurdoc/shortener/internal/repository/file/serializer"
+)
+
+func TestFileRepo_SetPassword(t *testing.T) {
+	ctx := context.TODO()
+
+	t.Run("Creator only", func(t *testing.T) {
+		repo, err := New(filepath.Join(t.TempDir(), "db.json"), serializer.NewJSONSerializer())
+		require.NoError(t, err)
+		require.NoError(t, repo.Store(ctx, &model.BaseRecord{ShortCode: "abc", OriginalURL: "http://example.com"}, 1))
+		var urlExistsErr *model.OriginalURLExistsError
+		require.ErrorAs(t, repo.Store(ctx, &model.BaseRecord{ShortCode: "xyz", OriginalURL: "http://example.com"}, 2), &urlExistsErr)
+
+		var notFoundErr *model.ShortCodeNotFoundError
+		assert.ErrorAs(t, repo.SetPassword(ctx, "abc", 2, "hash"), &notFoundErr)
+		require.NoError(t, repo.SetPassword(ctx, "abc", 1, "hash"))
+		stored, err := repo.Fetch(ctx, "abc")
+		require.NoError(t, err)
+		assert.Equal(t, "hash", stored.PasswordHash)
+	})
+
+	t.Run("Files without creators", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "db.json")
+		require.NoError(t, os.WriteFile(path, []byte(`{
+			"records": [
+				{"short_url": "one", "original_url": "http://one.com"},
+				{"short_url": "two", "original_url": "http://two.com"}
+			],
+			"ownership": [
+				{"user_id": 1, "short_url": "one"},
+				{"user_id": 1, "short_url": "two"},
+				{"user_id": 2, "short_url": "two"}
+			]
+		}`), 0666))
+		repo, err := New(path, serializer.NewJSONSerializer())
+		require.NoError(t, err)
+
+		require.NoError(t, repo.SetPassword(ctx, "one", 1, "hash"))
+		var notFoundErr *model.ShortCodeNotFoundError
+		assert.ErrorAs(t, repo.SetPassword(ctx, "two", 1, "hash"), &notFoundErr)
+	})
+}
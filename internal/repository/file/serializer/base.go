@@ -1,15 +1,30 @@
 package serializer
 
-import "github.com/domurdoc/shortener/internal/model"
+import (
+	"time"
+
+	"github.com/domurdoc/shortener/internal/model"
+)
 
 type Ownership struct {
 	UserID    model.UserID
 	ShortCode model.ShortCode
 }
 
+type TeamOwnership struct {
+	TeamID    model.TeamID
+	ShortCode model.ShortCode
+}
+
 type Snapshot struct {
-	Records   []model.BaseRecord
-	Ownership []Ownership
+	Records       []model.BaseRecord
+	Ownership     []Ownership
+	ForceDeleted  []model.ShortCode
+	TeamOwnership []TeamOwnership
+	CreatedAt     map[model.ShortCode]time.Time
+	Clicks        map[model.ShortCode]int64
+	Creators      map[model.ShortCode]model.UserID
+	Sequence      int64
 }
 
 type Serializer interface {
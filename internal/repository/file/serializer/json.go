@@ -2,13 +2,23 @@ package serializer
 
 import (
 	"encoding/json"
+	"time"
 
 	"github.com/domurdoc/shortener/internal/model"
 )
 
 type jsonRecord struct {
-	ShortURL    model.ShortCode   `json:"short_url"`
-	OriginalURL model.OriginalURL `json:"original_url"`
+	ShortURL       model.ShortCode   `json:"short_url"`
+	OriginalURL    model.OriginalURL `json:"original_url"`
+	CanonicalURL   model.OriginalURL `json:"canonical_url,omitempty"`
+	PasswordHash   string            `json:"password_hash,omitempty"`
+	MaxClicks      int64             `json:"max_clicks,omitempty"`
+	ActiveFrom     time.Time         `json:"active_from,omitzero"`
+	ActiveUntil    time.Time         `json:"active_until,omitzero"`
+	RedirectStatus int               `json:"redirect_status,omitempty"`
+	CreatedAt      *time.Time        `json:"created_at,omitempty"`
+	Clicks         int64             `json:"clicks,omitempty"`
+	CreatorID      model.UserID      `json:"creator_id,omitempty"`
 }
 
 type jsonOwnership struct {
@@ -16,29 +26,56 @@ type jsonOwnership struct {
 	ShortCode model.ShortCode `json:"short_url"`
 }
 
+type jsonTeamOwnership struct {
+	TeamID    model.TeamID    `json:"team_id"`
+	ShortCode model.ShortCode `json:"short_url"`
+}
+
 type jsonSnapshot struct {
-	Records   []jsonRecord    `json:"records"`
-	Ownership []jsonOwnership `json:"ownership"`
+	Records       []jsonRecord        `json:"records"`
+	Ownership     []jsonOwnership     `json:"ownership"`
+	ForceDeleted  []model.ShortCode   `json:"force_deleted,omitempty"`
+	TeamOwnership []jsonTeamOwnership `json:"team_ownership,omitempty"`
+	Sequence      int64               `json:"sequence,omitempty"`
 }
 
 func toJSONRecord(r model.BaseRecord) jsonRecord {
 	return jsonRecord{
-		ShortURL:    r.ShortCode,
-		OriginalURL: r.OriginalURL,
+		ShortURL:       r.ShortCode,
+		OriginalURL:    r.OriginalURL,
+		CanonicalURL:   r.CanonicalURL,
+		PasswordHash:   r.PasswordHash,
+		MaxClicks:      r.MaxClicks,
+		ActiveFrom:     r.ActiveFrom,
+		ActiveUntil:    r.ActiveUntil,
+		RedirectStatus: r.RedirectStatus,
 	}
 }
 
 func fromJSONRecord(jr jsonRecord) model.BaseRecord {
 	return model.BaseRecord{
-		ShortCode:   jr.ShortURL,
-		OriginalURL: jr.OriginalURL,
+		ShortCode:    jr.ShortURL,
+		OriginalURL:  jr.OriginalURL,
+		CanonicalURL: jr.CanonicalURL,
+		PasswordHash: jr.PasswordHash,
+		LinkOptions: model.LinkOptions{
+			MaxClicks:      jr.MaxClicks,
+			ActiveFrom:     jr.ActiveFrom,
+			ActiveUntil:    jr.ActiveUntil,
+			RedirectStatus: jr.RedirectStatus,
+		},
 	}
 }
 
 func toJSONSnapshot(r *Snapshot) jsonSnapshot {
 	jsonRecords := make([]jsonRecord, 0, len(r.Records))
-	for _, r := range r.Records {
-		jr := toJSONRecord(r)
+	for _, record := range r.Records {
+		jr := toJSONRecord(record)
+		if createdAt, ok := r.CreatedAt[record.ShortCode]; ok {
+			jr.CreatedAt = &createdAt
+		}
+		jr.Clicks = r.Clicks[record.ShortCode]
+		jr.CreatorID = r.Creators[record.ShortCode]
 		jsonRecords = append(jsonRecords, jr)
 	}
 
@@ -48,17 +85,37 @@ func toJSONSnapshot(r *Snapshot) jsonSnapshot {
 		jsonOwnerships = append(jsonOwnerships, jo)
 	}
 
+	jsonTeamOwnerships := make([]jsonTeamOwnership, 0, len(r.TeamOwnership))
+	for _, o := range r.TeamOwnership {
+		jsonTeamOwnerships = append(jsonTeamOwnerships, jsonTeamOwnership(o))
+	}
+
 	return jsonSnapshot{
-		Records:   jsonRecords,
-		Ownership: jsonOwnerships,
+		Records:       jsonRecords,
+		Ownership:     jsonOwnerships,
+		ForceDeleted:  r.ForceDeleted,
+		TeamOwnership: jsonTeamOwnerships,
+		Sequence:      r.Sequence,
 	}
 }
 
 func fromJSONSnapshot(js *jsonSnapshot) *Snapshot {
 	records := make([]model.BaseRecord, 0, len(js.Records))
+	createdAt := make(map[model.ShortCode]time.Time)
+	clicks := make(map[model.ShortCode]int64)
+	creators := make(map[model.ShortCode]model.UserID)
 	for _, jr := range js.Records {
 		r := fromJSONRecord(jr)
 		records = append(records, r)
+		if jr.CreatedAt != nil {
+			createdAt[r.ShortCode] = *jr.CreatedAt
+		}
+		if jr.Clicks != 0 {
+			clicks[r.ShortCode] = jr.Clicks
+		}
+		if jr.CreatorID != 0 {
+			creators[r.ShortCode] = jr.CreatorID
+		}
 	}
 
 	ownership := make([]Ownership, 0, len(js.Ownership))
@@ -67,9 +124,20 @@ func fromJSONSnapshot(js *jsonSnapshot) *Snapshot {
 		ownership = append(ownership, o)
 	}
 
+	teamOwnership := make([]TeamOwnership, 0, len(js.TeamOwnership))
+	for _, jo := range js.TeamOwnership {
+		teamOwnership = append(teamOwnership, TeamOwnership(jo))
+	}
+
 	return &Snapshot{
-		Records:   records,
-		Ownership: ownership,
+		Records:       records,
+		Ownership:     ownership,
+		ForceDeleted:  js.ForceDeleted,
+		TeamOwnership: teamOwnership,
+		CreatedAt:     createdAt,
+		Clicks:        clicks,
+		Creators:      creators,
+		Sequence:      js.Sequence,
 	}
 }
 
@@ -0,0 +1,77 @@
+package mem
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/domurdoc/shortener/internal/model"
+)
+
+type idempotencyKey struct {
+	userID model.UserID
+	key    string
+}
+
+type MemIdempotencyRepo struct {
+	storage map[idempotencyKey]model.IdempotencyKey
+	mu      sync.Mutex
+}
+
+func NewMemIdempotencyRepo() *MemIdempotencyRepo {
+	return &MemIdempotencyRepo{storage: make(map[idempotencyKey]model.IdempotencyKey)}
+}
+
+func (m *MemIdempotencyRepo) FetchIdempotencyKey(ctx context.Context, userID model.UserID, key string) (*model.IdempotencyKey, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	stored, ok := m.storage[idempotencyKey{userID, key}]
+	if !ok {
+		return nil, &model.IdempotencyKeyNotFoundError{Key: key}
+	}
+	return &stored, nil
+}
+
+func (m *MemIdempotencyRepo) ReserveIdempotencyKey(ctx context.Context, pending *model.IdempotencyKey, now time.Time) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	k := idempotencyKey{pending.UserID, pending.Key}
+	if stored, ok := m.storage[k]; ok && stored.ExpiresAt.After(now) {
+		return false, nil
+	}
+	m.storage[k] = *pending
+	return true, nil
+}
+
+func (m *MemIdempotencyRepo) StoreIdempotencyKey(ctx context.Context, stored *model.IdempotencyKey) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	k := idempotencyKey{stored.UserID, stored.Key}
+	if current, ok := m.storage[k]; ok && current.Pending() && current.RequestHash == stored.RequestHash {
+		m.storage[k] = *stored
+	}
+	return nil
+}
+
+func (m *MemIdempotencyRepo) ReleaseIdempotencyKey(ctx context.Context, userID model.UserID, key string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	k := idempotencyKey{userID, key}
+	if current, ok := m.storage[k]; ok && current.Pending() {
+		delete(m.storage, k)
+	}
+	return nil
+}
+
+func (m *MemIdempotencyRepo) DeleteExpiredIdempotencyKeys(ctx context.Context, now time.Time) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	count := 0
+	for k, stored := range m.storage {
+		if !stored.ExpiresAt.After(now) {
+			delete(m.storage, k)
+			count++
+		}
+	}
+	return count, nil
+}
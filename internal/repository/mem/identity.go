@@ -0,0 +1,71 @@
+package mem
+
+import (
+	"cmp"
+	"context"
+	"maps"
+	"slices"
+	"sync"
+
+	"github.com/domurdoc/shortener/internal/model"
+)
+
+type identityKey struct {
+	issuer  string
+	subject string
+}
+
+type MemIdentityRepo struct {
+	storage map[identityKey]model.Identity
+	users   map[model.UserID]bool
+	mu      sync.Mutex
+}
+
+func NewMemIdentityRepo() *MemIdentityRepo {
+	return &MemIdentityRepo{
+		storage: make(map[identityKey]model.Identity),
+		users:   make(map[model.UserID]bool),
+	}
+}
+
+func (m *MemIdentityRepo) FetchIdentity(ctx context.Context, issuer, subject string) (*model.Identity, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	identity, ok := m.storage[identityKey{issuer, subject}]
+	if !ok {
+		return nil, &model.IdentityNotFoundError{Issuer: issuer, Subject: subject}
+	}
+	return &identity, nil
+}
+
+func (m *MemIdentityRepo) StoreIdentity(ctx context.Context, identity *model.Identity) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.storage[identityKey{identity.Issuer, identity.Subject}] = *identity
+	m.users[identity.UserID] = true
+	return nil
+}
+
+func (m *MemIdentityRepo) HasIdentity(ctx context.Context, userID model.UserID) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.users[userID], nil
+}
+
+func (m *MemIdentityRepo) ListIdentities(ctx context.Context, limit, offset int) ([]model.Identity, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	keys := slices.SortedFunc(maps.Keys(m.storage), func(a, b identityKey) int {
+		return cmp.Or(cmp.Compare(a.issuer, b.issuer), cmp.Compare(a.subject, b.subject))
+	})
+	if offset >= len(keys) {
+		return nil, nil
+	}
+	keys = keys[offset:min(offset+limit, len(keys))]
+	identities := make([]model.Identity, 0, len(keys))
+	for _, key := range keys {
+		identities = append(identities, m.storage[key])
+	}
+	return identities, nil
+}
@@ -6,16 +6,26 @@ import (
 	"fmt"
 	"maps"
 	"slices"
+	"strings"
 	"sync"
+	"time"
 
 	"github.com/domurdoc/shortener/internal/model"
 )
 
 type MemRecordRepo struct {
-	ShortCodeRecords   map[model.ShortCode]model.BaseRecord
-	ShortCodeUserIDS   map[model.ShortCode]map[model.UserID]model.BaseRecord
-	UserIDRecords      map[model.UserID]map[model.ShortCode]model.BaseRecord
+	ShortCodeRecords map[model.ShortCode]model.BaseRecord
+	ShortCodeUserIDS map[model.ShortCode]map[model.UserID]model.BaseRecord
+	UserIDRecords    map[model.UserID]map[model.ShortCode]model.BaseRecord
+	// OriginalURLRecords is keyed by BaseRecord.DedupURL.
 	OriginalURLRecords map[model.OriginalURL]model.BaseRecord
+	ForceDeleted       map[model.ShortCode]bool
+	ShortCodeTeamIDS   map[model.ShortCode]map[model.TeamID]model.BaseRecord
+	TeamIDRecords      map[model.TeamID]map[model.ShortCode]model.BaseRecord
+	CreatedAt          map[model.ShortCode]time.Time
+	Clicks             map[model.ShortCode]int64
+	Creators           map[model.ShortCode]model.UserID
+	Sequence           int64
 	mu                 sync.Mutex
 }
 
@@ -25,6 +35,12 @@ func NewMemRecordRepo() *MemRecordRepo {
 		ShortCodeUserIDS:   make(map[model.ShortCode]map[model.UserID]model.BaseRecord),
 		UserIDRecords:      make(map[model.UserID]map[model.ShortCode]model.BaseRecord),
 		OriginalURLRecords: make(map[model.OriginalURL]model.BaseRecord),
+		ForceDeleted:       make(map[model.ShortCode]bool),
+		ShortCodeTeamIDS:   make(map[model.ShortCode]map[model.TeamID]model.BaseRecord),
+		TeamIDRecords:      make(map[model.TeamID]map[model.ShortCode]model.BaseRecord),
+		CreatedAt:          make(map[model.ShortCode]time.Time),
+		Clicks:             make(map[model.ShortCode]int64),
+		Creators:           make(map[model.ShortCode]model.UserID),
 	}
 }
 
@@ -38,21 +54,61 @@ func (r *MemRecordRepo) Store(ctx context.Context, record *model.BaseRecord, use
 }
 
 func (r *MemRecordRepo) StoreBatch(ctx context.Context, records []model.BaseRecord, userID model.UserID) error {
+	return r.storeBatch(records, func(record model.BaseRecord, created bool) {
+		if created {
+			r.Creators[record.ShortCode] = userID
+		}
+		if _, ok := r.UserIDRecords[userID]; !ok {
+			r.UserIDRecords[userID] = make(map[model.ShortCode]model.BaseRecord)
+		}
+		r.UserIDRecords[userID][record.ShortCode] = record
+		r.ShortCodeUserIDS[record.ShortCode][userID] = record
+	})
+}
+
+func (r *MemRecordRepo) StoreForTeam(ctx context.Context, record *model.BaseRecord, teamID model.TeamID) error {
+	err := r.storeBatch([]model.BaseRecord{*record}, func(record model.BaseRecord, _ bool) {
+		if _, ok := r.TeamIDRecords[teamID]; !ok {
+			r.TeamIDRecords[teamID] = make(map[model.ShortCode]model.BaseRecord)
+		}
+		if _, ok := r.ShortCodeTeamIDS[record.ShortCode]; !ok {
+			r.ShortCodeTeamIDS[record.ShortCode] = make(map[model.TeamID]model.BaseRecord)
+		}
+		r.TeamIDRecords[teamID][record.ShortCode] = record
+		r.ShortCodeTeamIDS[record.ShortCode][teamID] = record
+	})
+	var batchURLExistsErr model.BatchOriginalURLExistsError
+	if errors.As(err, &batchURLExistsErr) {
+		return batchURLExistsErr[0]
+	}
+	return err
+}
+
+// storeBatch calls own for every record, created tells the record is new
+// rather than deduplicated.
+func (r *MemRecordRepo) storeBatch(records []model.BaseRecord, own func(record model.BaseRecord, created bool)) error {
 	r.mu.Lock()
 	defer r.mu.Unlock()
-	for _, record := range records {
-		if _, exists := r.ShortCodeRecords[record.ShortCode]; exists {
-			return fmt.Errorf("ShortCode %s already exists", record.ShortCode)
+	batchURLs := make(map[model.ShortCode]model.OriginalURL, len(records))
+	for pos, record := range records {
+		originalURL, taken := batchURLs[record.ShortCode]
+		if existingRecord, exists := r.ShortCodeRecords[record.ShortCode]; exists {
+			originalURL, taken = existingRecord.DedupURL(), true
+		}
+		if taken && originalURL != record.DedupURL() {
+			return &model.ShortCodeExistsError{ShortCode: record.ShortCode, BatchPos: pos}
 		}
+		batchURLs[record.ShortCode] = record.DedupURL()
 	}
 	var batchURLExistsErr model.BatchOriginalURLExistsError
 	for pos, record := range records {
-		existingRecord, exists := r.OriginalURLRecords[record.OriginalURL]
+		existingRecord, exists := r.OriginalURLRecords[record.DedupURL()]
 		if !exists {
-			r.OriginalURLRecords[record.OriginalURL] = record
+			r.OriginalURLRecords[record.DedupURL()] = record
 			r.ShortCodeRecords[record.ShortCode] = record
 			r.ShortCodeUserIDS[record.ShortCode] = make(map[model.UserID]model.BaseRecord)
-		} else if existingRecord.ShortCode != record.ShortCode {
+			r.CreatedAt[record.ShortCode] = time.Now()
+		} else {
 			record.ShortCode = existingRecord.ShortCode
 			urlExistsErr := &model.OriginalURLExistsError{
 				OriginalURL: record.OriginalURL,
@@ -61,11 +117,7 @@ func (r *MemRecordRepo) StoreBatch(ctx context.Context, records []model.BaseReco
 			}
 			batchURLExistsErr = append(batchURLExistsErr, urlExistsErr)
 		}
-		if _, ok := r.UserIDRecords[userID]; !ok {
-			r.UserIDRecords[userID] = make(map[model.ShortCode]model.BaseRecord)
-		}
-		r.UserIDRecords[userID][record.ShortCode] = record
-		r.ShortCodeUserIDS[record.ShortCode][userID] = record
+		own(record, !exists)
 	}
 	if len(batchURLExistsErr) != 0 {
 		return batchURLExistsErr
@@ -73,6 +125,13 @@ func (r *MemRecordRepo) StoreBatch(ctx context.Context, records []model.BaseReco
 	return nil
 }
 
+func (r *MemRecordRepo) NextShortCodeID(ctx context.Context) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.Sequence++
+	return r.Sequence, nil
+}
+
 func (r *MemRecordRepo) Fetch(ctx context.Context, shortCode model.ShortCode) (*model.BaseRecord, error) {
 	r.mu.Lock()
 	defer r.mu.Unlock()
@@ -80,8 +139,7 @@ func (r *MemRecordRepo) Fetch(ctx context.Context, shortCode model.ShortCode) (*
 	if !exists {
 		return nil, &model.ShortCodeNotFoundError{ShortCode: shortCode}
 	}
-	userIDS := r.ShortCodeUserIDS[shortCode]
-	if len(userIDS) == 0 {
+	if r.isDeleted(shortCode) {
 		return nil, &model.ShortCodeDeletedError{ShortCode: shortCode}
 	}
 	return &record, nil
@@ -92,7 +150,10 @@ func (r *MemRecordRepo) FetchForUser(ctx context.Context, userID model.UserID) (
 	if !ok {
 		return nil, nil
 	}
-	return slices.Collect(maps.Values(originalURLRecords)), nil
+	records := slices.Collect(maps.Values(originalURLRecords))
+	return slices.DeleteFunc(records, func(record model.BaseRecord) bool {
+		return r.ForceDeleted[record.ShortCode]
+	}), nil
 }
 
 func (r *MemRecordRepo) Delete(ctx context.Context, records []model.UserRecord) (int, error) {
@@ -112,3 +173,309 @@ func (r *MemRecordRepo) Delete(ctx context.Context, records []model.UserRecord)
 	}
 	return counter, nil
 }
+
+func (r *MemRecordRepo) FetchDetails(ctx context.Context, shortCode model.ShortCode) (*model.RecordDetails, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	record, exists := r.ShortCodeRecords[shortCode]
+	if !exists {
+		return nil, &model.ShortCodeNotFoundError{ShortCode: shortCode}
+	}
+	return r.details(record), nil
+}
+
+func (r *MemRecordRepo) List(ctx context.Context, limit, offset int) ([]model.RecordDetails, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	shortCodes := slices.Sorted(maps.Keys(r.ShortCodeRecords))
+	if offset >= len(shortCodes) {
+		return nil, nil
+	}
+	shortCodes = shortCodes[offset:min(offset+limit, len(shortCodes))]
+	records := make([]model.RecordDetails, 0, len(shortCodes))
+	for _, shortCode := range shortCodes {
+		records = append(records, *r.details(r.ShortCodeRecords[shortCode]))
+	}
+	return records, nil
+}
+
+// Import stores records with their short codes, owners and takedown state as is.
+func (r *MemRecordRepo) Import(ctx context.Context, records []model.RecordDetails) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, record := range records {
+		if existing, ok := r.OriginalURLRecords[record.DedupURL()]; ok && existing.ShortCode != record.ShortCode {
+			return fmt.Errorf("url %s is already stored as %s", record.OriginalURL, existing.ShortCode)
+		}
+		if existing, ok := r.ShortCodeRecords[record.ShortCode]; ok && existing.DedupURL() != record.DedupURL() {
+			return &model.ShortCodeExistsError{ShortCode: record.ShortCode}
+		}
+	}
+	for _, record := range records {
+		base := record.BaseRecord
+		r.ShortCodeRecords[base.ShortCode] = base
+		r.OriginalURLRecords[base.DedupURL()] = base
+		if _, ok := r.ShortCodeUserIDS[base.ShortCode]; !ok {
+			r.ShortCodeUserIDS[base.ShortCode] = make(map[model.UserID]model.BaseRecord)
+		}
+		for _, userID := range record.Owners {
+			if _, ok := r.UserIDRecords[userID]; !ok {
+				r.UserIDRecords[userID] = make(map[model.ShortCode]model.BaseRecord)
+			}
+			r.UserIDRecords[userID][base.ShortCode] = base
+			r.ShortCodeUserIDS[base.ShortCode][userID] = base
+		}
+		for _, teamID := range record.Teams {
+			if _, ok := r.TeamIDRecords[teamID]; !ok {
+				r.TeamIDRecords[teamID] = make(map[model.ShortCode]model.BaseRecord)
+			}
+			if _, ok := r.ShortCodeTeamIDS[base.ShortCode]; !ok {
+				r.ShortCodeTeamIDS[base.ShortCode] = make(map[model.TeamID]model.BaseRecord)
+			}
+			r.TeamIDRecords[teamID][base.ShortCode] = base
+			r.ShortCodeTeamIDS[base.ShortCode][teamID] = base
+		}
+		if record.ForceDeleted {
+			r.ForceDeleted[base.ShortCode] = true
+		}
+		if record.CreatedAt.IsZero() {
+			record.CreatedAt = time.Now()
+		}
+		r.CreatedAt[base.ShortCode] = record.CreatedAt
+		r.Clicks[base.ShortCode] = record.Clicks
+		if record.Creator != 0 {
+			r.Creators[base.ShortCode] = record.Creator
+		}
+	}
+	return nil
+}
+
+// ListForUser orders records by creation time.
+func (r *MemRecordRepo) ListForUser(ctx context.Context, userID model.UserID, limit, offset int) ([]model.RecordDetails, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.listPage(r.UserIDRecords[userID], limit, offset), nil
+}
+
+func (r *MemRecordRepo) ListForTeam(ctx context.Context, teamID model.TeamID, limit, offset int) ([]model.RecordDetails, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.listPage(r.TeamIDRecords[teamID], limit, offset), nil
+}
+
+func (r *MemRecordRepo) listPage(owned map[model.ShortCode]model.BaseRecord, limit, offset int) []model.RecordDetails {
+	shortCodes := paginate(r.liveShortCodes(owned), limit, offset)
+	records := make([]model.RecordDetails, 0, len(shortCodes))
+	for _, shortCode := range shortCodes {
+		records = append(records, *r.details(r.ShortCodeRecords[shortCode]))
+	}
+	return records
+}
+
+func (r *MemRecordRepo) FetchPageForUser(ctx context.Context, userID model.UserID, limit, offset int) ([]model.BaseRecord, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.fetchPage(r.UserIDRecords[userID], limit, offset), nil
+}
+
+func (r *MemRecordRepo) FetchPageForTeam(ctx context.Context, teamID model.TeamID, limit, offset int) ([]model.BaseRecord, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.fetchPage(r.TeamIDRecords[teamID], limit, offset), nil
+}
+
+func (r *MemRecordRepo) CountForUser(ctx context.Context, userID model.UserID) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return len(r.liveShortCodes(r.UserIDRecords[userID])), nil
+}
+
+func (r *MemRecordRepo) CountForTeam(ctx context.Context, teamID model.TeamID) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return len(r.liveShortCodes(r.TeamIDRecords[teamID])), nil
+}
+
+func (r *MemRecordRepo) fetchPage(owned map[model.ShortCode]model.BaseRecord, limit, offset int) []model.BaseRecord {
+	shortCodes := paginate(r.liveShortCodes(owned), limit, offset)
+	records := make([]model.BaseRecord, 0, len(shortCodes))
+	for _, shortCode := range shortCodes {
+		records = append(records, r.ShortCodeRecords[shortCode])
+	}
+	return records
+}
+
+// liveShortCodes leaves out force deleted links and orders the rest by
+// creation time.
+func (r *MemRecordRepo) liveShortCodes(owned map[model.ShortCode]model.BaseRecord) []model.ShortCode {
+	shortCodes := slices.Collect(maps.Keys(owned))
+	shortCodes = slices.DeleteFunc(shortCodes, func(shortCode model.ShortCode) bool {
+		return r.ForceDeleted[shortCode]
+	})
+	slices.SortFunc(shortCodes, func(a, b model.ShortCode) int {
+		if c := r.CreatedAt[a].Compare(r.CreatedAt[b]); c != 0 {
+			return c
+		}
+		return strings.Compare(string(a), string(b))
+	})
+	return shortCodes
+}
+
+// paginate returns all items from offset on when limit is 0.
+func paginate[T any](items []T, limit, offset int) []T {
+	if offset >= len(items) {
+		return nil
+	}
+	items = items[offset:]
+	if limit > 0 && limit < len(items) {
+		items = items[:limit]
+	}
+	return items
+}
+
+func (r *MemRecordRepo) RecordClick(ctx context.Context, shortCode model.ShortCode) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	record, exists := r.ShortCodeRecords[shortCode]
+	if !exists {
+		return &model.ShortCodeNotFoundError{ShortCode: shortCode}
+	}
+	if record.MaxClicks > 0 && r.Clicks[shortCode] >= record.MaxClicks {
+		return &model.ClickLimitReachedError{ShortCode: shortCode, MaxClicks: record.MaxClicks}
+	}
+	r.Clicks[shortCode]++
+	return nil
+}
+
+func (r *MemRecordRepo) AddClicks(ctx context.Context, clicks map[model.ShortCode]int64) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for shortCode, n := range clicks {
+		if _, exists := r.ShortCodeRecords[shortCode]; exists {
+			r.Clicks[shortCode] += n
+		}
+	}
+	return nil
+}
+
+// SetPassword updates ShortCodeRecords only, the record copies kept for owners
+// are never read for passwords.
+func (r *MemRecordRepo) SetPassword(ctx context.Context, shortCode model.ShortCode, userID model.UserID, passwordHash string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	_, owned := r.UserIDRecords[userID][shortCode]
+	if !owned || r.Creators[shortCode] != userID || r.ForceDeleted[shortCode] {
+		return &model.ShortCodeNotFoundError{ShortCode: shortCode}
+	}
+	record := r.ShortCodeRecords[shortCode]
+	record.PasswordHash = passwordHash
+	r.ShortCodeRecords[shortCode] = record
+	return nil
+}
+
+// SetTeamPassword leaves links the team got by deduplication to their creator.
+func (r *MemRecordRepo) SetTeamPassword(ctx context.Context, shortCode model.ShortCode, teamID model.TeamID, passwordHash string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	_, owned := r.TeamIDRecords[teamID][shortCode]
+	if !owned || r.Creators[shortCode] != 0 || r.ForceDeleted[shortCode] {
+		return &model.ShortCodeNotFoundError{ShortCode: shortCode}
+	}
+	record := r.ShortCodeRecords[shortCode]
+	record.PasswordHash = passwordHash
+	r.ShortCodeRecords[shortCode] = record
+	return nil
+}
+
+func (r *MemRecordRepo) SetCanonicalURL(ctx context.Context, shortCode model.ShortCode, canonicalURL model.OriginalURL) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	record, ok := r.ShortCodeRecords[shortCode]
+	if !ok {
+		return &model.ShortCodeNotFoundError{ShortCode: shortCode}
+	}
+	if existing, ok := r.OriginalURLRecords[canonicalURL]; ok && existing.ShortCode != shortCode {
+		return &model.OriginalURLExistsError{OriginalURL: canonicalURL, ShortCode: existing.ShortCode}
+	}
+	delete(r.OriginalURLRecords, record.DedupURL())
+	record.CanonicalURL = canonicalURL
+	r.ShortCodeRecords[shortCode] = record
+	r.OriginalURLRecords[canonicalURL] = record
+	return nil
+}
+
+func (r *MemRecordRepo) details(record model.BaseRecord) *model.RecordDetails {
+	return &model.RecordDetails{
+		BaseRecord:   record,
+		Owners:       slices.Sorted(maps.Keys(r.ShortCodeUserIDS[record.ShortCode])),
+		Teams:        slices.Sorted(maps.Keys(r.ShortCodeTeamIDS[record.ShortCode])),
+		IsDeleted:    r.isDeleted(record.ShortCode),
+		ForceDeleted: r.ForceDeleted[record.ShortCode],
+		CreatedAt:    r.CreatedAt[record.ShortCode],
+		Clicks:       r.Clicks[record.ShortCode],
+		Creator:      r.Creators[record.ShortCode],
+	}
+}
+
+func (r *MemRecordRepo) ForceDelete(ctx context.Context, shortCode model.ShortCode) error {
+	return r.setForceDeleted(shortCode, true)
+}
+
+func (r *MemRecordRepo) Restore(ctx context.Context, shortCode model.ShortCode) error {
+	return r.setForceDeleted(shortCode, false)
+}
+
+func (r *MemRecordRepo) Stats(ctx context.Context) (*model.RecordStats, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	stats := model.RecordStats{Records: len(r.ShortCodeRecords)}
+	for shortCode := range r.ShortCodeRecords {
+		if !r.isDeleted(shortCode) {
+			stats.ActiveRecords++
+		}
+	}
+	return &stats, nil
+}
+
+func (r *MemRecordRepo) setForceDeleted(shortCode model.ShortCode, forceDeleted bool) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, exists := r.ShortCodeRecords[shortCode]; !exists {
+		return &model.ShortCodeNotFoundError{ShortCode: shortCode}
+	}
+	if forceDeleted {
+		r.ForceDeleted[shortCode] = true
+	} else {
+		delete(r.ForceDeleted, shortCode)
+	}
+	return nil
+}
+
+func (r *MemRecordRepo) FetchForTeam(ctx context.Context, teamID model.TeamID) ([]model.BaseRecord, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	records := slices.Collect(maps.Values(r.TeamIDRecords[teamID]))
+	return slices.DeleteFunc(records, func(record model.BaseRecord) bool {
+		return r.ForceDeleted[record.ShortCode]
+	}), nil
+}
+
+func (r *MemRecordRepo) DeleteForTeam(ctx context.Context, teamID model.TeamID, shortCodes []model.ShortCode) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	counter := 0
+	for _, shortCode := range shortCodes {
+		if _, ok := r.TeamIDRecords[teamID][shortCode]; !ok {
+			continue
+		}
+		counter++
+		delete(r.TeamIDRecords[teamID], shortCode)
+		delete(r.ShortCodeTeamIDS[shortCode], teamID)
+	}
+	return counter, nil
+}
+
+func (r *MemRecordRepo) isDeleted(shortCode model.ShortCode) bool {
+	return r.ForceDeleted[shortCode] ||
+		len(r.ShortCodeUserIDS[shortCode]) == 0 && len(r.ShortCodeTeamIDS[shortCode]) == 0
+}
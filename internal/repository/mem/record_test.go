@@ -0,0 +1,55 @@
+package mem
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/domurdoc/shortener/internal/model"
+)
+
+func TestMemRecordRepo_SetPassword(t *testing.T) {
+	ctx := context.TODO()
+	repo := NewMemRecordRepo()
+	record := &model.BaseRecord{ShortCode: "abc", OriginalURL: "http://example.com"}
+	require.NoError(t, repo.Store(ctx, record, 1))
+	// the second user shortens the same URL and becomes a co-owner
+	var urlExistsErr *model.OriginalURLExistsError
+	require.ErrorAs(t, repo.Store(ctx, &model.BaseRecord{ShortCode: "xyz", OriginalURL: "http://example.com"}, 2), &urlExistsErr)
+	require.NoError(t, repo.StoreForTeam(ctx, &model.BaseRecord{ShortCode: "team", OriginalURL: "http://team.com"}, 1))
+
+	var notFoundErr *model.ShortCodeNotFoundError
+	assert.ErrorAs(t, repo.SetPassword(ctx, "abc", 2, "hash"), &notFoundErr)
+	assert.ErrorAs(t, repo.SetPassword(ctx, "abc", 3, "hash"), &notFoundErr)
+	assert.ErrorAs(t, repo.SetPassword(ctx, "team", 1, "hash"), &notFoundErr)
+	require.NoError(t, repo.SetPassword(ctx, "abc", 1, "hash"))
+
+	stored, err := repo.Fetch(ctx, "abc")
+	require.NoError(t, err)
+	assert.Equal(t, "hash", stored.PasswordHash)
+	details, err := repo.FetchDetails(ctx, "abc")
+	require.NoError(t, err)
+	assert.Equal(t, model.UserID(1), details.Creator)
+	assert.Equal(t, []model.UserID{1, 2}, details.Owners)
+}
+
+func TestMemRecordRepo_SetTeamPassword(t *testing.T) {
+	ctx := context.TODO()
+	repo := NewMemRecordRepo()
+	require.NoError(t, repo.StoreForTeam(ctx, &model.BaseRecord{ShortCode: "team", OriginalURL: "http://team.com"}, 1))
+	require.NoError(t, repo.Store(ctx, &model.BaseRecord{ShortCode: "abc", OriginalURL: "http://example.com"}, 1))
+	// the team shortens a URL a user shortened first and becomes a co-owner
+	var urlExistsErr *model.OriginalURLExistsError
+	require.ErrorAs(t, repo.StoreForTeam(ctx, &model.BaseRecord{ShortCode: "xyz", OriginalURL: "http://example.com"}, 1), &urlExistsErr)
+
+	var notFoundErr *model.ShortCodeNotFoundError
+	assert.ErrorAs(t, repo.SetTeamPassword(ctx, "abc", 1, "hash"), &notFoundErr)
+	assert.ErrorAs(t, repo.SetTeamPassword(ctx, "team", 2, "hash"), &notFoundErr)
+	require.NoError(t, repo.SetTeamPassword(ctx, "team", 1, "hash"))
+
+	stored, err := repo.Fetch(ctx, "team")
+	require.NoError(t, err)
+	assert.Equal(t, "hash", stored.PasswordHash)
+}
@@ -0,0 +1,135 @@
+package mem
+
+import (
+	"context"
+	"maps"
+	"slices"
+	"sync"
+
+	"github.com/domurdoc/shortener/internal/model"
+)
+
+type MemTeamRepo struct {
+	teams   map[model.TeamID]model.Team
+	members map[model.TeamID]map[model.UserID]model.TeamRole
+	mu      sync.Mutex
+}
+
+func NewMemTeamRepo() *MemTeamRepo {
+	return &MemTeamRepo{
+		teams:   make(map[model.TeamID]model.Team),
+		members: make(map[model.TeamID]map[model.UserID]model.TeamRole),
+	}
+}
+
+func (m *MemTeamRepo) CreateTeam(ctx context.Context, name string, ownerID model.UserID) (*model.Team, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	nextTeamID := model.TeamID(1)
+	if len(m.teams) > 0 {
+		nextTeamID = slices.Max(slices.Collect(maps.Keys(m.teams))) + 1
+	}
+
+	team := model.Team{ID: nextTeamID, Name: name}
+	m.teams[nextTeamID] = team
+	m.members[nextTeamID] = map[model.UserID]model.TeamRole{ownerID: model.TeamRoleOwner}
+	return &team, nil
+}
+
+func (m *MemTeamRepo) ListTeams(ctx context.Context, userID model.UserID) ([]model.TeamMembership, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var memberships []model.TeamMembership
+	for _, teamID := range slices.Sorted(maps.Keys(m.teams)) {
+		if role, ok := m.members[teamID][userID]; ok {
+			memberships = append(memberships, model.TeamMembership{Team: m.teams[teamID], Role: role})
+		}
+	}
+	return memberships, nil
+}
+
+func (m *MemTeamRepo) ListMembers(ctx context.Context, teamID model.TeamID) ([]model.TeamMember, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	members, ok := m.members[teamID]
+	if !ok {
+		return nil, &model.TeamNotFoundError{TeamID: teamID}
+	}
+	teamMembers := make([]model.TeamMember, 0, len(members))
+	for _, userID := range slices.Sorted(maps.Keys(members)) {
+		teamMembers = append(teamMembers, model.TeamMember{TeamID: teamID, UserID: userID, Role: members[userID]})
+	}
+	return teamMembers, nil
+}
+
+func (m *MemTeamRepo) GetMember(ctx context.Context, teamID model.TeamID, userID model.UserID) (*model.TeamMember, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	members, ok := m.members[teamID]
+	if !ok {
+		return nil, &model.TeamNotFoundError{TeamID: teamID}
+	}
+	role, ok := members[userID]
+	if !ok {
+		return nil, &model.TeamMemberNotFoundError{TeamID: teamID, UserID: userID}
+	}
+	return &model.TeamMember{TeamID: teamID, UserID: userID, Role: role}, nil
+}
+
+func (m *MemTeamRepo) SetMember(ctx context.Context, member *model.TeamMember) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	members, ok := m.members[member.TeamID]
+	if !ok {
+		return &model.TeamNotFoundError{TeamID: member.TeamID}
+	}
+	members[member.UserID] = member.Role
+	return nil
+}
+
+func (m *MemTeamRepo) RemoveMember(ctx context.Context, teamID model.TeamID, userID model.UserID) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	members, ok := m.members[teamID]
+	if !ok {
+		return &model.TeamNotFoundError{TeamID: teamID}
+	}
+	if _, ok := members[userID]; !ok {
+		return &model.TeamMemberNotFoundError{TeamID: teamID, UserID: userID}
+	}
+	delete(members, userID)
+	return nil
+}
+
+func (m *MemTeamRepo) ListAllTeams(ctx context.Context, limit, offset int) ([]model.Team, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	teamIDS := slices.Sorted(maps.Keys(m.teams))
+	if offset >= len(teamIDS) {
+		return nil, nil
+	}
+	teamIDS = teamIDS[offset:min(offset+limit, len(teamIDS))]
+	teams := make([]model.Team, 0, len(teamIDS))
+	for _, teamID := range teamIDS {
+		teams = append(teams, m.teams[teamID])
+	}
+	return teams, nil
+}
+
+func (m *MemTeamRepo) StoreTeam(ctx context.Context, team *model.Team) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.teams[team.ID] = *team
+	if _, ok := m.members[team.ID]; !ok {
+		m.members[team.ID] = make(map[model.UserID]model.TeamRole)
+	}
+	return nil
+}
@@ -0,0 +1,73 @@
+package mem
+
+import (
+	"context"
+	"maps"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/domurdoc/shortener/internal/model"
+)
+
+type MemRefreshTokenRepo struct {
+	storage map[string]model.RefreshToken
+	mu      sync.Mutex
+}
+
+func NewMemRefreshTokenRepo() *MemRefreshTokenRepo {
+	return &MemRefreshTokenRepo{storage: make(map[string]model.RefreshToken)}
+}
+
+func (m *MemRefreshTokenRepo) StoreRefreshToken(ctx context.Context, token *model.RefreshToken) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.storage[token.Hash] = *token
+	return nil
+}
+
+func (m *MemRefreshTokenRepo) UseRefreshToken(ctx context.Context, hash string, grace time.Duration) (*model.RefreshToken, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	token, ok := m.storage[hash]
+	if !ok {
+		return nil, &model.RefreshTokenNotFoundError{}
+	}
+	if token.Used {
+		if !token.UsedAt.IsZero() && time.Since(token.UsedAt) < grace {
+			return &token, nil
+		}
+		return nil, &model.RefreshTokenReusedError{Family: token.Family}
+	}
+	token.Used = true
+	token.UsedAt = time.Now()
+	m.storage[hash] = token
+	return &token, nil
+}
+
+func (m *MemRefreshTokenRepo) RevokeRefreshTokenFamily(ctx context.Context, family string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for hash, token := range m.storage {
+		if token.Family == family {
+			delete(m.storage, hash)
+		}
+	}
+	return nil
+}
+
+func (m *MemRefreshTokenRepo) ListRefreshTokens(ctx context.Context, limit, offset int) ([]model.RefreshToken, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	hashes := slices.Sorted(maps.Keys(m.storage))
+	if offset >= len(hashes) {
+		return nil, nil
+	}
+	hashes = hashes[offset:min(offset+limit, len(hashes))]
+	tokens := make([]model.RefreshToken, 0, len(hashes))
+	for _, hash := range hashes {
+		tokens = append(tokens, m.storage[hash])
+	}
+	return tokens, nil
+}
@@ -38,7 +38,56 @@ func (m *MemUserRepo) CreateUser(ctx context.Context) (*model.User, error) {
 		nextUserID = slices.Max(slices.Collect(maps.Keys(m.storage))) + 1
 	}
 
-	user := model.User{ID: nextUserID}
+	user := model.User{ID: nextUserID, Role: model.RoleUser}
 	m.storage[nextUserID] = user
 	return &user, nil
 }
+
+func (m *MemUserRepo) ListUsers(ctx context.Context, limit, offset int) ([]model.User, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	userIDS := slices.Sorted(maps.Keys(m.storage))
+	if offset >= len(userIDS) {
+		return nil, nil
+	}
+	userIDS = userIDS[offset:min(offset+limit, len(userIDS))]
+	users := make([]model.User, 0, len(userIDS))
+	for _, userID := range userIDS {
+		users = append(users, m.storage[userID])
+	}
+	return users, nil
+}
+
+func (m *MemUserRepo) CountUsers(ctx context.Context) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return len(m.storage), nil
+}
+
+func (m *MemUserRepo) SetUserRole(ctx context.Context, userID model.UserID, role model.Role) error {
+	return m.updateUser(userID, func(user *model.User) { user.Role = role })
+}
+
+func (m *MemUserRepo) SetUserDisabled(ctx context.Context, userID model.UserID, disabled bool) error {
+	return m.updateUser(userID, func(user *model.User) { user.Disabled = disabled })
+}
+
+func (m *MemUserRepo) StoreUser(ctx context.Context, user *model.User) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.storage[user.ID] = *user
+	return nil
+}
+
+func (m *MemUserRepo) updateUser(userID model.UserID, update func(*model.User)) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	user, ok := m.storage[userID]
+	if !ok {
+		return &model.UserNotFoundError{UserID: userID}
+	}
+	update(&user)
+	m.storage[userID] = user
+	return nil
+}
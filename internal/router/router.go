@@ -5,21 +5,71 @@ import (
 
 	"github.com/go-chi/chi/v5"
 
+	"github.com/domurdoc/shortener/internal/auth"
 	"github.com/domurdoc/shortener/internal/handler"
+	"github.com/domurdoc/shortener/internal/httputil"
 )
 
-func New(handler *handler.Handler) http.Handler {
+// oidcHandler may be nil when single sign-on is not configured. middlewares
+// guard every route that requires an authenticated user.
+func New(
+	handler *handler.Handler,
+	adminHandler *handler.AdminHandler,
+	oidcHandler *handler.OIDCHandler,
+	middlewares ...httputil.Middleware,
+) http.Handler {
 	router := chi.NewRouter()
-	setupRoutes(router, handler)
+	setupPublicRoutes(router, handler, oidcHandler)
+	router.Group(func(r chi.Router) {
+		for _, m := range middlewares {
+			r.Use(m)
+		}
+		setupRoutes(r, handler)
+		setupAdminRoutes(r, adminHandler)
+	})
 	return router
 }
 
-func setupRoutes(router *chi.Mux, handler *handler.Handler) {
-	router.Post("/", handler.Shorten)
+func setupPublicRoutes(router chi.Router, handler *handler.Handler, oidcHandler *handler.OIDCHandler) {
 	router.Get("/ping", handler.Ping)
+	router.Get("/api/openapi.json", handler.OpenAPI)
 	router.Get("/{shortCode}", handler.Retrieve)
-	router.Post("/api/shorten", handler.ShortenJSON)
-	router.Post("/api/shorten/batch", handler.ShortenBatchJSON)
+	router.Post("/{shortCode}", handler.Retrieve)
+	if oidcHandler != nil {
+		router.Get("/api/auth/oidc/login", oidcHandler.Login)
+		router.Get("/api/auth/oidc/callback", oidcHandler.Callback)
+	}
+}
+
+func setupRoutes(router chi.Router, handler *handler.Handler) {
+	router.Post("/", handler.Idempotent(handler.Shorten))
+	router.Post("/api/shorten", handler.Idempotent(handler.ShortenJSON))
+	router.Post("/api/shorten/batch", handler.Idempotent(handler.ShortenBatchJSON))
+	router.Post("/api/shorten/import", handler.ShortenImport)
 	router.Get("/api/user/urls", handler.RetrieveForUser)
+	router.Get("/api/user/urls/export", handler.ExportForUser)
 	router.Delete("/api/user/urls", handler.DeleteShortCodes)
+	router.Put("/api/user/urls/{shortCode}/password", handler.SetLinkPassword)
+	router.Delete("/api/user/urls/{shortCode}/password", handler.RemoveLinkPassword)
+	router.Post("/api/teams", handler.CreateTeam)
+	router.Get("/api/teams", handler.RetrieveTeams)
+	router.Get("/api/teams/{teamID}/members", handler.RetrieveTeamMembers)
+	router.Put("/api/teams/{teamID}/members/{userID}", handler.SetTeamMember)
+	router.Delete("/api/teams/{teamID}/members/{userID}", handler.RemoveTeamMember)
+	router.Delete("/api/teams/{teamID}/urls", handler.DeleteTeamShortCodes)
+	router.Put("/api/teams/{teamID}/urls/{shortCode}/password", handler.SetTeamLinkPassword)
+	router.Delete("/api/teams/{teamID}/urls/{shortCode}/password", handler.RemoveTeamLinkPassword)
+}
+
+func setupAdminRoutes(router chi.Router, handler *handler.AdminHandler) {
+	router.Route("/api/admin", func(r chi.Router) {
+		r.Use(auth.AdminMiddleware)
+		r.Get("/urls/{shortCode}", handler.LookupShortCode)
+		r.Delete("/urls/{shortCode}", handler.ForceDelete)
+		r.Post("/urls/{shortCode}/restore", handler.Restore)
+		r.Get("/users", handler.ListUsers)
+		r.Post("/users/{userID}/disable", handler.DisableUser)
+		r.Post("/users/{userID}/enable", handler.EnableUser)
+		r.Get("/stats", handler.Stats)
+	})
 }
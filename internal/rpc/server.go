@@ -0,0 +1,148 @@
+package rpc
+
+import (
+	"context"
+	"errors"
+
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+
+	"github.com/domurdoc/shortener/internal/auth"
+	"github.com/domurdoc/shortener/internal/httputil"
+	"github.com/domurdoc/shortener/internal/model"
+	"github.com/domurdoc/shortener/internal/service"
+	pb "github.com/domurdoc/shortener/pkg/api/shortener/v1"
+)
+
+// linkPasswordKey is the metadata key carrying the password of a protected
+// link, like the X-Link-Password header.
+const linkPasswordKey = "x-link-password"
+
+// PublicMethods do not require authentication.
+var PublicMethods = []string{pb.ShortenerService_Resolve_FullMethodName}
+
+type Server struct {
+	pb.UnimplementedShortenerServiceServer
+	service *service.Service
+	teams   *service.TeamService
+	log     *zap.SugaredLogger
+}
+
+func New(service *service.Service, teams *service.TeamService, log *zap.SugaredLogger) *Server {
+	return &Server{service: service, teams: teams, log: log}
+}
+
+func (s *Server) Shorten(ctx context.Context, req *pb.ShortenRequest) (*pb.ShortenResponse, error) {
+	user := auth.UserFromContext(ctx)
+	var shortURL string
+	var err error
+	if req.GetTeamId() != 0 {
+		shortURL, err = s.teams.Shorten(ctx, user, model.TeamID(req.GetTeamId()), req.GetUrl(), model.LinkOptions{})
+	} else {
+		shortURL, err = s.service.Shorten(ctx, user, req.GetUrl(), model.LinkOptions{})
+	}
+	var urlExistsErr *model.OriginalURLExistsError
+	if err != nil && !errors.As(err, &urlExistsErr) {
+		return nil, s.toStatus(err)
+	}
+	return &pb.ShortenResponse{ShortUrl: shortURL, AlreadyExists: err != nil}, nil
+}
+
+func (s *Server) ShortenBatch(ctx context.Context, req *pb.ShortenBatchRequest) (*pb.ShortenBatchResponse, error) {
+	if len(req.GetItems()) == 0 {
+		return nil, status.Error(codes.InvalidArgument, "at least one item must be passed")
+	}
+	originalURLS := make([]string, len(req.GetItems()))
+	for i, item := range req.GetItems() {
+		originalURLS[i] = item.GetOriginalUrl()
+	}
+	shortURLS, err := s.service.ShortenBatch(ctx, auth.UserFromContext(ctx), originalURLS, nil)
+	var urlExistsErr model.BatchOriginalURLExistsError
+	if err != nil && !errors.As(err, &urlExistsErr) {
+		return nil, s.toStatus(err)
+	}
+	items := make([]*pb.ShortenBatchResponse_Item, len(req.GetItems()))
+	for i, item := range req.GetItems() {
+		items[i] = &pb.ShortenBatchResponse_Item{CorrelationId: item.GetCorrelationId(), ShortUrl: shortURLS[i]}
+	}
+	return &pb.ShortenBatchResponse{Items: items, AlreadyExists: err != nil}, nil
+}
+
+func (s *Server) GetUserURLs(ctx context.Context, _ *pb.GetUserURLsRequest) (*pb.GetUserURLsResponse, error) {
+	urlRecords, err := s.service.GetForUser(ctx, auth.UserFromContext(ctx))
+	if err != nil {
+		return nil, s.toStatus(err)
+	}
+	urls := make([]*pb.GetUserURLsResponse_URL, 0, len(urlRecords))
+	for _, ur := range urlRecords {
+		urls = append(urls, &pb.GetUserURLsResponse_URL{
+			ShortUrl:    string(ur.ShortURL),
+			OriginalUrl: string(ur.OriginalURL),
+			TeamId:      int64(ur.TeamID),
+		})
+	}
+	return &pb.GetUserURLsResponse{Urls: urls}, nil
+}
+
+// DeleteUserURLs only schedules the deletion, like its HTTP counterpart.
+func (s *Server) DeleteUserURLs(ctx context.Context, req *pb.DeleteUserURLsRequest) (*pb.DeleteUserURLsResponse, error) {
+	go s.service.DeleteShortCodes(context.WithoutCancel(ctx), auth.UserFromContext(ctx), req.GetShortCodes())
+	return &pb.DeleteUserURLsResponse{}, nil
+}
+
+func (s *Server) Resolve(ctx context.Context, req *pb.ResolveRequest) (*pb.ResolveResponse, error) {
+	var password string
+	if values := metadata.ValueFromIncomingContext(ctx, linkPasswordKey); len(values) != 0 {
+		password = values[0]
+	}
+	var client string
+	if p, ok := peer.FromContext(ctx); ok {
+		client = httputil.RemoteHost(p.Addr.String())
+	}
+	redirect, err := s.service.GetByShortCode(ctx, req.GetShortCode(), password, client)
+	// The link was fine when it was shortened, the destination went bad since.
+	var blockedErr *model.DestinationBlockedError
+	if errors.As(err, &blockedErr) {
+		return nil, status.Error(codes.FailedPrecondition, err.Error())
+	}
+	if err != nil {
+		return nil, s.toStatus(err)
+	}
+	return &pb.ResolveResponse{OriginalUrl: redirect.URL}, nil
+}
+
+// toStatus keeps the text of unexpected errors in the log, clients only
+// learn that the call failed.
+func (s *Server) toStatus(err error) error {
+	var invalidURLErr *model.InvalidURLError
+	var blockedErr *model.DestinationBlockedError
+	if errors.As(err, &invalidURLErr) || errors.As(err, &blockedErr) {
+		return status.Error(codes.InvalidArgument, err.Error())
+	}
+	var notFoundErr *model.ShortCodeNotFoundError
+	var teamNotFoundErr *model.TeamNotFoundError
+	var pendingErr *model.LinkPendingError
+	if errors.As(err, &notFoundErr) || errors.As(err, &teamNotFoundErr) || errors.As(err, &pendingErr) {
+		return status.Error(codes.NotFound, err.Error())
+	}
+	var isDeletedErr *model.ShortCodeDeletedError
+	var clickLimitErr *model.ClickLimitReachedError
+	var expiredErr *model.LinkExpiredError
+	if errors.As(err, &isDeletedErr) || errors.As(err, &clickLimitErr) || errors.As(err, &expiredErr) {
+		return status.Error(codes.FailedPrecondition, err.Error())
+	}
+	var permissionErr *model.TeamPermissionError
+	var passwordErr *model.LinkPasswordError
+	if errors.As(err, &permissionErr) || errors.As(err, &passwordErr) {
+		return status.Error(codes.PermissionDenied, err.Error())
+	}
+	var attemptsErr *model.TooManyAttemptsError
+	if errors.As(err, &attemptsErr) {
+		return status.Error(codes.ResourceExhausted, err.Error())
+	}
+	s.log.Errorw("grpc call failed", "err", err)
+	return status.Error(codes.Internal, "internal error")
+}
@@ -0,0 +1,118 @@
+package rpc
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+
+	"github.com/domurdoc/shortener/internal/auth"
+	"github.com/domurdoc/shortener/internal/auth/strategy"
+	"github.com/domurdoc/shortener/internal/auth/transport"
+	"github.com/domurdoc/shortener/internal/repository/mem"
+	"github.com/domurdoc/shortener/internal/service"
+	pb "github.com/domurdoc/shortener/pkg/api/shortener/v1"
+)
+
+func newTestClient(t *testing.T, refresher *auth.Refresher) pb.ShortenerServiceClient {
+	a := auth.New(strategy.NewJWT("secret", time.Hour), transport.NewBearer("Authorization"), mem.NewMemUserRepo(), refresher)
+	svc := service.New("http://localhost", 1, 1, time.Second, mem.NewMemRecordRepo(), nil, nil, service.Options{})
+
+	listener := bufconn.Listen(1 << 20)
+	server := grpc.NewServer(grpc.UnaryInterceptor(auth.NewUnaryInterceptor(a, true, zap.NewNop().Sugar(), PublicMethods...)))
+	pb.RegisterShortenerServiceServer(server, New(svc, nil, zap.NewNop().Sugar()))
+	go server.Serve(listener)
+	t.Cleanup(server.Stop)
+
+	conn, err := grpc.NewClient(
+		"passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return listener.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+	return pb.NewShortenerServiceClient(conn)
+}
+
+func TestServer(t *testing.T) {
+	client := newTestClient(t, nil)
+	ctx := context.Background()
+
+	var header metadata.MD
+	shortened, err := client.Shorten(ctx, &pb.ShortenRequest{Url: "http://yandex.com"}, grpc.Header(&header))
+	require.NoError(t, err)
+	assert.False(t, shortened.GetAlreadyExists())
+	require.Len(t, header.Get(auth.MetadataKey), 1)
+	authCtx := metadata.AppendToOutgoingContext(ctx, auth.MetadataKey, header.Get(auth.MetadataKey)[0])
+
+	again, err := client.Shorten(authCtx, &pb.ShortenRequest{Url: "http://yandex.com"})
+	require.NoError(t, err)
+	assert.True(t, again.GetAlreadyExists())
+	assert.Equal(t, shortened.GetShortUrl(), again.GetShortUrl())
+
+	urls, err := client.GetUserURLs(authCtx, &pb.GetUserURLsRequest{})
+	require.NoError(t, err)
+	require.Len(t, urls.GetUrls(), 1)
+	assert.Equal(t, "http://yandex.com", urls.GetUrls()[0].GetOriginalUrl())
+
+	shortCode := shortened.GetShortUrl()[len("http://localhost/"):]
+	resolved, err := client.Resolve(ctx, &pb.ResolveRequest{ShortCode: shortCode})
+	require.NoError(t, err)
+	assert.Equal(t, "http://yandex.com", resolved.GetOriginalUrl())
+
+	_, err = client.Shorten(authCtx, &pb.ShortenRequest{Url: "not a url"})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	badCtx := metadata.AppendToOutgoingContext(ctx, auth.MetadataKey, "Bearer garbage")
+	_, err = client.GetUserURLs(badCtx, &pb.GetUserURLsRequest{})
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+}
+
+func TestServer_RefreshToken(t *testing.T) {
+	refresher := auth.NewRefresher(transport.NewBearer("X-Refresh-Token"), mem.NewMemRefreshTokenRepo(), time.Hour, 0)
+	client := newTestClient(t, refresher)
+	ctx := context.Background()
+
+	var header metadata.MD
+	_, err := client.Shorten(ctx, &pb.ShortenRequest{Url: "http://yandex.com"}, grpc.Header(&header))
+	require.NoError(t, err)
+	require.Len(t, header.Get(auth.RefreshMetadataKey), 1)
+	refreshToken := header.Get(auth.RefreshMetadataKey)[0]
+
+	// An expired access token is replaced using the refresh token.
+	refreshCtx := metadata.AppendToOutgoingContext(ctx,
+		auth.MetadataKey, "Bearer expired",
+		auth.RefreshMetadataKey, refreshToken,
+	)
+	header = nil
+	urls, err := client.GetUserURLs(refreshCtx, &pb.GetUserURLsRequest{}, grpc.Header(&header))
+	require.NoError(t, err)
+	assert.Len(t, urls.GetUrls(), 1)
+	require.Len(t, header.Get(auth.MetadataKey), 1)
+	require.Len(t, header.Get(auth.RefreshMetadataKey), 1)
+	assert.NotEqual(t, refreshToken, header.Get(auth.RefreshMetadataKey)[0])
+
+	revokedCtx := metadata.AppendToOutgoingContext(ctx, auth.RefreshMetadataKey, "Bearer revoked")
+	_, err = client.GetUserURLs(revokedCtx, &pb.GetUserURLsRequest{})
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+}
+
+func TestServer_InternalErrorHidden(t *testing.T) {
+	s := New(nil, nil, zap.NewNop().Sugar())
+	err := s.toStatus(errors.New(`pq: relation "records" does not exist`))
+	assert.Equal(t, codes.Internal, status.Code(err))
+	assert.Equal(t, "internal error", status.Convert(err).Message())
+}
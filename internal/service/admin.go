@@ -0,0 +1,72 @@
+package service
+
+import (
+	"context"
+	"net/url"
+
+	"github.com/domurdoc/shortener/internal/model"
+	"github.com/domurdoc/shortener/internal/repository"
+)
+
+type AdminService struct {
+	baseURL    string
+	recordRepo repository.RecordRepo
+	userRepo   repository.UserRepo
+}
+
+func NewAdmin(
+	baseURL string,
+	recordRepo repository.RecordRepo,
+	userRepo repository.UserRepo,
+) *AdminService {
+	return &AdminService{
+		baseURL:    baseURL,
+		recordRepo: recordRepo,
+		userRepo:   userRepo,
+	}
+}
+
+func (s *AdminService) LookupShortCode(ctx context.Context, shortCode string) (*model.RecordDetails, error) {
+	details, err := s.recordRepo.FetchDetails(ctx, model.ShortCode(shortCode))
+	if err != nil {
+		return nil, err
+	}
+	shortURL, err := url.JoinPath(s.baseURL, shortCode)
+	if err != nil {
+		return nil, err
+	}
+	details.ShortURL = model.ShortURL(shortURL)
+	return details, nil
+}
+
+func (s *AdminService) ForceDelete(ctx context.Context, shortCode string) error {
+	return s.recordRepo.ForceDelete(ctx, model.ShortCode(shortCode))
+}
+
+func (s *AdminService) Restore(ctx context.Context, shortCode string) error {
+	return s.recordRepo.Restore(ctx, model.ShortCode(shortCode))
+}
+
+func (s *AdminService) ListUsers(ctx context.Context, limit, offset int) ([]model.User, error) {
+	return s.userRepo.ListUsers(ctx, limit, offset)
+}
+
+func (s *AdminService) SetUserDisabled(ctx context.Context, userID model.UserID, disabled bool) error {
+	return s.userRepo.SetUserDisabled(ctx, userID, disabled)
+}
+
+func (s *AdminService) SetUserRole(ctx context.Context, userID model.UserID, role model.Role) error {
+	return s.userRepo.SetUserRole(ctx, userID, role)
+}
+
+func (s *AdminService) Stats(ctx context.Context) (*model.Stats, error) {
+	recordStats, err := s.recordRepo.Stats(ctx)
+	if err != nil {
+		return nil, err
+	}
+	users, err := s.userRepo.CountUsers(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &model.Stats{RecordStats: *recordStats, Users: users}, nil
+}
@@ -2,24 +2,61 @@ package service
 
 import (
 	"database/sql"
+	"net/http"
+	"sync"
 	"time"
 
 	"go.uber.org/zap"
 
+	"github.com/domurdoc/shortener/internal/denylist"
 	"github.com/domurdoc/shortener/internal/model"
+	"github.com/domurdoc/shortener/internal/policy"
 	"github.com/domurdoc/shortener/internal/repository"
+	"github.com/domurdoc/shortener/internal/utils"
 )
 
 type Service struct {
-	baseURL        string
-	maxWorkers     int
-	maxBatchSize   int
-	checkInterval  time.Duration
-	deletedRecords chan model.UserRecord
-	doneCh         chan struct{}
-	repo           repository.RecordRepo
-	log            *zap.SugaredLogger
-	db             *sql.DB
+	baseURL            string
+	maxWorkers         int
+	maxBatchSize       int
+	checkInterval      time.Duration
+	deletedRecords     chan model.UserRecord
+	doneCh             chan struct{}
+	clicksMu           sync.Mutex
+	clicks             map[model.ShortCode]int64
+	clicksDone         chan struct{}
+	repo               repository.RecordRepo
+	log                *zap.SugaredLogger
+	db                 *sql.DB
+	codes              CodeGenerator
+	denylist           *denylist.Denylist
+	canonicalizer      *Canonicalizer
+	destinations       *policy.Policy
+	passwordAttempts   *AttemptLimiter
+	pendingFallbackURL string
+	redirectStatus     int
+	redirectMaxAge     time.Duration
+}
+
+// Options are the optional parts of Service, zero values select defaults.
+type Options struct {
+	// Codes generates short codes, random letters by default.
+	Codes CodeGenerator
+	// Denylist, when set, rejects generated codes it matches.
+	Denylist      *denylist.Denylist
+	Canonicalizer *Canonicalizer
+	// Destinations, when set, checks URLs before they are shortened and
+	// before redirects.
+	Destinations     *policy.Policy
+	PasswordAttempts *AttemptLimiter
+	// PendingFallbackURL, when set, is where links redirect before their
+	// ActiveFrom instead of failing.
+	PendingFallbackURL string
+	// RedirectStatus is the status of links that did not choose one, 307 by
+	// default.
+	RedirectStatus int
+	// RedirectMaxAge is how long permanent redirects may be cached.
+	RedirectMaxAge time.Duration
 }
 
 func New(
@@ -30,23 +67,52 @@ func New(
 	repo repository.RecordRepo,
 	log *zap.SugaredLogger,
 	db *sql.DB,
+	opts Options,
 ) *Service {
+	if log == nil {
+		log = zap.NewNop().Sugar()
+	}
+	if opts.Codes == nil {
+		opts.Codes = NewRandomCodeGenerator(utils.ALPHA, shortCodeLength, 0)
+	}
+	if opts.Canonicalizer == nil {
+		opts.Canonicalizer = NewCanonicalizer(false, false, false)
+	}
+	if opts.PasswordAttempts == nil {
+		opts.PasswordAttempts = NewAttemptLimiter(defaultPasswordAttempts, defaultPasswordLinkAttempts, defaultPasswordWindow)
+	}
+	if opts.RedirectStatus == 0 {
+		opts.RedirectStatus = http.StatusTemporaryRedirect
+	}
 	d := &Service{
-		baseURL:        baseURL,
-		maxWorkers:     maxWorkers,
-		maxBatchSize:   maxBatchSize,
-		checkInterval:  checkInterval,
-		deletedRecords: make(chan model.UserRecord),
-		doneCh:         make(chan struct{}),
-		repo:           repo,
-		log:            log,
-		db:             db,
+		baseURL:            baseURL,
+		maxWorkers:         maxWorkers,
+		maxBatchSize:       maxBatchSize,
+		checkInterval:      checkInterval,
+		deletedRecords:     make(chan model.UserRecord),
+		doneCh:             make(chan struct{}),
+		clicks:             make(map[model.ShortCode]int64),
+		clicksDone:         make(chan struct{}),
+		repo:               repo,
+		log:                log,
+		db:                 db,
+		codes:              opts.Codes,
+		denylist:           opts.Denylist,
+		canonicalizer:      opts.Canonicalizer,
+		destinations:       opts.Destinations,
+		passwordAttempts:   opts.PasswordAttempts,
+		pendingFallbackURL: opts.PendingFallbackURL,
+		redirectStatus:     opts.RedirectStatus,
+		redirectMaxAge:     opts.RedirectMaxAge,
 	}
 	go d.serveDeletions()
+	go d.serveClicks()
 	return d
 }
 
+// Close waits for counted clicks to be written.
 func (s *Service) Close() error {
 	close(s.doneCh)
+	<-s.clicksDone
 	return nil
 }
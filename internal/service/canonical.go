@@ -0,0 +1,143 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/url"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/domurdoc/shortener/internal/model"
+	"github.com/domurdoc/shortener/internal/repository"
+)
+
+const backfillPageSize = 500
+
+var (
+	defaultPorts = map[string]string{"http": "80", "https": "443"}
+	percentHex   = regexp.MustCompile(`%[0-9a-fA-F]{2}`)
+)
+
+// Canonicalizer builds the form URLs are deduplicated on. The scheme and host
+// are lowercased, default ports and empty paths dropped and percent-encoding
+// uppercased; query sorting and tracking parameter removal are optional.
+// Redirects go to the canonical form when redirectCanonical is set, to the
+// URL as sent otherwise.
+type Canonicalizer struct {
+	sortQuery         bool
+	stripTracking     bool
+	redirectCanonical bool
+}
+
+func NewCanonicalizer(sortQuery, stripTracking, redirectCanonical bool) *Canonicalizer {
+	return &Canonicalizer{
+		sortQuery:         sortQuery,
+		stripTracking:     stripTracking,
+		redirectCanonical: redirectCanonical,
+	}
+}
+
+// Canonicalize expects a URL that passed ValidateURL.
+func (c *Canonicalizer) Canonicalize(rawURL string) (string, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return "", err
+	}
+	u.Scheme = strings.ToLower(u.Scheme)
+	host, port := strings.ToLower(u.Hostname()), u.Port()
+	if port == defaultPorts[u.Scheme] {
+		port = ""
+	}
+	switch {
+	case port != "":
+		u.Host = net.JoinHostPort(host, port)
+	case strings.Contains(host, ":"):
+		u.Host = "[" + host + "]"
+	default:
+		u.Host = host
+	}
+	if u.Path == "" && u.Opaque == "" {
+		u.Path = "/"
+	}
+	u.RawQuery = c.canonicalQuery(u.RawQuery)
+	u.ForceQuery = false
+	return percentHex.ReplaceAllStringFunc(u.String(), strings.ToUpper), nil
+}
+
+// canonicalQuery works on the raw parameters to keep their encoding.
+func (c *Canonicalizer) canonicalQuery(rawQuery string) string {
+	if rawQuery == "" || !c.sortQuery && !c.stripTracking {
+		return rawQuery
+	}
+	params := strings.Split(rawQuery, "&")
+	if c.stripTracking {
+		params = slices.DeleteFunc(params, isTrackingParam)
+	}
+	if c.sortQuery {
+		slices.Sort(params)
+	}
+	return strings.Join(params, "&")
+}
+
+func isTrackingParam(param string) bool {
+	key, _, _ := strings.Cut(param, "=")
+	if unescaped, err := url.QueryUnescape(key); err == nil {
+		key = unescaped
+	}
+	key = strings.ToLower(key)
+	return strings.HasPrefix(key, "utm_") || key == "fbclid"
+}
+
+// record fills the redirect and dedup forms of rawURL.
+func (c *Canonicalizer) record(rawURL string) (*model.BaseRecord, error) {
+	canonicalURL, err := c.Canonicalize(rawURL)
+	if err != nil {
+		return nil, err
+	}
+	record := &model.BaseRecord{
+		OriginalURL:  model.OriginalURL(rawURL),
+		CanonicalURL: model.OriginalURL(canonicalURL),
+	}
+	if c.redirectCanonical {
+		record.OriginalURL = record.CanonicalURL
+	}
+	return record, nil
+}
+
+// Backfill recomputes the canonical form of stored records, for records
+// stored before canonicalization or after its options changed. A record whose
+// form another record already holds keeps its own and is counted as skipped,
+// so are records whose URL no longer parses.
+func (c *Canonicalizer) Backfill(ctx context.Context, records repository.RecordRepo) (updated, skipped int, err error) {
+	for offset := 0; ; offset += backfillPageSize {
+		page, err := records.List(ctx, backfillPageSize, offset)
+		if err != nil {
+			return updated, skipped, err
+		}
+		for _, record := range page {
+			canonicalURL, err := c.Canonicalize(string(record.OriginalURL))
+			if err != nil {
+				skipped++
+				continue
+			}
+			if model.OriginalURL(canonicalURL) == record.CanonicalURL {
+				continue
+			}
+			err = records.SetCanonicalURL(ctx, record.ShortCode, model.OriginalURL(canonicalURL))
+			var urlExistsErr *model.OriginalURLExistsError
+			switch {
+			case errors.As(err, &urlExistsErr):
+				skipped++
+			case err != nil:
+				return updated, skipped, err
+			default:
+				updated++
+			}
+		}
+		if len(page) < backfillPageSize {
+			return updated, skipped, nil
+		}
+	}
+}
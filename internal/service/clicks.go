@@ -0,0 +1,81 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/domurdoc/shortener/internal/model"
+)
+
+// countClick counts a redirect. Limited links are counted in the repository
+// right away, the limit has to hold across instances. Clicks of other links
+// are kept in memory and written every checkInterval, and failing to count
+// them never fails the redirect.
+func (s *Service) countClick(ctx context.Context, record *model.BaseRecord) error {
+	if record.MaxClicks == 0 {
+		s.clicksMu.Lock()
+		s.clicks[record.ShortCode]++
+		s.clicksMu.Unlock()
+		return nil
+	}
+	err := s.repo.RecordClick(ctx, record.ShortCode)
+	var clickLimitErr *model.ClickLimitReachedError
+	if errors.As(err, &clickLimitErr) {
+		return err
+	}
+	if err != nil {
+		s.log.Errorw("failed to count click", "short_code", record.ShortCode, "err", err)
+	}
+	return nil
+}
+
+// pendingClicks adds clicks not written yet to the records.
+func (s *Service) pendingClicks(records []model.RecordDetails) {
+	s.clicksMu.Lock()
+	defer s.clicksMu.Unlock()
+	for i := range records {
+		records[i].Clicks += s.clicks[records[i].ShortCode]
+	}
+}
+
+func (s *Service) serveClicks() {
+	defer close(s.clicksDone)
+
+	t := time.NewTicker(s.checkInterval)
+	defer t.Stop()
+
+	for {
+		select {
+		case <-s.doneCh:
+			s.flushClicks()
+			return
+		case <-t.C:
+			s.flushClicks()
+		}
+	}
+}
+
+// flushClicks keeps the counts when writing them fails, they are retried
+// with the next flush.
+func (s *Service) flushClicks() {
+	s.clicksMu.Lock()
+	clicks := s.clicks
+	s.clicks = make(map[model.ShortCode]int64)
+	s.clicksMu.Unlock()
+
+	if len(clicks) == 0 {
+		return
+	}
+	if err := s.repo.AddClicks(context.Background(), clicks); err != nil {
+		s.log.Errorw("failed to save clicks", "count", len(clicks), "err", err)
+		s.clicksMu.Lock()
+		for shortCode, n := range s.clicks {
+			clicks[shortCode] += n
+		}
+		s.clicks = clicks
+		s.clicksMu.Unlock()
+		return
+	}
+	s.log.Debugw("clicks saved", "count", len(clicks))
+}
@@ -0,0 +1,238 @@
+package service
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/binary"
+	"math/bits"
+	"math/rand/v2"
+	"slices"
+	"strconv"
+	"sync"
+
+	"github.com/domurdoc/shortener/internal/model"
+	"github.com/domurdoc/shortener/internal/repository"
+	"github.com/domurdoc/shortener/internal/utils"
+)
+
+const (
+	// MaxShortCodeLength is the width of the short code column.
+	MaxShortCodeLength = 32
+	// maxCodeAttempts bounds the retries after short code collisions and
+	// denied codes.
+	maxCodeAttempts = 10
+	// codeLengthWindow is the number of stored codes the collision rate is
+	// measured over, large enough for thresholds of a fraction of a percent.
+	codeLengthWindow = 1000
+)
+
+// CodeGenerator makes short codes for new records. attempt counts the
+// collisions and denied codes met so far, deterministic generators must give a
+// different code for every attempt.
+type CodeGenerator interface {
+	Generate(ctx context.Context, originalURL string, attempt int) (model.ShortCode, error)
+}
+
+// collisionObserver is a CodeGenerator told whether each code it made
+// collided in the store. Denied codes never reach the store and are not
+// reported.
+type collisionObserver interface {
+	observe(collided bool)
+}
+
+// codeLength grows by one, up to MaxShortCodeLength, when the share of
+// collided codes in a window passes threshold. Zero threshold keeps it fixed.
+// Growth is not persisted, after a restart collisions grow it again.
+type codeLength struct {
+	mu         sync.Mutex
+	length     int
+	threshold  float64
+	stored     int
+	collisions int
+}
+
+func newCodeLength(length int, threshold float64) *codeLength {
+	return &codeLength{length: length, threshold: threshold}
+}
+
+func (l *codeLength) get() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.length
+}
+
+// observe counts a code that reached the store.
+func (l *codeLength) observe(collided bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.threshold <= 0 {
+		return
+	}
+	l.stored++
+	if collided {
+		l.collisions++
+	}
+	// The window need not end when its collisions already pass threshold.
+	limit := l.threshold * codeLengthWindow
+	if float64(l.collisions) > limit || l.stored >= codeLengthWindow {
+		if float64(l.collisions) > limit && l.length < MaxShortCodeLength {
+			l.length++
+		}
+		l.stored, l.collisions = 0, 0
+	}
+}
+
+// RandomCodeGenerator picks crypto-random codes.
+type RandomCodeGenerator struct {
+	alphabet string
+	length   *codeLength
+}
+
+func NewRandomCodeGenerator(alphabet string, length int, growthThreshold float64) *RandomCodeGenerator {
+	return &RandomCodeGenerator{alphabet: alphabet, length: newCodeLength(length, growthThreshold)}
+}
+
+func (g *RandomCodeGenerator) Generate(_ context.Context, _ string, _ int) (model.ShortCode, error) {
+	return model.ShortCode(utils.GenerateRandomString(g.alphabet, g.length.get())), nil
+}
+
+func (g *RandomCodeGenerator) observe(collided bool) {
+	g.length.observe(collided)
+}
+
+// SequenceCodeGenerator encodes the next value of the repository sequence,
+// a collision just takes the next value.
+type SequenceCodeGenerator struct {
+	repo     repository.RecordRepo
+	alphabet string
+	length   int
+}
+
+func NewSequenceCodeGenerator(repo repository.RecordRepo, alphabet string, length int) *SequenceCodeGenerator {
+	return &SequenceCodeGenerator{repo: repo, alphabet: alphabet, length: length}
+}
+
+func (g *SequenceCodeGenerator) Generate(ctx context.Context, _ string, _ int) (model.ShortCode, error) {
+	id, err := g.repo.NextShortCodeID(ctx)
+	if err != nil {
+		return "", err
+	}
+	return model.ShortCode(encodeBase(uint64(id), g.alphabet, g.length)), nil
+}
+
+// HashidsCodeGenerator hides the order of sequence values hashids style: the
+// alphabet is shuffled with a salt and rotated by the value, the first
+// character of a code tells the rotation. The rest of the code encodes the
+// value multiplied by a salt derived factor, which keeps it unique.
+type HashidsCodeGenerator struct {
+	repo     repository.RecordRepo
+	alphabet string
+	length   int
+	modulus  uint64
+	factor   uint64
+}
+
+func NewHashidsCodeGenerator(repo repository.RecordRepo, alphabet, salt string, length int) *HashidsCodeGenerator {
+	shuffled := []byte(alphabet)
+	seed := sha256.Sum256([]byte(salt))
+	rnd := rand.New(rand.NewChaCha8(seed))
+	rnd.Shuffle(len(shuffled), func(i, j int) {
+		shuffled[i], shuffled[j] = shuffled[j], shuffled[i]
+	})
+	// A factor coprime with the body base permutes the values under the
+	// modulus, which stays within uint64.
+	base := uint64(len(shuffled) - 1)
+	modulus := uint64(1)
+	for range length - 1 {
+		hi, lo := bits.Mul64(modulus, base)
+		if hi != 0 {
+			break
+		}
+		modulus = lo
+	}
+	factor := uint64(1)
+	for modulus > 2 {
+		factor = 1 + rnd.Uint64N(modulus-1)
+		if gcd(factor, base) == 1 {
+			break
+		}
+	}
+	return &HashidsCodeGenerator{
+		repo:     repo,
+		alphabet: string(shuffled),
+		length:   length,
+		modulus:  modulus,
+		factor:   factor,
+	}
+}
+
+func (g *HashidsCodeGenerator) Generate(ctx context.Context, _ string, _ int) (model.ShortCode, error) {
+	id, err := g.repo.NextShortCodeID(ctx)
+	if err != nil {
+		return "", err
+	}
+	n := uint64(id)
+	offset := n % uint64(len(g.alphabet))
+	alphabet := g.alphabet[offset:] + g.alphabet[:offset]
+	hi, lo := bits.Mul64(n%g.modulus, g.factor)
+	_, scrambled := bits.Div64(hi, lo, g.modulus)
+	scrambled += n - n%g.modulus
+	return model.ShortCode(alphabet[:1] + encodeBase(scrambled, alphabet[1:], g.length-1)), nil
+}
+
+// HashCodeGenerator derives the code from a SHA-256 of the URL, so a URL
+// always gets the same code unless it collided or the length grew.
+type HashCodeGenerator struct {
+	alphabet string
+	length   *codeLength
+}
+
+func NewHashCodeGenerator(alphabet string, length int, growthThreshold float64) *HashCodeGenerator {
+	return &HashCodeGenerator{alphabet: alphabet, length: newCodeLength(length, growthThreshold)}
+}
+
+func (g *HashCodeGenerator) Generate(_ context.Context, originalURL string, attempt int) (model.ShortCode, error) {
+	input := originalURL
+	if attempt > 0 {
+		input += "#" + strconv.Itoa(attempt)
+	}
+	sum := sha256.Sum256([]byte(input))
+	length := g.length.get()
+	var code string
+	for i := 0; len(code) < length; i += 8 {
+		if i+8 > len(sum) {
+			sum = sha256.Sum256(sum[:])
+			i = 0
+		}
+		// The leading digit is skewed, the rest are close to uniform.
+		digits := encodeBase(binary.BigEndian.Uint64(sum[i:i+8]), g.alphabet, 0)
+		if len(digits) > 1 {
+			code += digits[1:]
+		}
+	}
+	return model.ShortCode(code[:length]), nil
+}
+
+func (g *HashCodeGenerator) observe(collided bool) {
+	g.length.observe(collided)
+}
+
+// encodeBase writes n in the base of the alphabet, left padded to at least
+// length characters.
+func encodeBase(n uint64, alphabet string, length int) string {
+	base := uint64(len(alphabet))
+	var code []byte
+	for n > 0 || len(code) < length {
+		code = append(code, alphabet[n%base])
+		n /= base
+	}
+	slices.Reverse(code)
+	return string(code)
+}
+
+func gcd(a, b uint64) uint64 {
+	for b != 0 {
+		a, b = b, a%b
+	}
+	return a
+}
@@ -0,0 +1,88 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/domurdoc/shortener/internal/denylist"
+	"github.com/domurdoc/shortener/internal/model"
+	"github.com/domurdoc/shortener/internal/repository/mem"
+	"github.com/domurdoc/shortener/internal/utils"
+)
+
+func TestCodeLength(t *testing.T) {
+	t.Run("Grows when collisions pass threshold", func(t *testing.T) {
+		length := newCodeLength(6, 0.01)
+		for range 10 {
+			length.observe(true)
+		}
+		assert.Equal(t, 6, length.get())
+		length.observe(true)
+		assert.Equal(t, 7, length.get())
+	})
+
+	t.Run("Collisions spread over windows do not grow it", func(t *testing.T) {
+		length := newCodeLength(6, 0.01)
+		for range 3 {
+			for i := range codeLengthWindow {
+				length.observe(i < 10)
+			}
+		}
+		assert.Equal(t, 6, length.get())
+	})
+
+	t.Run("Zero threshold keeps it fixed", func(t *testing.T) {
+		length := newCodeLength(6, 0)
+		for range 100 {
+			length.observe(true)
+		}
+		assert.Equal(t, 6, length.get())
+	})
+
+	t.Run("Stops at the column width", func(t *testing.T) {
+		length := newCodeLength(MaxShortCodeLength, 0.01)
+		for range 100 {
+			length.observe(true)
+		}
+		assert.Equal(t, MaxShortCodeLength, length.get())
+	})
+}
+
+func TestService_CodeGrowth(t *testing.T) {
+	user := &model.User{ID: 1}
+
+	t.Run("Denied codes are not collisions", func(t *testing.T) {
+		codes := NewRandomCodeGenerator(utils.ALPHA, 6, 0.01)
+		// about a fifth of the codes contain an a
+		s := New("http://localhost", 1, 1, time.Second, mem.NewMemRecordRepo(), nil, nil, Options{
+			Codes:    codes,
+			Denylist: denylist.New([]string{"a"}, false),
+		})
+		defer s.Close()
+
+		for i := range 2 * codeLengthWindow {
+			_, err := s.Shorten(context.TODO(), user, fmt.Sprintf("http://example.com/%d", i), model.LinkOptions{})
+			require.NoError(t, err)
+		}
+		assert.Equal(t, 6, codes.length.get())
+	})
+
+	t.Run("Store collisions grow codes", func(t *testing.T) {
+		codes := NewRandomCodeGenerator("a", 1, 0.001)
+		s := New("http://localhost", 1, 1, time.Second, mem.NewMemRecordRepo(), nil, nil, Options{Codes: codes})
+		defer s.Close()
+
+		shortURL, err := s.Shorten(context.TODO(), user, "http://example.com/1", model.LinkOptions{})
+		require.NoError(t, err)
+		assert.Equal(t, "http://localhost/a", shortURL)
+		// "a" collides twice, which passes 0.1% of the window
+		shortURL, err = s.Shorten(context.TODO(), user, "http://example.com/2", model.LinkOptions{})
+		require.NoError(t, err)
+		assert.Equal(t, "http://localhost/aa", shortURL)
+	})
+}
@@ -0,0 +1,89 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/domurdoc/shortener/internal/model"
+	"github.com/domurdoc/shortener/internal/repository"
+)
+
+const (
+	idempotencyCleanupInterval = time.Hour
+	// idempotencyPendingTTL frees keys of requests that never finished, such
+	// as ones cut short by a restart.
+	idempotencyPendingTTL = time.Minute
+)
+
+type IdempotencyService struct {
+	repo        repository.IdempotencyRepo
+	ttl         time.Duration
+	mu          sync.Mutex
+	lastCleanup time.Time
+}
+
+func NewIdempotency(repo repository.IdempotencyRepo, ttl time.Duration) *IdempotencyService {
+	return &IdempotencyService{repo: repo, ttl: ttl, lastCleanup: time.Now()}
+}
+
+// Begin reserves an unused or expired key for the request and returns nil,
+// the caller then runs the request and calls Save or Release. For a used key
+// it returns the stored response, *model.IdempotencyKeyMismatchError when the
+// key was used for another request, and *model.IdempotencyKeyPendingError
+// while the first request with the key still runs.
+func (s *IdempotencyService) Begin(ctx context.Context, user *model.User, key, requestHash string) (*model.IdempotencyKey, error) {
+	now := time.Now()
+	reserved, err := s.repo.ReserveIdempotencyKey(ctx, &model.IdempotencyKey{
+		UserID:      user.ID,
+		Key:         key,
+		RequestHash: requestHash,
+		ExpiresAt:   now.Add(idempotencyPendingTTL),
+	}, now)
+	if err != nil || reserved {
+		return nil, err
+	}
+	stored, err := s.repo.FetchIdempotencyKey(ctx, user.ID, key)
+	var notFoundErr *model.IdempotencyKeyNotFoundError
+	if errors.As(err, &notFoundErr) {
+		// Released since the reservation failed.
+		return nil, &model.IdempotencyKeyPendingError{Key: key}
+	}
+	if err != nil {
+		return nil, err
+	}
+	if stored.RequestHash != requestHash {
+		return nil, &model.IdempotencyKeyMismatchError{Key: key}
+	}
+	if stored.Pending() {
+		return nil, &model.IdempotencyKeyPendingError{Key: key}
+	}
+	return stored, nil
+}
+
+// Release frees a key reserved by Begin without a response to keep.
+func (s *IdempotencyService) Release(ctx context.Context, user *model.User, key string) error {
+	return s.repo.ReleaseIdempotencyKey(ctx, user.ID, key)
+}
+
+// Save completes a key reserved by Begin, keeps the response for the service
+// TTL and drops expired keys now and then.
+func (s *IdempotencyService) Save(ctx context.Context, stored *model.IdempotencyKey) error {
+	now := time.Now()
+	stored.ExpiresAt = now.Add(s.ttl)
+	if err := s.repo.StoreIdempotencyKey(ctx, stored); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	cleanup := now.Sub(s.lastCleanup) >= idempotencyCleanupInterval
+	if cleanup {
+		s.lastCleanup = now
+	}
+	s.mu.Unlock()
+	if cleanup {
+		_, err := s.repo.DeleteExpiredIdempotencyKeys(ctx, now)
+		return err
+	}
+	return nil
+}
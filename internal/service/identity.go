@@ -0,0 +1,52 @@
+package service
+
+import (
+	"context"
+	"errors"
+
+	"github.com/domurdoc/shortener/internal/model"
+	"github.com/domurdoc/shortener/internal/repository"
+)
+
+type IdentityService struct {
+	identities repository.IdentityRepo
+	users      repository.UserRepo
+}
+
+func NewIdentity(identities repository.IdentityRepo, users repository.UserRepo) *IdentityService {
+	return &IdentityService{identities: identities, users: users}
+}
+
+// ResolveUser maps an external identity to a user. A first login from an
+// anonymous session links the identity to that user, so its links are kept.
+func (s *IdentityService) ResolveUser(ctx context.Context, current *model.User, issuer, subject string) (*model.User, error) {
+	identity, err := s.identities.FetchIdentity(ctx, issuer, subject)
+	if err == nil {
+		return s.users.GetUser(ctx, identity.UserID)
+	}
+	var notFoundErr *model.IdentityNotFoundError
+	if !errors.As(err, &notFoundErr) {
+		return nil, err
+	}
+	user := current
+	if user != nil {
+		linked, err := s.identities.HasIdentity(ctx, user.ID)
+		if err != nil {
+			return nil, err
+		}
+		if linked {
+			user = nil
+		}
+	}
+	if user == nil {
+		user, err = s.users.CreateUser(ctx)
+		if err != nil {
+			return nil, err
+		}
+	}
+	identity = &model.Identity{Issuer: issuer, Subject: subject, UserID: user.ID}
+	if err := s.identities.StoreIdentity(ctx, identity); err != nil {
+		return nil, err
+	}
+	return user, nil
+}
@@ -0,0 +1,154 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+
+	"github.com/domurdoc/shortener/internal/model"
+)
+
+// MaxPasswordLength is the bcrypt input limit in bytes.
+const MaxPasswordLength = 72
+
+const (
+	defaultPasswordAttempts     = 5
+	defaultPasswordLinkAttempts = 100
+	defaultPasswordWindow       = 15 * time.Minute
+)
+
+// AttemptLimiter allows a number of failed password attempts per client and
+// short code in a fixed window, so a stranger guessing does not lock out the
+// recipients of a link. A larger cap per short code stops guessing from many
+// addresses.
+type AttemptLimiter struct {
+	maxPerClient int
+	maxPerLink   int
+	window       time.Duration
+	mu           sync.Mutex
+	failures     map[attemptKey]*attemptWindow
+	lastSweep    time.Time
+}
+
+// attemptKey with an empty client counts the attempts on the whole link.
+type attemptKey struct {
+	shortCode model.ShortCode
+	client    string
+}
+
+type attemptWindow struct {
+	start time.Time
+	count int
+}
+
+func NewAttemptLimiter(maxPerClient, maxPerLink int, window time.Duration) *AttemptLimiter {
+	return &AttemptLimiter{
+		maxPerClient: maxPerClient,
+		maxPerLink:   maxPerLink,
+		window:       window,
+		failures:     make(map[attemptKey]*attemptWindow),
+		lastSweep:    time.Now(),
+	}
+}
+
+// attempt counts an attempt of client at shortCode as failed until done
+// reports it succeeded, so concurrent guesses cannot pass the limits. When
+// attempts are paused it returns how long instead.
+func (l *AttemptLimiter) attempt(shortCode model.ShortCode, client string) (done func(ok bool), wait time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	now := time.Now()
+	if now.Sub(l.lastSweep) >= l.window {
+		for key, w := range l.failures {
+			if now.Sub(w.start) >= l.window {
+				delete(l.failures, key)
+			}
+		}
+		l.lastSweep = now
+	}
+	clientWindow := l.current(attemptKey{shortCode, client}, now)
+	linkWindow := l.current(attemptKey{shortCode: shortCode}, now)
+	if clientWindow.count >= l.maxPerClient {
+		return nil, l.window - now.Sub(clientWindow.start)
+	}
+	if linkWindow.count >= l.maxPerLink {
+		return nil, l.window - now.Sub(linkWindow.start)
+	}
+	clientWindow.count++
+	linkWindow.count++
+	return func(ok bool) {
+		if !ok {
+			return
+		}
+		l.mu.Lock()
+		defer l.mu.Unlock()
+		clientWindow.count--
+		linkWindow.count--
+	}, 0
+}
+
+func (l *AttemptLimiter) current(key attemptKey, now time.Time) *attemptWindow {
+	w, ok := l.failures[key]
+	if !ok || now.Sub(w.start) >= l.window {
+		w = &attemptWindow{start: now}
+		l.failures[key] = w
+	}
+	return w
+}
+
+// SetPassword protects a link the user created, an empty password removes the
+// protection. Co-owners who shortened the same URL later cannot change it.
+func (s *Service) SetPassword(ctx context.Context, user *model.User, shortCode string, password string) error {
+	passwordHash, err := hashPassword(password)
+	if err != nil {
+		return err
+	}
+	return s.repo.SetPassword(ctx, model.ShortCode(shortCode), user.ID, passwordHash)
+}
+
+// SetPasswordForTeam is SetPassword for links created for the team, the
+// caller checks the permission.
+func (s *Service) SetPasswordForTeam(ctx context.Context, teamID model.TeamID, shortCode string, password string) error {
+	passwordHash, err := hashPassword(password)
+	if err != nil {
+		return err
+	}
+	return s.repo.SetTeamPassword(ctx, model.ShortCode(shortCode), teamID, passwordHash)
+}
+
+// hashPassword keeps the empty password empty.
+func hashPassword(password string) (string, error) {
+	if password == "" {
+		return "", nil
+	}
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return "", err
+	}
+	return string(hash), nil
+}
+
+// checkPassword lets the redirect through for open links and for the right
+// password of protected ones. client identifies who guesses, such as an IP
+// address.
+func (s *Service) checkPassword(record *model.BaseRecord, password, client string) error {
+	if record.PasswordHash == "" {
+		return nil
+	}
+	if password == "" {
+		return &model.LinkPasswordError{ShortCode: record.ShortCode}
+	}
+	done, wait := s.passwordAttempts.attempt(record.ShortCode, client)
+	if wait > 0 {
+		return &model.TooManyAttemptsError{ShortCode: record.ShortCode, RetryAfter: wait}
+	}
+	err := bcrypt.CompareHashAndPassword([]byte(record.PasswordHash), []byte(password))
+	done(err == nil)
+	if errors.Is(err, bcrypt.ErrMismatchedHashAndPassword) {
+		return &model.LinkPasswordError{ShortCode: record.ShortCode, Given: true}
+	}
+	return err
+}
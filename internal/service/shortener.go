@@ -3,10 +3,12 @@ package service
 import (
 	"context"
 	"errors"
+	"fmt"
+	"net/http"
 	"net/url"
+	"time"
 
 	"github.com/domurdoc/shortener/internal/model"
-	"github.com/domurdoc/shortener/internal/utils"
 )
 
 // 2048 - max url length (RFC)
@@ -15,18 +17,48 @@ const (
 	shortCodeLength = 6
 )
 
-func (s *Service) Shorten(ctx context.Context, user *model.User, originalURL string) (string, error) {
-	shortCode, shortURL, err := s.generateShortCodeURL(originalURL)
+func (s *Service) Shorten(ctx context.Context, user *model.User, originalURL string, opts model.LinkOptions) (string, error) {
+	return s.shorten(ctx, originalURL, opts, func(record *model.BaseRecord) error {
+		return s.repo.Store(ctx, record, user.ID)
+	})
+}
+
+func (s *Service) ShortenForTeam(ctx context.Context, teamID model.TeamID, originalURL string, opts model.LinkOptions) (string, error) {
+	return s.shorten(ctx, originalURL, opts, func(record *model.BaseRecord) error {
+		return s.repo.StoreForTeam(ctx, record, teamID)
+	})
+}
+
+func (s *Service) shorten(
+	ctx context.Context,
+	originalURL string,
+	opts model.LinkOptions,
+	store func(*model.BaseRecord) error,
+) (string, error) {
+	if err := s.checkURL(ctx, originalURL); err != nil {
+		return "", err
+	}
+	record, err := s.canonicalizer.record(originalURL)
 	if err != nil {
 		return "", err
 	}
-	record := &model.BaseRecord{
-		OriginalURL: model.OriginalURL(originalURL),
-		ShortCode:   model.ShortCode(shortCode),
+	record.LinkOptions = opts
+	for attempt := 0; attempt < maxCodeAttempts; attempt++ {
+		record.ShortCode, attempt, err = s.generateCode(ctx, string(record.DedupURL()), attempt)
+		if err != nil {
+			return "", err
+		}
+		err = store(record)
+		var codeExistsErr *model.ShortCodeExistsError
+		collided := errors.As(err, &codeExistsErr)
+		s.observeCodes(collided, 1)
+		if !collided {
+			break
+		}
 	}
-	err = s.repo.Store(ctx, record, user.ID)
 	var urlErr *model.OriginalURLExistsError
 	if errors.As(err, &urlErr) {
+		urlErr.OptionsIgnored = !opts.IsZero()
 		shortURL, err := url.JoinPath(s.baseURL, string(urlErr.ShortCode))
 		if err != nil {
 			return "", err
@@ -36,56 +68,266 @@ func (s *Service) Shorten(ctx context.Context, user *model.User, originalURL str
 	if err != nil {
 		return "", err
 	}
-	return shortURL, nil
+	return url.JoinPath(s.baseURL, string(record.ShortCode))
 }
 
-func (s *Service) GetByShortCode(ctx context.Context, shortCode string) (string, error) {
+// GetByShortCode resolves a link for a redirect, password is checked for
+// protected links only. client identifies the caller for the password attempt
+// limits.
+func (s *Service) GetByShortCode(ctx context.Context, shortCode, password, client string) (*model.Redirect, error) {
 	record, err := s.repo.Fetch(ctx, model.ShortCode(shortCode))
 	if err != nil {
-		return "", err
+		return nil, err
+	}
+	if err := checkActive(record, time.Now()); err != nil {
+		var pendingErr *model.LinkPendingError
+		if errors.As(err, &pendingErr) && s.pendingFallbackURL != "" {
+			return &model.Redirect{URL: s.pendingFallbackURL, Status: http.StatusTemporaryRedirect}, nil
+		}
+		return nil, err
 	}
-	return string(record.OriginalURL), nil
+	if s.destinations != nil {
+		if err := s.destinations.CheckRedirect(string(record.OriginalURL)); err != nil {
+			return nil, err
+		}
+	}
+	if err := s.checkPassword(record, password, client); err != nil {
+		return nil, err
+	}
+	if err := s.countClick(ctx, record); err != nil {
+		return nil, err
+	}
+	return s.redirect(record), nil
 }
 
-func (s *Service) ShortenBatch(ctx context.Context, user *model.User, originalURLS []string) ([]string, error) {
-	shortURLS := make([]string, 0, len(originalURLS))
-	records := make([]model.BaseRecord, 0, len(originalURLS))
+// redirect lets only permanent redirects be cached, and only for links that
+// answer every request the same way. Limited and protected links have to
+// reach the server each time.
+func (s *Service) redirect(record *model.BaseRecord) *model.Redirect {
+	redirect := &model.Redirect{URL: string(record.OriginalURL), Status: record.RedirectStatus}
+	if redirect.Status == 0 {
+		redirect.Status = s.redirectStatus
+	}
+	permanent := redirect.Status == http.StatusMovedPermanently || redirect.Status == http.StatusPermanentRedirect
+	if permanent && record.PasswordHash == "" && record.MaxClicks == 0 && record.ActiveUntil.IsZero() {
+		redirect.MaxAge = s.redirectMaxAge
+	}
+	return redirect
+}
+
+// ShortenBatch applies opts to the URL at the same position, opts may be nil.
+func (s *Service) ShortenBatch(ctx context.Context, user *model.User, originalURLS []string, opts []model.LinkOptions) ([]string, error) {
 	for _, originalURL := range originalURLS {
-		shortCode, shortURL, err := s.generateShortCodeURL(originalURL)
+		if err := s.checkURL(ctx, originalURL); err != nil {
+			return nil, err
+		}
+	}
+	return s.shortenBatch(ctx, user, originalURLS, opts)
+}
+
+// shortenBatch stores URLs that passed checkURL.
+func (s *Service) shortenBatch(ctx context.Context, user *model.User, originalURLS []string, opts []model.LinkOptions) ([]string, error) {
+	records := make([]model.BaseRecord, 0, len(originalURLS))
+	attempts := make([]int, 0, len(originalURLS))
+	for i, originalURL := range originalURLS {
+		record, err := s.canonicalizer.record(originalURL)
+		if err != nil {
+			return nil, err
+		}
+		if i < len(opts) {
+			record.LinkOptions = opts[i]
+		}
+		var attempt int
+		record.ShortCode, attempt, err = s.generateCode(ctx, string(record.DedupURL()), 0)
 		if err != nil {
 			return nil, err
 		}
-		record := model.BaseRecord{
-			OriginalURL: model.OriginalURL(originalURL),
-			ShortCode:   model.ShortCode(shortCode),
+		records = append(records, *record)
+		attempts = append(attempts, attempt)
+	}
+	var err error
+	for {
+		err = s.repo.StoreBatch(ctx, records, user.ID)
+		var codeExistsErr *model.ShortCodeExistsError
+		if !errors.As(err, &codeExistsErr) {
+			s.observeCodes(false, len(records))
+			break
+		}
+		s.observeCodes(true, 1)
+		pos := codeExistsErr.BatchPos
+		attempts[pos]++
+		if attempts[pos] >= maxCodeAttempts {
+			return nil, err
+		}
+		records[pos].ShortCode, attempts[pos], err = s.generateCode(ctx, string(records[pos].DedupURL()), attempts[pos])
+		if err != nil {
+			return nil, err
+		}
+	}
+	var batchURLExistsErr model.BatchOriginalURLExistsError
+	if err != nil && !errors.As(err, &batchURLExistsErr) {
+		return nil, err
+	}
+	for _, urlExistsErr := range batchURLExistsErr {
+		urlExistsErr.OptionsIgnored = !records[urlExistsErr.BatchPos].LinkOptions.IsZero()
+		records[urlExistsErr.BatchPos].ShortCode = urlExistsErr.ShortCode
+	}
+	shortURLS := make([]string, len(records))
+	for i, record := range records {
+		shortURL, err := url.JoinPath(s.baseURL, string(record.ShortCode))
+		if err != nil {
+			return nil, err
 		}
+		shortURLS[i] = shortURL
+	}
+	if len(batchURLExistsErr) != 0 {
+		return shortURLS, batchURLExistsErr
+	}
+	return shortURLS, nil
+}
 
-		records = append(records, record)
-		shortURLS = append(shortURLS, shortURL)
+// ShortenBatchPartial stores the valid URLs and reports every item separately
+// instead of failing the whole batch. opts are applied as in ShortenBatch.
+func (s *Service) ShortenBatchPartial(
+	ctx context.Context,
+	user *model.User,
+	originalURLS []string,
+	opts []model.LinkOptions,
+) ([]model.BatchItem, error) {
+	items := make([]model.BatchItem, len(originalURLS))
+	validURLS := make([]string, 0, len(originalURLS))
+	validOpts := make([]model.LinkOptions, 0, len(opts))
+	positions := make([]int, 0, len(originalURLS))
+	for pos, originalURL := range originalURLS {
+		if err := s.checkURL(ctx, originalURL); err != nil {
+			items[pos] = model.BatchItem{Status: model.BatchItemInvalid, Error: err}
+			continue
+		}
+		validURLS = append(validURLS, originalURL)
+		if pos < len(opts) {
+			validOpts = append(validOpts, opts[pos])
+		}
+		positions = append(positions, pos)
+	}
+	if len(validURLS) == 0 {
+		return items, nil
 	}
-	err := s.repo.StoreBatch(ctx, records, user.ID)
+	shortURLS, err := s.shortenBatch(ctx, user, validURLS, validOpts)
+	exists := make(map[int]*model.OriginalURLExistsError)
 	var batchURLExistsErr model.BatchOriginalURLExistsError
 	if errors.As(err, &batchURLExistsErr) {
 		for _, urlExistsErr := range batchURLExistsErr {
-			shortURL, err := url.JoinPath(s.baseURL, string(urlExistsErr.ShortCode))
-			if err != nil {
-				return nil, err
+			exists[urlExistsErr.BatchPos] = urlExistsErr
+		}
+	} else if err != nil {
+		return nil, err
+	}
+	for i, pos := range positions {
+		items[pos] = model.BatchItem{ShortURL: model.ShortURL(shortURLS[i]), Status: model.BatchItemCreated}
+		if urlExistsErr, ok := exists[i]; ok {
+			items[pos].Status = model.BatchItemExists
+			if urlExistsErr.OptionsIgnored {
+				items[pos].Error = urlExistsErr
 			}
-			shortURLS[urlExistsErr.BatchPos] = shortURL
 		}
-		return shortURLS, batchURLExistsErr
 	}
+	return items, nil
+}
+
+func (s *Service) GetForUser(ctx context.Context, user *model.User) ([]model.URLRecord, error) {
+	records, err := s.repo.FetchForUser(ctx, user.ID)
 	if err != nil {
 		return nil, err
 	}
-	return shortURLS, nil
+	return s.toURLRecords(records, 0)
 }
 
-func (s *Service) GetForUser(ctx context.Context, user *model.User) ([]model.URLRecord, error) {
-	records, err := s.repo.FetchForUser(ctx, user.ID)
+// listPage lists records of the team, or personal records of the user when
+// teamID is 0, with short URLs and clicks not written yet filled in.
+func (s *Service) listPage(ctx context.Context, userID model.UserID, teamID model.TeamID, limit, offset int) ([]model.RecordDetails, error) {
+	var records []model.RecordDetails
+	var err error
+	if teamID != 0 {
+		records, err = s.repo.ListForTeam(ctx, teamID, limit, offset)
+	} else {
+		records, err = s.repo.ListForUser(ctx, userID, limit, offset)
+	}
+	if err != nil {
+		return nil, err
+	}
+	s.pendingClicks(records)
+	for i := range records {
+		shortURL, err := url.JoinPath(s.baseURL, string(records[i].ShortCode))
+		if err != nil {
+			return nil, err
+		}
+		records[i].ShortURL = model.ShortURL(shortURL)
+	}
+	return records, nil
+}
+
+// GetPageForUser returns a page of the user's personal links and how many
+// there are in total, a limit of 0 returns all links from offset on.
+func (s *Service) GetPageForUser(ctx context.Context, user *model.User, limit, offset int) ([]model.URLRecord, int, error) {
+	total, err := s.repo.CountForUser(ctx, user.ID)
+	if err != nil {
+		return nil, 0, err
+	}
+	urlRecords, err := s.getPage(ctx, user.ID, 0, limit, offset)
+	if err != nil {
+		return nil, 0, err
+	}
+	return urlRecords, total, nil
+}
+
+// GetPageForTeam is GetPageForUser for team links.
+func (s *Service) GetPageForTeam(ctx context.Context, teamID model.TeamID, limit, offset int) ([]model.URLRecord, int, error) {
+	total, err := s.repo.CountForTeam(ctx, teamID)
+	if err != nil {
+		return nil, 0, err
+	}
+	urlRecords, err := s.getPage(ctx, 0, teamID, limit, offset)
+	if err != nil {
+		return nil, 0, err
+	}
+	return urlRecords, total, nil
+}
+
+// countOwned counts links of the team, or personal links of the user when
+// teamID is 0.
+func (s *Service) countOwned(ctx context.Context, userID model.UserID, teamID model.TeamID) (int, error) {
+	if teamID != 0 {
+		return s.repo.CountForTeam(ctx, teamID)
+	}
+	return s.repo.CountForUser(ctx, userID)
+}
+
+func (s *Service) getPage(ctx context.Context, userID model.UserID, teamID model.TeamID, limit, offset int) ([]model.URLRecord, error) {
+	var records []model.BaseRecord
+	var err error
+	if teamID != 0 {
+		records, err = s.repo.FetchPageForTeam(ctx, teamID, limit, offset)
+	} else {
+		records, err = s.repo.FetchPageForUser(ctx, userID, limit, offset)
+	}
 	if err != nil {
 		return nil, err
 	}
+	return s.toURLRecords(records, teamID)
+}
+
+func (s *Service) DeleteForTeam(ctx context.Context, teamID model.TeamID, shortCodes []string) (int, error) {
+	if len(shortCodes) == 0 {
+		return 0, nil
+	}
+	codes := make([]model.ShortCode, len(shortCodes))
+	for i, shortCode := range shortCodes {
+		codes[i] = model.ShortCode(shortCode)
+	}
+	return s.repo.DeleteForTeam(ctx, teamID, codes)
+}
+
+func (s *Service) toURLRecords(records []model.BaseRecord, teamID model.TeamID) ([]model.URLRecord, error) {
 	urlRecords := make([]model.URLRecord, 0, len(records))
 	for _, record := range records {
 		shortURL, err := url.JoinPath(s.baseURL, string(record.ShortCode))
@@ -95,25 +337,68 @@ func (s *Service) GetForUser(ctx context.Context, user *model.User) ([]model.URL
 		urlRecord := model.URLRecord{
 			OriginalURL: record.OriginalURL,
 			ShortURL:    model.ShortURL(shortURL),
+			TeamID:      teamID,
 		}
 		urlRecords = append(urlRecords, urlRecord)
 	}
 	return urlRecords, nil
 }
 
-func (s *Service) generateShortCodeURL(originalURL string) (string, string, error) {
-	if err := validateURL(originalURL); err != nil {
-		return "", "", err
+// observeCodes reports n codes stored or collided to a generator growing its
+// codes with collisions.
+func (s *Service) observeCodes(collided bool, n int) {
+	observer, ok := s.codes.(collisionObserver)
+	if !ok {
+		return
 	}
-	shortCode := utils.GenerateRandomString(utils.ALPHA, shortCodeLength)
-	shortURL, err := url.JoinPath(s.baseURL, shortCode)
-	if err != nil {
-		return "", "", err
+	for range n {
+		observer.observe(collided)
+	}
+}
+
+// generateCode skips codes the denylist matches, each one counts as an
+// attempt. It returns the attempt of the accepted code. Denied codes do not
+// count as collisions, they never reach the store.
+func (s *Service) generateCode(ctx context.Context, originalURL string, attempt int) (model.ShortCode, int, error) {
+	for ; attempt < maxCodeAttempts; attempt++ {
+		shortCode, err := s.codes.Generate(ctx, originalURL, attempt)
+		if err != nil {
+			return "", attempt, err
+		}
+		if s.denylist == nil {
+			return shortCode, attempt, nil
+		}
+		if _, denied := s.denylist.Match(string(shortCode)); !denied {
+			return shortCode, attempt, nil
+		}
+	}
+	return "", attempt, fmt.Errorf("no allowed short code in %d attempts", maxCodeAttempts)
+}
+
+// checkActive returns the error for a record opened outside its activation
+// window.
+func checkActive(record *model.BaseRecord, now time.Time) error {
+	if !record.ActiveFrom.IsZero() && now.Before(record.ActiveFrom) {
+		return &model.LinkPendingError{ShortCode: record.ShortCode, ActiveFrom: record.ActiveFrom}
+	}
+	if !record.ActiveUntil.IsZero() && !now.Before(record.ActiveUntil) {
+		return &model.LinkExpiredError{ShortCode: record.ShortCode, ActiveUntil: record.ActiveUntil}
+	}
+	return nil
+}
+
+// checkURL validates the URL and applies the destination policy.
+func (s *Service) checkURL(ctx context.Context, originalURL string) error {
+	if err := ValidateURL(originalURL); err != nil {
+		return err
+	}
+	if s.destinations == nil {
+		return nil
 	}
-	return shortCode, shortURL, nil
+	return s.destinations.Check(ctx, originalURL)
 }
 
-func validateURL(URL string) error {
+func ValidateURL(URL string) error {
 	if len(URL) > URLMaxLength {
 		return &model.InvalidURLError{Msg: "url too long", URL: URL}
 	}
@@ -124,7 +409,9 @@ func validateURL(URL string) error {
 	if parsedLongURL.Host == "" {
 		return &model.InvalidURLError{Msg: "must be absolute", URL: URL}
 	}
-	if parsedLongURL.String() != URL {
+	// The scheme is the only part url.Parse changes, canonicalization
+	// lowercases it anyway.
+	if parsedLongURL.String() != parsedLongURL.Scheme+URL[len(parsedLongURL.Scheme):] {
 		return &model.InvalidURLError{Msg: "must be url-encoded", URL: URL}
 	}
 	return nil
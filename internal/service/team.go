@@ -0,0 +1,187 @@
+package service
+
+import (
+	"context"
+	"errors"
+
+	"github.com/domurdoc/shortener/internal/model"
+	"github.com/domurdoc/shortener/internal/repository"
+)
+
+type TeamService struct {
+	repo      repository.TeamRepo
+	shortener *Service
+}
+
+func NewTeam(repo repository.TeamRepo, shortener *Service) *TeamService {
+	return &TeamService{repo: repo, shortener: shortener}
+}
+
+func (s *TeamService) CreateTeam(ctx context.Context, user *model.User, name string) (*model.Team, error) {
+	return s.repo.CreateTeam(ctx, name, user.ID)
+}
+
+func (s *TeamService) GetTeams(ctx context.Context, user *model.User) ([]model.TeamMembership, error) {
+	return s.repo.ListTeams(ctx, user.ID)
+}
+
+func (s *TeamService) GetMembers(ctx context.Context, user *model.User, teamID model.TeamID) ([]model.TeamMember, error) {
+	if err := s.authorize(ctx, user, teamID, model.TeamPermissionRead); err != nil {
+		return nil, err
+	}
+	return s.repo.ListMembers(ctx, teamID)
+}
+
+func (s *TeamService) SetMember(ctx context.Context, user *model.User, member *model.TeamMember) error {
+	if err := s.authorize(ctx, user, member.TeamID, model.TeamPermissionManage); err != nil {
+		return err
+	}
+	if member.Role != model.TeamRoleOwner {
+		if err := s.checkNotLastOwner(ctx, member.TeamID, member.UserID); err != nil {
+			return err
+		}
+	}
+	return s.repo.SetMember(ctx, member)
+}
+
+// RemoveMember lets owners remove anyone and every member leave on their own.
+func (s *TeamService) RemoveMember(ctx context.Context, user *model.User, teamID model.TeamID, userID model.UserID) error {
+	if user.ID != userID {
+		if err := s.authorize(ctx, user, teamID, model.TeamPermissionManage); err != nil {
+			return err
+		}
+	}
+	if err := s.checkNotLastOwner(ctx, teamID, userID); err != nil {
+		return err
+	}
+	return s.repo.RemoveMember(ctx, teamID, userID)
+}
+
+func (s *TeamService) Shorten(
+	ctx context.Context,
+	user *model.User,
+	teamID model.TeamID,
+	originalURL string,
+	opts model.LinkOptions,
+) (string, error) {
+	if err := s.authorize(ctx, user, teamID, model.TeamPermissionCreate); err != nil {
+		return "", err
+	}
+	return s.shortener.ShortenForTeam(ctx, teamID, originalURL, opts)
+}
+
+func (s *TeamService) GetURLs(ctx context.Context, user *model.User, teamID model.TeamID, limit, offset int) ([]model.URLRecord, int, error) {
+	if err := s.authorize(ctx, user, teamID, model.TeamPermissionRead); err != nil {
+		return nil, 0, err
+	}
+	return s.shortener.GetPageForTeam(ctx, teamID, limit, offset)
+}
+
+// GetAllURLs pages through personal links followed by links of every team the
+// user belongs to. Only the segments overlapping the page are fetched.
+func (s *TeamService) GetAllURLs(ctx context.Context, user *model.User, limit, offset int) ([]model.URLRecord, int, error) {
+	return pageOwned(ctx, s, user, limit, offset, s.shortener.getPage)
+}
+
+// ListAllURLs is GetAllURLs for exports, it lists records with details.
+func (s *TeamService) ListAllURLs(ctx context.Context, user *model.User, limit, offset int) ([]model.RecordDetails, error) {
+	records, _, err := pageOwned(ctx, s, user, limit, offset, s.shortener.listPage)
+	return records, err
+}
+
+// pageOwned pages through the user's personal links and then team links with
+// fetch, a limit of 0 returns all links from offset on.
+func pageOwned[T any](
+	ctx context.Context,
+	s *TeamService,
+	user *model.User,
+	limit, offset int,
+	fetch func(ctx context.Context, userID model.UserID, teamID model.TeamID, limit, offset int) ([]T, error),
+) ([]T, int, error) {
+	memberships, err := s.repo.ListTeams(ctx, user.ID)
+	if err != nil {
+		return nil, 0, err
+	}
+	teamIDs := make([]model.TeamID, 0, len(memberships)+1)
+	teamIDs = append(teamIDs, 0)
+	for _, membership := range memberships {
+		teamIDs = append(teamIDs, membership.ID)
+	}
+
+	var items []T
+	var total int
+	for _, teamID := range teamIDs {
+		count, err := s.shortener.countOwned(ctx, user.ID, teamID)
+		if err != nil {
+			return nil, 0, err
+		}
+		start := max(offset-total, 0)
+		total += count
+		if start >= count {
+			continue
+		}
+		pageLimit := 0
+		if limit > 0 {
+			pageLimit = limit - len(items)
+			if pageLimit == 0 {
+				continue
+			}
+		}
+		page, err := fetch(ctx, user.ID, teamID, pageLimit, start)
+		if err != nil {
+			return nil, 0, err
+		}
+		items = append(items, page...)
+	}
+	return items, total, nil
+}
+
+func (s *TeamService) DeleteShortCodes(ctx context.Context, user *model.User, teamID model.TeamID, shortCodes []string) (int, error) {
+	if err := s.authorize(ctx, user, teamID, model.TeamPermissionDelete); err != nil {
+		return 0, err
+	}
+	return s.shortener.DeleteForTeam(ctx, teamID, shortCodes)
+}
+
+// SetPassword protects a link created for the team, an empty password removes
+// the protection.
+func (s *TeamService) SetPassword(ctx context.Context, user *model.User, teamID model.TeamID, shortCode, password string) error {
+	if err := s.authorize(ctx, user, teamID, model.TeamPermissionEdit); err != nil {
+		return err
+	}
+	return s.shortener.SetPasswordForTeam(ctx, teamID, shortCode, password)
+}
+
+func (s *TeamService) authorize(ctx context.Context, user *model.User, teamID model.TeamID, permission model.TeamPermission) error {
+	member, err := s.repo.GetMember(ctx, teamID, user.ID)
+	var notMemberErr *model.TeamMemberNotFoundError
+	if errors.As(err, &notMemberErr) {
+		return &model.TeamPermissionError{TeamID: teamID, UserID: user.ID, Permission: permission}
+	}
+	if err != nil {
+		return err
+	}
+	if !member.Role.Can(permission) {
+		return &model.TeamPermissionError{TeamID: teamID, UserID: user.ID, Permission: permission}
+	}
+	return nil
+}
+
+func (s *TeamService) checkNotLastOwner(ctx context.Context, teamID model.TeamID, userID model.UserID) error {
+	members, err := s.repo.ListMembers(ctx, teamID)
+	if err != nil {
+		return err
+	}
+	owners := 0
+	isOwner := false
+	for _, member := range members {
+		if member.Role == model.TeamRoleOwner {
+			owners++
+			isOwner = isOwner || member.UserID == userID
+		}
+	}
+	if isOwner && owners == 1 {
+		return &model.LastTeamOwnerError{TeamID: teamID}
+	}
+	return nil
+}
@@ -2,15 +2,32 @@ package utils
 
 import "crypto/rand"
 
-const ALPHA = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ"
+const (
+	ALPHA  = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ"
+	BASE62 = "0123456789" + ALPHA
+	// READABLE leaves out l, I, 0 and O that are easy to mix up.
+	READABLE = "123456789abcdefghijkmnopqrstuvwxyzABCDEFGHJKLMNPQRSTUVWXYZ"
+)
 
+// GenerateRandomString picks every character uniformly from charSet, random
+// bytes past the largest multiple of len(charSet) are rejected to avoid
+// modulo bias.
 func GenerateRandomString(charSet string, length int) string {
-	// https://stackoverflow.com/a/67035900
 	charSetLength := len(charSet)
+	limit := 256 - 256%charSetLength
+	result := make([]byte, 0, length)
 	buf := make([]byte, length)
-	rand.Read(buf)
-	for i := range length {
-		buf[i] = charSet[int(buf[i])%charSetLength]
+	for len(result) < length {
+		rand.Read(buf)
+		for _, b := range buf {
+			if int(b) >= limit {
+				continue
+			}
+			result = append(result, charSet[int(b)%charSetLength])
+			if len(result) == length {
+				break
+			}
+		}
 	}
-	return string(buf)
+	return string(result)
 }
@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS refresh_tokens_family_idx;
+
+DROP TABLE IF EXISTS refresh_tokens;
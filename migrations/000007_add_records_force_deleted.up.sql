@@ -0,0 +1,4 @@
+ALTER TABLE
+    records
+ADD
+    COLUMN IF NOT EXISTS force_deleted BOOLEAN NOT NULL DEFAULT FALSE;
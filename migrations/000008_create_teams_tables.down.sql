@@ -0,0 +1,5 @@
+DROP TABLE IF EXISTS team_ownership;
+
+DROP TABLE IF EXISTS team_members;
+
+DROP TABLE IF EXISTS teams;
@@ -0,0 +1,17 @@
+CREATE TABLE IF NOT EXISTS teams (
+    id SERIAL PRIMARY KEY,
+    name VARCHAR(256) NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS team_members (
+    team_id INTEGER REFERENCES teams (id) ON DELETE CASCADE,
+    user_id INTEGER REFERENCES users (id),
+    role VARCHAR(16) NOT NULL,
+    UNIQUE (team_id, user_id)
+);
+
+CREATE TABLE IF NOT EXISTS team_ownership (
+    team_id INTEGER REFERENCES teams (id) ON DELETE CASCADE,
+    record_id INTEGER REFERENCES records (id),
+    UNIQUE (team_id, record_id)
+);
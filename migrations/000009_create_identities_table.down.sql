@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS identities_user_id_idx;
+
+DROP TABLE IF EXISTS identities;
@@ -0,0 +1,8 @@
+CREATE TABLE IF NOT EXISTS identities (
+    issuer VARCHAR(512) NOT NULL,
+    subject VARCHAR(255) NOT NULL,
+    user_id INTEGER REFERENCES users (id),
+    PRIMARY KEY (issuer, subject)
+);
+
+CREATE INDEX IF NOT EXISTS identities_user_id_idx ON identities (user_id);
@@ -0,0 +1,6 @@
+ALTER TABLE
+    records
+ADD
+    COLUMN IF NOT EXISTS created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+ADD
+    COLUMN IF NOT EXISTS clicks BIGINT NOT NULL DEFAULT 0;
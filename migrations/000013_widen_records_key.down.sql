@@ -0,0 +1,4 @@
+ALTER TABLE
+    records
+ALTER COLUMN
+    key TYPE VARCHAR(6);
@@ -0,0 +1,7 @@
+ALTER TABLE
+    records
+ADD
+    CONSTRAINT unique_records_value UNIQUE (value);
+
+ALTER TABLE
+    records DROP COLUMN IF EXISTS canonical;
@@ -0,0 +1,23 @@
+ALTER TABLE
+    records
+ADD
+    COLUMN IF NOT EXISTS canonical VARCHAR(2048);
+
+-- raw values only seed the column, the server backfills the canonical form
+-- when it starts
+UPDATE
+    records
+SET
+    canonical = value
+WHERE
+    canonical IS NULL;
+
+ALTER TABLE
+    records
+ALTER COLUMN
+    canonical SET NOT NULL;
+
+CREATE UNIQUE INDEX IF NOT EXISTS unique_records_canonical ON records (canonical);
+
+ALTER TABLE
+    records DROP CONSTRAINT IF EXISTS unique_records_value;
@@ -0,0 +1,4 @@
+ALTER TABLE
+    records
+ADD
+    COLUMN IF NOT EXISTS password_hash VARCHAR(60) NOT NULL DEFAULT '';
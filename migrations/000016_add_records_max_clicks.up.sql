@@ -0,0 +1,4 @@
+ALTER TABLE
+    records
+ADD
+    COLUMN IF NOT EXISTS max_clicks BIGINT NOT NULL DEFAULT 0;
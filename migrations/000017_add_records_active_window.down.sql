@@ -0,0 +1,3 @@
+ALTER TABLE
+    records DROP COLUMN IF EXISTS active_from,
+    DROP COLUMN IF EXISTS active_until;
@@ -0,0 +1,6 @@
+ALTER TABLE
+    records
+ADD
+    COLUMN IF NOT EXISTS active_from TIMESTAMPTZ NULL,
+ADD
+    COLUMN IF NOT EXISTS active_until TIMESTAMPTZ NULL;
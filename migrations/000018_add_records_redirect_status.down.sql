@@ -0,0 +1,2 @@
+ALTER TABLE
+    records DROP COLUMN IF EXISTS redirect_status;
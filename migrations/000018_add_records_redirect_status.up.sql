@@ -0,0 +1,4 @@
+ALTER TABLE
+    records
+ADD
+    COLUMN IF NOT EXISTS redirect_status SMALLINT NOT NULL DEFAULT 0;
@@ -0,0 +1,4 @@
+ALTER TABLE
+    records
+ALTER COLUMN
+    canonical TYPE TEXT;
@@ -0,0 +1,4 @@
+ALTER TABLE
+    refresh_tokens
+ADD
+    COLUMN IF NOT EXISTS used_at TIMESTAMPTZ;
@@ -0,0 +1,18 @@
+ALTER TABLE
+    records
+ADD
+    COLUMN IF NOT EXISTS creator_id INTEGER REFERENCES users (id);
+
+-- The first owner is not recorded for older links, only a sole owner of a
+-- personal link is known to be its creator.
+UPDATE
+    records r
+SET
+    creator_id = o.user_id
+FROM
+    ownership o
+WHERE
+    o.record_id = r.id
+    AND r.creator_id IS NULL
+    AND NOT EXISTS(SELECT 1 FROM team_ownership t WHERE t.record_id = r.id)
+    AND (SELECT COUNT(*) FROM ownership c WHERE c.record_id = r.id) = 1;
@@ -0,0 +1,28 @@
+package client
+
+import (
+	"context"
+	"net/http"
+)
+
+type Stats struct {
+	URLs       int `json:"urls"`
+	ActiveURLs int `json:"active_urls"`
+	Users      int `json:"users"`
+}
+
+// Stats requires the admin role.
+func (c *Client) Stats(ctx context.Context) (*Stats, error) {
+	resp, body, err := c.doJSON(ctx, http.MethodGet, "/api/admin/stats", nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, newAPIError(resp, body)
+	}
+	var stats Stats
+	if err := decode(resp, body, &stats); err != nil {
+		return nil, err
+	}
+	return &stats, nil
+}
@@ -0,0 +1,215 @@
+// Package client is a typed HTTP client for the shortener API.
+package client
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/cookiejar"
+	"net/url"
+	"strings"
+	"sync"
+)
+
+const (
+	authorizationHeader = "Authorization"
+	refreshTokenHeader  = "X-Refresh-Token"
+)
+
+type Client struct {
+	baseURL    *url.URL
+	httpClient *http.Client
+	bearer     bool
+	gzip       bool
+
+	mu           sync.Mutex
+	token        string
+	refreshToken string
+	onTokens     func(token, refreshToken string)
+}
+
+type Option func(*Client)
+
+// WithHTTPClient replaces the default client. In cookie mode a cookie jar is
+// attached to it unless it already has one.
+func WithHTTPClient(httpClient *http.Client) Option {
+	return func(c *Client) {
+		c.httpClient = httpClient
+	}
+}
+
+// WithBearer authenticates by the Authorization header instead of cookies.
+// An empty token makes the server register a new user on the first call; the
+// issued token is kept and available from Token.
+func WithBearer(token string) Option {
+	return func(c *Client) {
+		c.bearer = true
+		c.token = token
+	}
+}
+
+// WithRefreshToken sets the refresh token sent in bearer mode. The server
+// rotates it when the access token is about to expire; the current one is
+// available from RefreshToken.
+func WithRefreshToken(refreshToken string) Option {
+	return func(c *Client) {
+		c.refreshToken = refreshToken
+	}
+}
+
+// WithTokenHook calls fn whenever the server issues new bearer tokens, so
+// callers can persist them. The refresh token it was given is single use.
+func WithTokenHook(fn func(token, refreshToken string)) Option {
+	return func(c *Client) {
+		c.onTokens = fn
+	}
+}
+
+// WithGzip compresses request bodies.
+func WithGzip() Option {
+	return func(c *Client) {
+		c.gzip = true
+	}
+}
+
+func New(baseURL string, opts ...Option) (*Client, error) {
+	u, err := url.Parse(strings.TrimSuffix(baseURL, "/"))
+	if err != nil {
+		return nil, err
+	}
+	c := &Client{baseURL: u, httpClient: &http.Client{}}
+	for _, opt := range opts {
+		opt(c)
+	}
+	// Redirects are API responses here, see Resolve.
+	httpClient := *c.httpClient
+	httpClient.CheckRedirect = func(*http.Request, []*http.Request) error {
+		return http.ErrUseLastResponse
+	}
+	if !c.bearer && httpClient.Jar == nil {
+		jar, err := cookiejar.New(nil)
+		if err != nil {
+			return nil, err
+		}
+		httpClient.Jar = jar
+	}
+	c.httpClient = &httpClient
+	return c, nil
+}
+
+// Token returns the current bearer token, empty in cookie mode.
+func (c *Client) Token() string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.token
+}
+
+// RefreshToken returns the current refresh token, empty in cookie mode.
+func (c *Client) RefreshToken() string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.refreshToken
+}
+
+// storeTokens keeps the tokens the server issued in the response.
+func (c *Client) storeTokens(resp *http.Response) {
+	token, tokenOK := strings.CutPrefix(resp.Header.Get(authorizationHeader), "Bearer ")
+	refreshToken, refreshOK := strings.CutPrefix(resp.Header.Get(refreshTokenHeader), "Bearer ")
+	if !tokenOK && !refreshOK {
+		return
+	}
+	c.mu.Lock()
+	if tokenOK {
+		c.token = token
+	}
+	if refreshOK {
+		c.refreshToken = refreshToken
+	}
+	token, refreshToken = c.token, c.refreshToken
+	c.mu.Unlock()
+	if c.onTokens != nil {
+		c.onTokens(token, refreshToken)
+	}
+}
+
+func (c *Client) do(ctx context.Context, method, path string, query url.Values, contentType string, body []byte) (*http.Response, []byte, error) {
+	u := c.baseURL.JoinPath(path)
+	u.RawQuery = query.Encode()
+
+	var reqBody io.Reader
+	if body != nil {
+		if c.gzip {
+			var buf bytes.Buffer
+			zw := gzip.NewWriter(&buf)
+			if _, err := zw.Write(body); err != nil {
+				return nil, nil, err
+			}
+			if err := zw.Close(); err != nil {
+				return nil, nil, err
+			}
+			body = buf.Bytes()
+		}
+		reqBody = bytes.NewReader(body)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, u.String(), reqBody)
+	if err != nil {
+		return nil, nil, err
+	}
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	if body != nil && c.gzip {
+		req.Header.Set("Content-Encoding", "gzip")
+	}
+	if c.bearer {
+		if token := c.Token(); token != "" {
+			req.Header.Set(authorizationHeader, "Bearer "+token)
+		}
+		if refreshToken := c.RefreshToken(); refreshToken != "" {
+			req.Header.Set(refreshTokenHeader, "Bearer "+refreshToken)
+		}
+	} else {
+		// Cookie authenticated writes must name their origin, see the
+		// server's CSRF protection.
+		req.Header.Set("Origin", c.baseURL.Scheme+"://"+c.baseURL.Host)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer resp.Body.Close()
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, nil, err
+	}
+	if c.bearer {
+		c.storeTokens(resp)
+	}
+	return resp, respBody, nil
+}
+
+func (c *Client) doJSON(ctx context.Context, method, path string, query url.Values, in any) (*http.Response, []byte, error) {
+	var body []byte
+	contentType := ""
+	if in != nil {
+		var err error
+		body, err = json.Marshal(in)
+		if err != nil {
+			return nil, nil, err
+		}
+		contentType = "application/json"
+	}
+	return c.do(ctx, method, path, query, contentType, body)
+}
+
+func decode(resp *http.Response, body []byte, out any) error {
+	if err := json.Unmarshal(body, out); err != nil {
+		return fmt.Errorf("decode %d response: %w", resp.StatusCode, err)
+	}
+	return nil
+}
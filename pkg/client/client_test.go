@@ -0,0 +1,187 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"path"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/domurdoc/shortener/internal/auth"
+	"github.com/domurdoc/shortener/internal/auth/strategy"
+	"github.com/domurdoc/shortener/internal/auth/transport"
+	"github.com/domurdoc/shortener/internal/compressor"
+	"github.com/domurdoc/shortener/internal/handler"
+	"github.com/domurdoc/shortener/internal/httputil"
+	"github.com/domurdoc/shortener/internal/repository/mem"
+	"github.com/domurdoc/shortener/internal/router"
+	"github.com/domurdoc/shortener/internal/service"
+)
+
+func newTestServer(t *testing.T, refresher *auth.Refresher) *httptest.Server {
+	server := httptest.NewUnstartedServer(nil)
+	svc := service.New("http://"+server.Listener.Addr().String(), 1, 1, time.Millisecond, mem.NewMemRecordRepo(), zap.NewNop().Sugar(), nil, service.Options{})
+	a := auth.New(
+		strategy.NewJWT("secret", time.Hour),
+		transport.NewMulti(transport.NewBearer("Authorization"), transport.NewCookie("access", 3600, false)),
+		mem.NewMemUserRepo(),
+		refresher,
+	)
+	server.Config.Handler = httputil.AddMiddlewares(
+		router.New(
+			handler.New(svc, nil, nil),
+			handler.NewAdmin(nil),
+			nil,
+			auth.NewAuthMiddleware(a, true),
+			auth.NewCSRFMiddleware("http://"+server.Listener.Addr().String()),
+		),
+		compressor.GZIPMiddleware,
+	)
+	server.Start()
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestClient(t *testing.T) {
+	server := newTestServer(t, nil)
+	ctx := context.Background()
+
+	for _, tt := range []struct {
+		name string
+		opts []Option
+	}{
+		{"cookie", nil},
+		{"bearer gzip", []Option{WithBearer(""), WithGzip()}},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			c, err := New(server.URL, tt.opts...)
+			require.NoError(t, err)
+			originalURL := "http://" + tt.name[:4] + ".example"
+
+			shortURL, err := c.Shorten(ctx, originalURL)
+			require.NoError(t, err)
+			_, err = c.Shorten(ctx, originalURL)
+			var conflictErr *ConflictError
+			require.ErrorAs(t, err, &conflictErr)
+			assert.Equal(t, shortURL, conflictErr.ShortURL)
+
+			results, err := c.ShortenBatch(ctx, []BatchItem{
+				{CorrelationID: "a", OriginalURL: originalURL + "/a"},
+				{CorrelationID: "b", OriginalURL: originalURL},
+			})
+			var batchConflictErr *BatchConflictError
+			require.ErrorAs(t, err, &batchConflictErr)
+			require.Len(t, results, 2)
+			assert.Equal(t, shortURL, results[1].ShortURL)
+
+			itemResults, err := c.ShortenBatchPartial(ctx, []BatchItem{
+				{CorrelationID: "a", OriginalURL: "not a url"},
+				{CorrelationID: "b", OriginalURL: originalURL},
+			})
+			require.NoError(t, err)
+			require.Len(t, itemResults, 2)
+			assert.Equal(t, "invalid", itemResults[0].Status)
+			assert.Equal(t, BatchItemResult{CorrelationID: "b", ShortURL: shortURL, Status: "exists"}, itemResults[1])
+
+			page, err := c.ListURLs(ctx, ListOptions{Limit: 1, Offset: 1})
+			require.NoError(t, err)
+			assert.Equal(t, 2, page.Total)
+			assert.Len(t, page.URLs, 1)
+
+			resolved, err := c.Resolve(ctx, path.Base(shortURL))
+			require.NoError(t, err)
+			assert.Equal(t, originalURL, resolved)
+			_, err = c.Resolve(ctx, "missing")
+			assert.ErrorIs(t, err, ErrNotFound)
+
+			require.NoError(t, c.Delete(ctx, path.Base(shortURL)))
+			assert.Eventually(t, func() bool {
+				_, err := c.Resolve(ctx, path.Base(shortURL))
+				return err != nil
+			}, time.Second, 10*time.Millisecond)
+			_, err = c.Resolve(ctx, path.Base(shortURL))
+			assert.ErrorIs(t, err, ErrGone)
+		})
+	}
+
+	t.Run("bearer token is reusable", func(t *testing.T) {
+		c, err := New(server.URL, WithBearer(""))
+		require.NoError(t, err)
+		_, err = c.Shorten(ctx, "http://reuse.example")
+		require.NoError(t, err)
+		require.NotEmpty(t, c.Token())
+
+		same, err := New(server.URL, WithBearer(c.Token()))
+		require.NoError(t, err)
+		page, err := same.ListURLs(ctx, ListOptions{})
+		require.NoError(t, err)
+		assert.Len(t, page.URLs, 1)
+	})
+}
+
+func TestClient_RefreshToken(t *testing.T) {
+	refresher := auth.NewRefresher(
+		transport.NewMulti(transport.NewBearer("X-Refresh-Token"), transport.NewCookie("refresh", 3600, false)),
+		mem.NewMemRefreshTokenRepo(),
+		time.Hour,
+		0,
+	)
+	server := newTestServer(t, refresher)
+	ctx := context.Background()
+
+	var stored [2]string
+	c, err := New(server.URL, WithBearer(""), WithTokenHook(func(token, refreshToken string) {
+		stored = [2]string{token, refreshToken}
+	}))
+	require.NoError(t, err)
+	_, err = c.Shorten(ctx, "http://refresh.example")
+	require.NoError(t, err)
+	require.NotEmpty(t, c.RefreshToken())
+	assert.Equal(t, [2]string{c.Token(), c.RefreshToken()}, stored)
+
+	// A client holding only the refresh token signs in as the same user and
+	// gets it rotated.
+	resumed, err := New(server.URL, WithBearer(""), WithRefreshToken(c.RefreshToken()))
+	require.NoError(t, err)
+	page, err := resumed.ListURLs(ctx, ListOptions{})
+	require.NoError(t, err)
+	require.Len(t, page.URLs, 1)
+	assert.NotEmpty(t, resumed.Token())
+	assert.NotEqual(t, c.RefreshToken(), resumed.RefreshToken())
+}
+
+func TestClient_ResolveRedirectStatus(t *testing.T) {
+	for _, status := range []int{
+		http.StatusMovedPermanently,
+		http.StatusFound,
+		http.StatusTemporaryRedirect,
+		http.StatusPermanentRedirect,
+	} {
+		t.Run(http.StatusText(status), func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				http.Redirect(w, r, "https://example.com/"+r.URL.Path[1:], status)
+			}))
+			t.Cleanup(server.Close)
+			c, err := New(server.URL)
+			require.NoError(t, err)
+			resolved, err := c.Resolve(context.Background(), "abc")
+			require.NoError(t, err)
+			assert.Equal(t, "https://example.com/abc", resolved)
+		})
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotModified)
+	}))
+	t.Cleanup(server.Close)
+	c, err := New(server.URL)
+	require.NoError(t, err)
+	_, err = c.Resolve(context.Background(), "abc")
+	var apiErr *APIError
+	assert.ErrorAs(t, err, &apiErr)
+}
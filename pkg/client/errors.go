@@ -0,0 +1,77 @@
+package client
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+var (
+	ErrNotFound = errors.New("not found")
+	ErrGone     = errors.New("link is deleted")
+)
+
+// APIError is an unexpected response status. It unwraps to ErrNotFound or
+// ErrGone for 404 and 410. Type is the problem type of RFC 7807 responses.
+type APIError struct {
+	StatusCode int
+	Type       string
+	Message    string
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("%d %s: %s", e.StatusCode, http.StatusText(e.StatusCode), e.Message)
+}
+
+func (e *APIError) Unwrap() error {
+	switch e.StatusCode {
+	case http.StatusNotFound:
+		return ErrNotFound
+	case http.StatusGone:
+		return ErrGone
+	}
+	return nil
+}
+
+// ConflictError means the URL was already shortened; ShortURL is the existing link.
+type ConflictError struct {
+	ShortURL string
+}
+
+func (e *ConflictError) Error() string {
+	return fmt.Sprintf("url is already shortened as %s", e.ShortURL)
+}
+
+// BatchConflictError means some URLs of a batch were already shortened. The
+// results returned along with it are complete.
+type BatchConflictError struct{}
+
+func (e *BatchConflictError) Error() string {
+	return "some urls are already shortened"
+}
+
+type problem struct {
+	Type   string `json:"type"`
+	Title  string `json:"title"`
+	Detail string `json:"detail"`
+}
+
+func newAPIError(resp *http.Response, body []byte) *APIError {
+	apiErr := &APIError{StatusCode: resp.StatusCode, Message: strings.TrimSpace(string(body))}
+	if !strings.HasPrefix(resp.Header.Get("Content-Type"), "application/problem+json") {
+		return apiErr
+	}
+	var p problem
+	if err := json.Unmarshal(body, &p); err != nil {
+		return apiErr
+	}
+	apiErr.Type = p.Type
+	if p.Detail != "" {
+		apiErr.Message = p.Detail
+	} else if p.Title != "" {
+		apiErr.Message = p.Title
+	}
+	return apiErr
+}
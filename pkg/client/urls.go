@@ -0,0 +1,187 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strconv"
+)
+
+type BatchItem struct {
+	CorrelationID string `json:"correlation_id"`
+	OriginalURL   string `json:"original_url"`
+}
+
+type BatchResult struct {
+	CorrelationID string `json:"correlation_id"`
+	ShortURL      string `json:"short_url"`
+}
+
+// BatchItemResult is one item of a partial batch, ShortURL is empty and Error
+// set when Status is invalid.
+type BatchItemResult struct {
+	CorrelationID string `json:"correlation_id"`
+	ShortURL      string `json:"short_url"`
+	Status        string `json:"status"`
+	Error         string `json:"error"`
+}
+
+type URLRecord struct {
+	ShortURL    string `json:"short_url"`
+	OriginalURL string `json:"original_url"`
+	TeamID      int64  `json:"team_id,omitempty"`
+}
+
+type ListOptions struct {
+	// Limit of 0 returns all links from Offset on.
+	Limit  int
+	Offset int
+	// Owner is one of personal (default), team or all.
+	Owner  string
+	TeamID int64
+}
+
+type Page struct {
+	URLs  []URLRecord
+	Total int
+}
+
+type shortenRequest struct {
+	URL    string `json:"url"`
+	TeamID int64  `json:"team_id,omitempty"`
+}
+
+type shortenResponse struct {
+	Result string `json:"result"`
+}
+
+type batchConflictResponse struct {
+	Items []BatchResult `json:"items"`
+}
+
+// Shorten returns *ConflictError when the URL was shortened before.
+func (c *Client) Shorten(ctx context.Context, originalURL string) (string, error) {
+	return c.ShortenForTeam(ctx, originalURL, 0)
+}
+
+func (c *Client) ShortenForTeam(ctx context.Context, originalURL string, teamID int64) (string, error) {
+	resp, body, err := c.doJSON(ctx, http.MethodPost, "/api/shorten", nil, shortenRequest{URL: originalURL, TeamID: teamID})
+	if err != nil {
+		return "", err
+	}
+	switch resp.StatusCode {
+	case http.StatusCreated, http.StatusConflict:
+	default:
+		return "", newAPIError(resp, body)
+	}
+	var res shortenResponse
+	if err := decode(resp, body, &res); err != nil {
+		return "", err
+	}
+	if resp.StatusCode == http.StatusConflict {
+		return res.Result, &ConflictError{ShortURL: res.Result}
+	}
+	return res.Result, nil
+}
+
+// ShortenBatch returns results in request order. When some URLs were shortened
+// before, the results are returned together with *BatchConflictError.
+func (c *Client) ShortenBatch(ctx context.Context, items []BatchItem) ([]BatchResult, error) {
+	resp, body, err := c.doJSON(ctx, http.MethodPost, "/api/shorten/batch", nil, items)
+	if err != nil {
+		return nil, err
+	}
+	switch resp.StatusCode {
+	case http.StatusCreated, http.StatusConflict:
+	default:
+		return nil, newAPIError(resp, body)
+	}
+	if resp.StatusCode == http.StatusConflict {
+		var conflict batchConflictResponse
+		if err := decode(resp, body, &conflict); err != nil {
+			return nil, err
+		}
+		return conflict.Items, &BatchConflictError{}
+	}
+	var results []BatchResult
+	if err := decode(resp, body, &results); err != nil {
+		return nil, err
+	}
+	return results, nil
+}
+
+// ShortenBatchPartial stores the valid items even when others are invalid and
+// reports each item's status (created, exists or invalid) in request order.
+func (c *Client) ShortenBatchPartial(ctx context.Context, items []BatchItem) ([]BatchItemResult, error) {
+	query := url.Values{"partial": {"true"}}
+	resp, body, err := c.doJSON(ctx, http.MethodPost, "/api/shorten/batch", query, items)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusMultiStatus {
+		return nil, newAPIError(resp, body)
+	}
+	var results []BatchItemResult
+	if err := decode(resp, body, &results); err != nil {
+		return nil, err
+	}
+	return results, nil
+}
+
+func (c *Client) ListURLs(ctx context.Context, opts ListOptions) (*Page, error) {
+	query := url.Values{}
+	if opts.Limit > 0 {
+		query.Set("limit", strconv.Itoa(opts.Limit))
+	}
+	if opts.Offset > 0 {
+		query.Set("offset", strconv.Itoa(opts.Offset))
+	}
+	if opts.Owner != "" {
+		query.Set("owner", opts.Owner)
+	}
+	if opts.TeamID != 0 {
+		query.Set("team_id", strconv.FormatInt(opts.TeamID, 10))
+	}
+	resp, body, err := c.doJSON(ctx, http.MethodGet, "/api/user/urls", query, nil)
+	if err != nil {
+		return nil, err
+	}
+	page := &Page{}
+	page.Total, _ = strconv.Atoi(resp.Header.Get("X-Total-Count"))
+	switch resp.StatusCode {
+	case http.StatusOK:
+		if err := decode(resp, body, &page.URLs); err != nil {
+			return nil, err
+		}
+		return page, nil
+	case http.StatusNoContent:
+		return page, nil
+	}
+	return nil, newAPIError(resp, body)
+}
+
+// Delete schedules deletion; links stop resolving once it is processed.
+func (c *Client) Delete(ctx context.Context, shortCodes ...string) error {
+	resp, body, err := c.doJSON(ctx, http.MethodDelete, "/api/user/urls", nil, shortCodes)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode != http.StatusAccepted {
+		return newAPIError(resp, body)
+	}
+	return nil
+}
+
+// Resolve returns the original URL without following the redirect, whatever
+// redirect status the link uses.
+func (c *Client) Resolve(ctx context.Context, shortCode string) (string, error) {
+	resp, body, err := c.do(ctx, http.MethodGet, shortCode, nil, "", nil)
+	if err != nil {
+		return "", err
+	}
+	location := resp.Header.Get("Location")
+	if resp.StatusCode < http.StatusMultipleChoices || resp.StatusCode >= http.StatusBadRequest || location == "" {
+		return "", newAPIError(resp, body)
+	}
+	return location, nil
+}